@@ -55,6 +55,39 @@ func (ct *CellType) fallbackTo(cellData string, fallback CellType) CellType {
 	return fallback
 }
 
+// ErrorValue is an error that a cell of type CellTypeError holds, such
+// as ErrDiv0 for a division by zero.
+type ErrorValue string
+
+// The error values of Excel
+const (
+	ErrNull        ErrorValue = "#NULL!"
+	ErrDiv0        ErrorValue = "#DIV/0!"
+	ErrValue       ErrorValue = "#VALUE!"
+	ErrRef         ErrorValue = "#REF!"
+	ErrName        ErrorValue = "#NAME?"
+	ErrNum         ErrorValue = "#NUM!"
+	ErrNA          ErrorValue = "#N/A"
+	ErrGettingData ErrorValue = "#GETTING_DATA"
+	ErrSpill       ErrorValue = "#SPILL!"
+)
+
+// CellError is returned when the value of a cell that holds an error,
+// such as the result of a formula that divides by zero, is read as a
+// number.
+type CellError struct {
+	// Ref is the cell's reference, e.g. "B2", if it is known
+	Ref   string
+	Value ErrorValue
+}
+
+func (e *CellError) Error() string {
+	if e.Ref == "" {
+		return fmt.Sprintf("cell holds the error %s", e.Value)
+	}
+	return fmt.Sprintf("cell %s holds the error %s", e.Ref, e.Value)
+}
+
 // Cell is a high level structure intended to provide user access to
 // the contents of Cell within an xlsx.Row.
 type Cell struct {
@@ -62,6 +95,8 @@ type Cell struct {
 	Value          string
 	RichText       []RichTextRun
 	formula        string
+	arrayRef       string
+	dynamicArray   bool
 	style          *Style
 	NumFmt         string
 	parsedNumFmt   *parsedNumberFormat
@@ -138,7 +173,10 @@ func (c *Cell) Modified() bool {
 		return true
 	}
 
-	return c.modified || c.Value != c.origValue || c.NumFmt != c.origNumFmt || !rtEq(c.RichText, c.origRichText)
+	// A formula is content even when it has no cached value, and
+	// so are the top left cell of a merge and a data validation,
+	// which a store may not remember was set
+	return c.modified || c.formula != "" || c.HMerge > 0 || c.VMerge > 0 || c.DataValidation != nil || c.Value != c.origValue || c.NumFmt != c.origNumFmt || !rtEq(c.RichText, c.origRichText)
 }
 
 // Return a string repersenting a Cell in a way that can be used by the CellStore
@@ -194,7 +232,7 @@ func (c *Cell) SetString(s string) {
 	c.updatable()
 	c.Value = s
 	c.RichText = nil
-	c.formula = ""
+	c.clearFormula()
 	c.cellType = CellTypeString
 	c.modified = true
 }
@@ -204,7 +242,7 @@ func (c *Cell) SetRichText(r []RichTextRun) {
 	c.updatable()
 	c.Value = ""
 	c.RichText = append([]RichTextRun(nil), r...)
-	c.formula = ""
+	c.clearFormula()
 	c.cellType = CellTypeString
 	c.modified = true
 }
@@ -259,7 +297,7 @@ func (c *Cell) SetFloatWithFormat(n float64, format string) {
 	c.updatable()
 	c.SetValue(n)
 	c.NumFmt = format
-	c.formula = ""
+	c.clearFormula()
 }
 
 // SetCellFormat set cell value  format
@@ -316,13 +354,17 @@ func (c *Cell) SetDateTimeWithFormat(n float64, format string) {
 	c.updatable()
 	c.Value = strconv.FormatFloat(n, 'f', -1, 64)
 	c.NumFmt = format
-	c.formula = ""
+	c.clearFormula()
 	c.cellType = CellTypeNumeric
 	c.modified = true
 }
 
-// Float returns the value of cell as a number.
+// Float returns the value of cell as a number.  If the cell holds an
+// error, the error returned is a *CellError.
 func (c *Cell) Float() (float64, error) {
+	if err := c.cellError(); err != nil {
+		return math.NaN(), err
+	}
 	f, err := strconv.ParseFloat(c.Value, 64)
 	if err != nil {
 		return math.NaN(), err
@@ -336,8 +378,12 @@ func (c *Cell) SetInt64(n int64) {
 	c.SetValue(n)
 }
 
-// Int64 returns the value of cell as 64-bit integer.
+// Int64 returns the value of cell as 64-bit integer.  If the cell
+// holds an error, the error returned is a *CellError.
 func (c *Cell) Int64() (int64, error) {
+	if err := c.cellError(); err != nil {
+		return -1, err
+	}
 	f, err := strconv.ParseInt(c.Value, 10, 64)
 	if err != nil {
 		return -1, err
@@ -424,7 +470,7 @@ func (c *Cell) SetNumeric(s string) {
 	c.updatable()
 	c.Value = s
 	c.NumFmt = builtInNumFmt[builtInNumFmtIndex_GENERAL]
-	c.formula = ""
+	c.clearFormula()
 	c.cellType = CellTypeNumeric
 	c.modified = true
 }
@@ -432,7 +478,11 @@ func (c *Cell) SetNumeric(s string) {
 // Int returns the value of cell as integer.
 // Has max 53 bits of precision
 // See: float64(int64(math.MaxInt))
+// If the cell holds an error, the error returned is a *CellError.
 func (c *Cell) Int() (int, error) {
+	if err := c.cellError(); err != nil {
+		return -1, err
+	}
 	f, err := strconv.ParseFloat(c.Value, 64)
 	if err != nil {
 		return -1, err
@@ -452,6 +502,37 @@ func (c *Cell) SetBool(b bool) {
 	c.modified = true
 }
 
+// SetError sets a cell's value to an error, such as ErrNA.
+func (c *Cell) SetError(e ErrorValue) {
+	c.updatable()
+	c.Value = string(e)
+	c.clearFormula()
+	c.cellType = CellTypeError
+	c.modified = true
+}
+
+// ErrorValue returns the error that a cell holds, and true, or false
+// if the cell doesn't hold an error.
+func (c *Cell) ErrorValue() (ErrorValue, bool) {
+	if c.cellType != CellTypeError {
+		return "", false
+	}
+	return ErrorValue(c.Value), true
+}
+
+// cellError returns a *CellError if the cell holds an error, or nil.
+func (c *Cell) cellError() error {
+	e, ok := c.ErrorValue()
+	if !ok {
+		return nil
+	}
+	err := &CellError{Value: e}
+	if c.Row != nil {
+		err.Ref = GetCellIDStringFromCoords(c.num, c.Row.num)
+	}
+	return err
+}
+
 // Bool returns a boolean from a cell's value.
 // TODO: Determine if the current return value is
 // appropriate for types other than CellTypeBool.
@@ -471,6 +552,7 @@ func (c *Cell) Bool() bool {
 // SetFormula sets the format string for a cell.
 func (c *Cell) SetFormula(formula string) {
 	c.updatable()
+	c.clearFormula()
 	c.formula = formula
 	c.cellType = CellTypeNumeric
 	c.modified = true
@@ -478,16 +560,131 @@ func (c *Cell) SetFormula(formula string) {
 
 func (c *Cell) SetStringFormula(formula string) {
 	c.updatable()
+	c.clearFormula()
 	c.formula = formula
 	c.cellType = CellTypeStringFormula
 	c.modified = true
 }
 
+// SetFormulaWithResult sets the formula for a cell together with its
+// result, which is written alongside the formula so that applications
+// that don't calculate formulas, such as previewers, can show it.  The
+// result is given as it would be by Cell.Value for a cell of the given
+// type, which may be CellTypeNumeric, CellTypeString,
+// CellTypeStringFormula, CellTypeBool or CellTypeError.
+func (c *Cell) SetFormulaWithResult(formula, value string, cellType CellType) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("SetFormulaWithResult(%q, %q): %w", formula, value, err)
+	}
+	switch cellType {
+	case CellTypeNumeric:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return wrap(fmt.Errorf("invalid numeric result"))
+		}
+	case CellTypeBool:
+		if value != "0" && value != "1" {
+			return wrap(fmt.Errorf("a boolean result must be \"0\" or \"1\""))
+		}
+	case CellTypeError:
+		if !strings.HasPrefix(value, "#") {
+			return wrap(fmt.Errorf("invalid error result"))
+		}
+	case CellTypeString, CellTypeStringFormula:
+	default:
+		return wrap(fmt.Errorf("unsupported cell type %d", cellType))
+	}
+	c.updatable()
+	c.clearFormula()
+	c.formula = formula
+	c.setFormulaResult(FormulaValue{Type: cellType, Value: value})
+	return nil
+}
+
+// SetArrayFormula sets an array formula, known in Excel as a CSE
+// formula, whose results fill the range ref, e.g. "A1:B3".  The cell
+// must be the top left cell of the range.  Its value is the first
+// element of the result, and the values of the other cells in the
+// range can be set like those of any other cell.
+func (c *Cell) SetArrayFormula(ref, formula string) error {
+	if err := c.setArrayFormula(ref, formula, false); err != nil {
+		return fmt.Errorf("SetArrayFormula(%q): %w", ref, err)
+	}
+	return nil
+}
+
+// SetDynamicArrayFormula sets a dynamic array formula, whose results
+// spill into the range ref, e.g. "A1:A10", in versions of Excel that
+// support dynamic arrays.  Older versions treat it as an array
+// formula.  The cell must be the top left cell of the range.
+func (c *Cell) SetDynamicArrayFormula(ref, formula string) error {
+	if err := c.setArrayFormula(ref, formula, true); err != nil {
+		return fmt.Errorf("SetDynamicArrayFormula(%q): %w", ref, err)
+	}
+	return nil
+}
+
+func (c *Cell) setArrayFormula(ref, formula string, dynamic bool) error {
+	r, err := ParseCellRange(ref)
+	if err != nil {
+		return err
+	}
+	if r.MinCol != c.num || (c.Row != nil && r.MinRow != c.Row.num) {
+		return fmt.Errorf("the range must start at the cell")
+	}
+	c.updatable()
+	c.formula = formula
+	c.arrayRef = r.String()
+	c.dynamicArray = dynamic
+	c.cellType = CellTypeNumeric
+	c.modified = true
+	return nil
+}
+
+// ArrayFormulaRef returns the range filled by the cell's array
+// formula, or an empty string if the cell doesn't hold an array
+// formula.
+func (c *Cell) ArrayFormulaRef() string {
+	return c.arrayRef
+}
+
+// IsDynamicArrayFormula returns true if the cell holds a dynamic array
+// formula, as set by SetDynamicArrayFormula.
+func (c *Cell) IsDynamicArrayFormula() bool {
+	return c.dynamicArray
+}
+
+func (c *Cell) clearFormula() {
+	c.formula = ""
+	c.arrayRef = ""
+	c.dynamicArray = false
+}
+
+// makeXLSXFormula returns the f element for the cell's formula, or nil
+// if it has none.
+func (c *Cell) makeXLSXFormula() *xlsxF {
+	switch {
+	case c.formula == "":
+		return nil
+	case c.arrayRef != "":
+		return &xlsxF{Content: c.formula, T: "array", Ref: c.arrayRef}
+	}
+	return &xlsxF{Content: c.formula}
+}
+
 // Formula returns the formula string for the cell.
 func (c *Cell) Formula() string {
 	return c.formula
 }
 
+// ParsedFormula returns the syntax tree of the cell's formula, as
+// produced by ParseFormula, or nil if the cell has no formula.
+func (c *Cell) ParsedFormula() (FormulaNode, error) {
+	if c.formula == "" {
+		return nil, nil
+	}
+	return ParseFormula(c.formula)
+}
+
 // GetStyle returns the Style associated with a Cell
 func (c *Cell) GetStyle() *Style {
 	if c.style == nil {
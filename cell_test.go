@@ -1,6 +1,11 @@
 package xlsx
 
 import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"math"
 	"path/filepath"
 	"testing"
@@ -943,6 +948,67 @@ func cellsFormattedValueEquals(t *testing.T, cell *Cell, expected string) {
 	}
 }
 
+func TestCellErrors(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "SetError", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		errs := []ErrorValue{ErrNull, ErrDiv0, ErrValue, ErrRef, ErrName, ErrNum, ErrNA, ErrGettingData, ErrSpill}
+		row := sheet.AddRow()
+		for _, e := range errs {
+			row.AddCell().SetError(e)
+		}
+		row.AddCell().SetInt(1)
+
+		parts, b := writeFileParts(c, f)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `<c r="G1" t="e"><v>#N/A</v></c>`)
+
+		f, err = OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		for i, e := range errs {
+			cell, err := f.Sheets[0].Cell(0, i)
+			c.Assert(err, qt.IsNil)
+			c.Assert(cell.Type(), qt.Equals, CellTypeError)
+			value, ok := cell.ErrorValue()
+			c.Assert(ok, qt.IsTrue)
+			c.Assert(value, qt.Equals, e)
+		}
+		cell, err := f.Sheets[0].Cell(0, len(errs))
+		c.Assert(err, qt.IsNil)
+		_, ok := cell.ErrorValue()
+		c.Assert(ok, qt.IsFalse)
+	})
+
+	c.Run("ReadingNumbers", func(c *qt.C) {
+		cell := &Cell{}
+		cell.SetError(ErrDiv0)
+		_, err := cell.Float()
+		var cellErr *CellError
+		c.Assert(errors.As(err, &cellErr), qt.IsTrue)
+		c.Assert(cellErr.Value, qt.Equals, ErrDiv0)
+		c.Assert(err, qt.ErrorMatches, "cell holds the error #DIV/0!")
+		_, err = cell.Int64()
+		c.Assert(errors.As(err, &cellErr), qt.IsTrue)
+		_, err = cell.Int()
+		c.Assert(errors.As(err, &cellErr), qt.IsTrue)
+		_, err = cell.GetTime(false)
+		c.Assert(errors.As(err, &cellErr), qt.IsTrue)
+
+		// A formula whose cached result is an error
+		c.Assert(cell.SetFormulaWithResult("1/0", string(ErrDiv0), CellTypeError), qt.IsNil)
+		value, ok := cell.ErrorValue()
+		c.Assert(ok, qt.IsTrue)
+		c.Assert(value, qt.Equals, ErrDiv0)
+
+		cell.SetInt(3)
+		n, err := cell.Int64()
+		c.Assert(err, qt.IsNil)
+		c.Assert(n, qt.Equals, int64(3))
+	})
+}
+
 func TestCellMerge(t *testing.T) {
 	c := qt.New(t)
 	csRunO(c, "MergeAndSave", func(c *qt.C, option FileOption) {
@@ -961,3 +1027,186 @@ func TestCellMerge(t *testing.T) {
 		c.Assert(err, qt.Equals, nil)
 	})
 }
+
+// writeFileParts writes a File and returns the contents of each of its
+// parts, by name, along with the complete file.
+func writeFileParts(c *qt.C, f *File) (map[string]string, []byte) {
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), qt.IsNil)
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, qt.IsNil)
+	parts := make(map[string]string)
+	for _, zf := range r.File {
+		rc, err := zf.Open()
+		c.Assert(err, qt.IsNil)
+		b, err := ioutil.ReadAll(rc)
+		c.Assert(err, qt.IsNil)
+		rc.Close()
+		parts[zf.Name] = string(b)
+	}
+	return parts, buf.Bytes()
+}
+
+func TestFormulaWithResult(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "CachedResults", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		row := sheet.AddRow()
+		c.Assert(row.AddCell().SetFormulaWithResult("1+2", "3", CellTypeNumeric), qt.IsNil)
+		c.Assert(row.AddCell().SetFormulaWithResult(`"a"&"b"`, "ab", CellTypeString), qt.IsNil)
+		c.Assert(row.AddCell().SetFormulaWithResult("1=1", "1", CellTypeBool), qt.IsNil)
+		c.Assert(row.AddCell().SetFormulaWithResult("1/0", "#DIV/0!", CellTypeError), qt.IsNil)
+
+		parts, b := writeFileParts(c, f)
+		xml := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `<c r="A1"><f>1+2</f><v>3</v></c>`)
+		c.Assert(xml, qt.Contains, `<c r="B1" t="str"><f>&#34;a&#34;&amp;&#34;b&#34;</f><v>ab</v></c>`)
+		c.Assert(xml, qt.Contains, `<c r="C1" t="b"><f>1=1</f><v>1</v></c>`)
+		c.Assert(xml, qt.Contains, `<c r="D1" t="e"><f>1/0</f><v>#DIV/0!</v></c>`)
+
+		f, err = OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		expected := []struct {
+			formula, value string
+			cellType       CellType
+		}{
+			{"1+2", "3", CellTypeNumeric},
+			{`"a"&"b"`, "ab", CellTypeStringFormula},
+			{"1=1", "1", CellTypeBool},
+			{"1/0", "#DIV/0!", CellTypeError},
+		}
+		for i, e := range expected {
+			cell, err := f.Sheets[0].Cell(0, i)
+			c.Assert(err, qt.IsNil)
+			c.Assert(cell.Formula(), qt.Equals, e.formula)
+			c.Assert(cell.Value, qt.Equals, e.value)
+			c.Assert(cell.Type(), qt.Equals, e.cellType)
+		}
+	})
+
+	c.Run("InvalidResults", func(c *qt.C) {
+		cell := &Cell{}
+		c.Assert(cell.SetFormulaWithResult("A1", "x", CellTypeNumeric), qt.ErrorMatches, `SetFormulaWithResult\("A1", "x"\): invalid numeric result`)
+		c.Assert(cell.SetFormulaWithResult("A1", "TRUE", CellTypeBool), qt.Not(qt.IsNil))
+		c.Assert(cell.SetFormulaWithResult("A1", "oops", CellTypeError), qt.Not(qt.IsNil))
+		c.Assert(cell.SetFormulaWithResult("A1", "x", CellTypeInline), qt.Not(qt.IsNil))
+		c.Assert(cell.Formula(), qt.Equals, "")
+	})
+
+	c.Run("FullCalcOnLoad", func(c *qt.C) {
+		f := NewFile()
+		_, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		parts, _ := writeFileParts(c, f)
+		c.Assert(parts["xl/workbook.xml"], qt.Not(qt.Contains), "fullCalcOnLoad")
+
+		f.FullCalcOnLoad = true
+		parts, b := writeFileParts(c, f)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `fullCalcOnLoad="true"`)
+		f, err = OpenBinary(b)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.FullCalcOnLoad, qt.IsTrue)
+	})
+}
+
+func TestArrayAndSharedFormulas(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "SharedFormulas", func(c *qt.C, option FileOption) {
+		f := NewFile(option, SharedFormulas())
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		for i := 1; i <= 4; i++ {
+			row := sheet.AddRow()
+			row.AddCell().SetInt(i)
+			row.AddCell().SetFormula(fmt.Sprintf("A%d*2", i))
+			if i < 4 {
+				row.AddCell().SetFormula(fmt.Sprintf("$A$1+A%d", i))
+			} else {
+				row.AddCell().SetFormula("A1")
+			}
+		}
+
+		parts, b := writeFileParts(c, f)
+		xml := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `<c r="B1"><f t="shared" ref="B1:B4" si="0">A1*2</f>`)
+		c.Assert(xml, qt.Contains, `<c r="B4"><f t="shared" si="0"></f>`)
+		c.Assert(xml, qt.Contains, `<c r="C1"><f t="shared" ref="C1:C3" si="1">$A$1+A1</f>`)
+		c.Assert(xml, qt.Contains, `<c r="C3"><f t="shared" si="1"></f>`)
+		c.Assert(xml, qt.Contains, `<c r="C4"><f>A1</f>`)
+		c.Assert(parts, qt.Not(qt.Contains), "xl/metadata.xml")
+
+		f, err = OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		cell, err := f.Sheets[0].Cell(2, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Formula(), qt.Equals, "A3*2")
+		cell, err = f.Sheets[0].Cell(2, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Formula(), qt.Equals, "$A$1+A3")
+	})
+
+	csRunO(c, "SeparateFormulasByDefault", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		for i := 1; i <= 2; i++ {
+			sheet.AddRow().AddCell().SetFormula(fmt.Sprintf("B%d*2", i))
+		}
+		parts, err := f.MakeStreamParts()
+		c.Assert(err, qt.IsNil)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `<c r="A2"><f>B2*2</f>`)
+	})
+
+	csRunO(c, "ArrayFormulas", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		row := sheet.AddRow()
+		cell := row.AddCell()
+		c.Assert(cell.SetArrayFormula("A1:A3", "ROW(B1:B3)"), qt.IsNil)
+		cell.Value = "1"
+		c.Assert(cell.ArrayFormulaRef(), qt.Equals, "A1:A3")
+		c.Assert(cell.IsDynamicArrayFormula(), qt.IsFalse)
+		cell = row.AddCell()
+		c.Assert(cell.SetArrayFormula("A1:B3", "1"), qt.ErrorMatches, `SetArrayFormula\("A1:B3"\): the range must start at the cell`)
+		c.Assert(cell.SetArrayFormula("B1:", "1"), qt.ErrorMatches, `SetArrayFormula\("B1:"\): .*`)
+		cell = row.AddCell()
+		c.Assert(cell.SetDynamicArrayFormula("C1:C3", "_xlfn.SEQUENCE(3)"), qt.IsNil)
+		c.Assert(cell.IsDynamicArrayFormula(), qt.IsTrue)
+
+		parts, b := writeFileParts(c, f)
+		xml := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `<c r="A1"><f t="array" ref="A1:A3">ROW(B1:B3)</f><v>1</v></c>`)
+		c.Assert(xml, qt.Contains, `<c r="C1" cm="1"><f t="array" ref="C1:C3">_xlfn.SEQUENCE(3)</f>`)
+		c.Assert(parts["xl/metadata.xml"], qt.Equals, TEMPLATE_XL_METADATA)
+		c.Assert(parts["xl/_rels/workbook.xml.rels"], qt.Contains, `Target="metadata.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sheetMetadata"`)
+		c.Assert(parts["[Content_Types].xml"], qt.Contains, `<Override PartName="/xl/metadata.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheetMetadata+xml">`)
+
+		f, err = OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		cell, err = f.Sheets[0].Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Formula(), qt.Equals, "ROW(B1:B3)")
+		c.Assert(cell.ArrayFormulaRef(), qt.Equals, "A1:A3")
+		c.Assert(cell.IsDynamicArrayFormula(), qt.IsFalse)
+		cell, err = f.Sheets[0].Cell(0, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.ArrayFormulaRef(), qt.Equals, "C1:C3")
+		c.Assert(cell.IsDynamicArrayFormula(), qt.IsTrue)
+
+		cell.SetFormula("1+1")
+		c.Assert(cell.ArrayFormulaRef(), qt.Equals, "")
+		c.Assert(cell.IsDynamicArrayFormula(), qt.IsFalse)
+
+		// Array formulas move with structural edits
+		c.Assert(f.Sheets[0].InsertCols(0, 1), qt.IsNil)
+		cell, err = f.Sheets[0].Cell(0, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.ArrayFormulaRef(), qt.Equals, "B1:B3")
+		c.Assert(cell.Formula(), qt.Equals, "ROW(C1:C3)")
+	})
+}
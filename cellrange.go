@@ -0,0 +1,295 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CellRange is a rectangular block of cells.  All of the coordinates
+// are zero based and inclusive, so the range "B2:C3" has MinCol 1,
+// MinRow 1, MaxCol 2 and MaxRow 2.  A range of whole rows (e.g. "1:3")
+// spans every column, and a range of whole columns (e.g. "A:C") spans
+// every row.
+type CellRange struct {
+	MinCol int
+	MinRow int
+	MaxCol int
+	MaxRow int
+}
+
+// NewCellRange returns the CellRange with the given corners, in any
+// order.
+func NewCellRange(col1, row1, col2, row2 int) CellRange {
+	if col1 > col2 {
+		col1, col2 = col2, col1
+	}
+	if row1 > row2 {
+		row1, row2 = row2, row1
+	}
+	return CellRange{MinCol: col1, MinRow: row1, MaxCol: col2, MaxRow: row2}
+}
+
+// RowsRange returns the CellRange covering the whole rows from first
+// to last.
+func RowsRange(first, last int) CellRange {
+	return NewCellRange(0, first, Excel2006MaxColIndex, last)
+}
+
+// ColsRange returns the CellRange covering the whole columns from
+// first to last.
+func ColsRange(first, last int) CellRange {
+	return NewCellRange(first, 0, last, Excel2006MaxRowIndex)
+}
+
+// ParseCellRange converts a range reference in A1 notation, such as
+// "A1:C3", "$A$1:$C$3", "B2", "1:3" or "A:C", into a CellRange.  A
+// leading sheet name is not accepted.
+func ParseCellRange(ref string) (CellRange, error) {
+	wrap := func(err error) (CellRange, error) {
+		return CellRange{}, fmt.Errorf("ParseCellRange(%q): %w", ref, err)
+	}
+	parts := strings.Split(ref, cellRangeChar)
+	if len(parts) > 2 {
+		return wrap(fmt.Errorf("too many %q", cellRangeChar))
+	}
+	col1, row1, err := parseRangePart(parts[0])
+	if err != nil {
+		return wrap(err)
+	}
+	col2, row2 := col1, row1
+	if len(parts) == 2 {
+		col2, row2, err = parseRangePart(parts[1])
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	switch {
+	case col1 >= 0 && row1 >= 0 && col2 >= 0 && row2 >= 0:
+		return NewCellRange(col1, row1, col2, row2), nil
+	case len(parts) == 2 && col1 < 0 && col2 < 0 && row1 >= 0 && row2 >= 0:
+		return RowsRange(row1, row2), nil
+	case len(parts) == 2 && row1 < 0 && row2 < 0 && col1 >= 0 && col2 >= 0:
+		return ColsRange(col1, col2), nil
+	}
+	return wrap(fmt.Errorf("not a valid range"))
+}
+
+// parseRangePart parses one side of a range reference, which may be a
+// cell ("B2"), a column ("B") or a row ("2"), optionally with "$"
+// markers.  A missing column or row is returned as -1.
+func parseRangePart(part string) (col, row int, err error) {
+	i := 0
+	if i < len(part) && part[i] == '$' {
+		i++
+	}
+	start := i
+	for i < len(part) && isLetter(part[i]) {
+		i++
+	}
+	letters := part[start:i]
+	if i < len(part) && part[i] == '$' {
+		if letters == "" && start > 0 {
+			return -1, -1, fmt.Errorf("misplaced %q in %q", fixedCellRefChar, part)
+		}
+		i++
+	}
+	digits := part[i:]
+	if letters == "" && digits == "" {
+		return -1, -1, fmt.Errorf("empty reference %q", part)
+	}
+
+	col, row = -1, -1
+	if letters != "" {
+		if len(letters) > 3 {
+			return -1, -1, fmt.Errorf("invalid column %q", letters)
+		}
+		col = ColLettersToIndex(letters)
+		if col > Excel2006MaxColIndex {
+			return -1, -1, fmt.Errorf("column %q is out of range", letters)
+		}
+	}
+	if digits != "" {
+		for j := 0; j < len(digits); j++ {
+			if !isDigit(digits[j]) {
+				return -1, -1, fmt.Errorf("invalid reference %q", part)
+			}
+		}
+		row, err = strconv.Atoi(digits)
+		if err != nil || row < 1 || row > Excel2006MaxRowCount {
+			return -1, -1, fmt.Errorf("row %q is out of range", digits)
+		}
+		row--
+	}
+	return col, row, nil
+}
+
+func isLetter(c byte) bool {
+	return ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z')
+}
+
+// IsWholeRows returns true if the range spans every column.
+func (r CellRange) IsWholeRows() bool {
+	return r.MinCol == 0 && r.MaxCol == Excel2006MaxColIndex
+}
+
+// IsWholeCols returns true if the range spans every row.
+func (r CellRange) IsWholeCols() bool {
+	return r.MinRow == 0 && r.MaxRow == Excel2006MaxRowIndex
+}
+
+// Contains returns true if the cell at the given zero based
+// coordinates lies within the range.
+func (r CellRange) Contains(col, row int) bool {
+	return col >= r.MinCol && col <= r.MaxCol && row >= r.MinRow && row <= r.MaxRow
+}
+
+// Intersect returns the cells that lie within both ranges, and true,
+// or false if the ranges don't overlap.
+func (r CellRange) Intersect(other CellRange) (CellRange, bool) {
+	i := CellRange{
+		MinCol: maxInt(r.MinCol, other.MinCol),
+		MinRow: maxInt(r.MinRow, other.MinRow),
+		MaxCol: minInt(r.MaxCol, other.MaxCol),
+		MaxRow: minInt(r.MaxRow, other.MaxRow),
+	}
+	if i.MinCol > i.MaxCol || i.MinRow > i.MaxRow {
+		return CellRange{}, false
+	}
+	return i, true
+}
+
+// Width returns the number of columns in the range.
+func (r CellRange) Width() int {
+	return r.MaxCol - r.MinCol + 1
+}
+
+// Height returns the number of rows in the range.
+func (r CellRange) Height() int {
+	return r.MaxRow - r.MinRow + 1
+}
+
+// String returns the range in A1 notation, e.g. "A1:C3".  A single
+// cell is returned on its own, e.g. "B2", and whole rows or columns
+// are returned as "1:3" or "A:C".
+func (r CellRange) String() string {
+	return r.format(false)
+}
+
+// AbsoluteString returns the range in A1 notation with every column
+// and row fixed, e.g. "$A$1:$C$3".
+func (r CellRange) AbsoluteString() string {
+	return r.format(true)
+}
+
+func (r CellRange) format(absolute bool) string {
+	fixed := ""
+	if absolute {
+		fixed = fixedCellRefChar
+	}
+	switch {
+	case r.IsWholeCols() && r.IsWholeRows():
+		return fixed + "1" + cellRangeChar + fixed + RowIndexToString(Excel2006MaxRowIndex)
+	case r.IsWholeRows():
+		return fixed + RowIndexToString(r.MinRow) + cellRangeChar + fixed + RowIndexToString(r.MaxRow)
+	case r.IsWholeCols():
+		return fixed + ColIndexToLetters(r.MinCol) + cellRangeChar + fixed + ColIndexToLetters(r.MaxCol)
+	}
+	first := GetCellIDStringFromCoordsWithFixed(r.MinCol, r.MinRow, absolute, absolute)
+	if r.MinCol == r.MaxCol && r.MinRow == r.MaxRow {
+		return first
+	}
+	return first + cellRangeChar + GetCellIDStringFromCoordsWithFixed(r.MaxCol, r.MaxRow, absolute, absolute)
+}
+
+// quoteSheetName returns the sheet name in the form it must take in
+// front of a reference, wrapping it in single quotes when it contains
+// anything other than letters, digits, underscores and dots, or when
+// it could be mistaken for a cell reference.
+func quoteSheetName(name string) string {
+	plain := name != "" && !isDigit(name[0])
+	for i := 0; plain && i < len(name); i++ {
+		c := name[i]
+		plain = isLetter(c) || isDigit(c) || c == '_' || c == '.'
+	}
+	if plain {
+		if _, _, err := parseRangePart(name); err == nil {
+			plain = false
+		} else if looksLikeR1C1(name) {
+			plain = false
+		}
+	}
+	if plain {
+		return name
+	}
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+// looksLikeR1C1 returns true if s could be read as a reference in
+// R1C1 notation, such as "R", "C2" or "R1C1".
+func looksLikeR1C1(s string) bool {
+	u := strings.ToUpper(s)
+	if strings.HasPrefix(u, "R") {
+		u = strings.TrimLeft(u[1:], "0123456789")
+	}
+	if strings.HasPrefix(u, "C") {
+		u = strings.TrimLeft(u[1:], "0123456789")
+	}
+	return u == "" && s != ""
+}
+
+// sheetRef returns the reference qualified with the (quoted, if
+// necessary) sheet name, e.g. "'My Sheet'!$A$1".
+func sheetRef(sheetName, ref string) string {
+	return quoteSheetName(sheetName) + externalSheetBangChar + ref
+}
+
+// splitSheetRef splits a reference such as "'My Sheet'!$A$1" into the
+// unquoted sheet name and the remaining reference.  If the reference
+// isn't qualified with a sheet name, the returned sheet name is empty.
+func splitSheetRef(ref string) (sheetName, rest string, err error) {
+	if strings.HasPrefix(ref, "'") {
+		var b strings.Builder
+		for i := 1; i < len(ref); i++ {
+			if ref[i] != '\'' {
+				b.WriteByte(ref[i])
+				continue
+			}
+			if i+1 < len(ref) && ref[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			if i+1 >= len(ref) || ref[i+1] != '!' {
+				return "", "", fmt.Errorf("splitSheetRef: expected %q after sheet name in %q", externalSheetBangChar, ref)
+			}
+			return b.String(), ref[i+2:], nil
+		}
+		return "", "", fmt.Errorf("splitSheetRef: unterminated sheet name in %q", ref)
+	}
+	i := strings.LastIndex(ref, externalSheetBangChar)
+	if i < 0 {
+		return "", ref, nil
+	}
+	return ref[:i], ref[i+1:], nil
+}
+
+// splitRefList splits a comma separated list of references, ignoring
+// any commas within quoted sheet names.
+func splitRefList(list string) []string {
+	var refs []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '\'':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				refs = append(refs, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(refs, list[start:])
+}
@@ -0,0 +1,96 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCellRange(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("ParseCellRange", func(c *qt.C) {
+		cases := []struct {
+			ref      string
+			expected CellRange
+			str      string
+			abs      string
+		}{
+			{"A1:C3", CellRange{0, 0, 2, 2}, "A1:C3", "$A$1:$C$3"},
+			{"$B$2:$D$10", CellRange{1, 1, 3, 9}, "B2:D10", "$B$2:$D$10"},
+			{"C3:A1", CellRange{0, 0, 2, 2}, "A1:C3", "$A$1:$C$3"},
+			{"b2", CellRange{1, 1, 1, 1}, "B2", "$B$2"},
+			{"1:3", CellRange{0, 0, Excel2006MaxColIndex, 2}, "1:3", "$1:$3"},
+			{"$2:$2", CellRange{0, 1, Excel2006MaxColIndex, 1}, "2:2", "$2:$2"},
+			{"A:C", CellRange{0, 0, 2, Excel2006MaxRowIndex}, "A:C", "$A:$C"},
+			{"XFD1048576", CellRange{Excel2006MaxColIndex, Excel2006MaxRowIndex, Excel2006MaxColIndex, Excel2006MaxRowIndex}, "XFD1048576", "$XFD$1048576"},
+		}
+		for _, tc := range cases {
+			r, err := ParseCellRange(tc.ref)
+			c.Assert(err, qt.IsNil, qt.Commentf(tc.ref))
+			c.Assert(r, qt.Equals, tc.expected, qt.Commentf(tc.ref))
+			c.Assert(r.String(), qt.Equals, tc.str)
+			c.Assert(r.AbsoluteString(), qt.Equals, tc.abs)
+		}
+	})
+
+	c.Run("ParseCellRangeErrors", func(c *qt.C) {
+		for _, ref := range []string{"", "A", "1", "A1:B2:C3", "A1:3", "XFE1", "A0", "A1048577", "A1B", "$$A1", "Sheet1!A1"} {
+			_, err := ParseCellRange(ref)
+			c.Assert(err, qt.Not(qt.IsNil), qt.Commentf(ref))
+		}
+	})
+
+	c.Run("Contains", func(c *qt.C) {
+		r := NewCellRange(3, 3, 1, 1)
+		c.Assert(r.Width(), qt.Equals, 3)
+		c.Assert(r.Height(), qt.Equals, 3)
+		c.Assert(r.Contains(1, 1), qt.IsTrue)
+		c.Assert(r.Contains(3, 2), qt.IsTrue)
+		c.Assert(r.Contains(0, 2), qt.IsFalse)
+		c.Assert(r.Contains(2, 4), qt.IsFalse)
+	})
+
+	c.Run("Intersect", func(c *qt.C) {
+		r, ok := NewCellRange(0, 0, 3, 3).Intersect(NewCellRange(2, 1, 5, 2))
+		c.Assert(ok, qt.IsTrue)
+		c.Assert(r, qt.Equals, NewCellRange(2, 1, 3, 2))
+		r, ok = ColsRange(1, 1).Intersect(RowsRange(4, 4))
+		c.Assert(ok, qt.IsTrue)
+		c.Assert(r.String(), qt.Equals, "B5")
+		_, ok = NewCellRange(0, 0, 1, 1).Intersect(NewCellRange(2, 2, 3, 3))
+		c.Assert(ok, qt.IsFalse)
+	})
+
+	c.Run("QuoteSheetName", func(c *qt.C) {
+		c.Assert(quoteSheetName("Sheet1"), qt.Equals, "Sheet1")
+		c.Assert(quoteSheetName("My_Data.2"), qt.Equals, "My_Data.2")
+		c.Assert(quoteSheetName("My Sheet"), qt.Equals, "'My Sheet'")
+		c.Assert(quoteSheetName("Bob's"), qt.Equals, "'Bob''s'")
+		c.Assert(quoteSheetName("2020"), qt.Equals, "'2020'")
+		c.Assert(quoteSheetName("AB12"), qt.Equals, "'AB12'")
+		c.Assert(quoteSheetName("R1C1"), qt.Equals, "'R1C1'")
+	})
+
+	c.Run("SplitSheetRef", func(c *qt.C) {
+		sheet, ref, err := splitSheetRef("'Bob''s data'!$A$1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet, qt.Equals, "Bob's data")
+		c.Assert(ref, qt.Equals, "$A$1")
+
+		sheet, ref, err = splitSheetRef("Sheet1!A1:B2")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet, qt.Equals, "Sheet1")
+		c.Assert(ref, qt.Equals, "A1:B2")
+
+		sheet, ref, err = splitSheetRef("A1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet, qt.Equals, "")
+		c.Assert(ref, qt.Equals, "A1")
+
+		_, _, err = splitSheetRef("'Sheet1")
+		c.Assert(err, qt.Not(qt.IsNil))
+
+		c.Assert(splitRefList("'a,b'!$A:$A,'a,b'!$1:$1"), qt.DeepEquals, []string{"'a,b'!$A:$A", "'a,b'!$1:$1"})
+	})
+}
@@ -40,6 +40,17 @@ type CellStoreRow interface {
 	CellUpdatable(c *Cell)
 }
 
+// cellShifter is implemented by the CellStoreRows of the cell stores
+// in this package.  It's kept out of CellStoreRow so that cell stores
+// from elsewhere needn't implement it, although columns can't then be
+// inserted into or removed from their Sheets.
+type cellShifter interface {
+	// ShiftCells moves the cells at or beyond the column index at
+	// by n columns.  When n is negative the -n cells starting at
+	// column index at are removed first.
+	ShiftCells(at, n int)
+}
+
 // CellVisitorFunc defines the signature of a function that will be
 // called when visiting a Cell using CellStore.ForEachInRow.
 type CellVisitorFunc func(c *Cell) error
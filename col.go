@@ -4,6 +4,8 @@ package xlsx
 const ColWidth = 9.5
 const Excel2006MaxRowCount = 1048576
 const Excel2006MaxRowIndex = Excel2006MaxRowCount - 1
+const Excel2006MaxColCount = 16384
+const Excel2006MaxColIndex = Excel2006MaxColCount - 1
 
 type Col struct {
 	Min          int
@@ -398,3 +400,27 @@ func (cs *ColStore) ForEach(fn func(idx int, col *Col)) {
 	}
 	chainOp(cs.Root, fn)
 }
+
+// shift moves the Cols in the ColStore to follow a structural edit of
+// the columns of the sheet.  A Col grows when columns are inserted
+// within its range, shrinks when some of its columns are removed and
+// is dropped when all of them are.
+func (cs *ColStore) shift(sh refShift) {
+	if cs.Root == nil {
+		return
+	}
+	var cols []*Col
+	cs.ForEach(func(_ int, col *Col) {
+		// Col ranges are one based
+		min, max, ok := sh.span(col.Min-1, col.Max-1)
+		if ok {
+			col.Min, col.Max = min+1, max+1
+			cols = append(cols, col)
+		}
+	})
+	cs.Root = nil
+	cs.Len = 0
+	for _, col := range cols {
+		cs.Add(col)
+	}
+}
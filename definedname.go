@@ -0,0 +1,275 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// definedNameMaxLen is the longest name Excel will accept.
+	definedNameMaxLen = 255
+	builtInNamePrefix = "_xlnm."
+)
+
+// DefinedName is a name defined in a File, such as "Totals", that
+// formulas can use in place of what it refers to.  Names are added
+// with File.AddName and found with File.Name and Sheet.Names.
+type DefinedName struct {
+	// Name is the name itself.
+	Name string
+	// Data is what the name refers to, without a leading "=": a
+	// reference such as "Sheet1!$B$10:$F$10", a constant or a
+	// formula.
+	Data string
+	// Comment describes the name in Excel's Name Manager.
+	Comment string
+	// Hidden hides the name from Excel's user interface.
+	Hidden bool
+	// localSheetID is the index of the Sheet that the name is local
+	// to, or nil for a workbook-wide name.
+	localSheetID *int
+	// attrs holds the rest of the definedName element as it was
+	// read, so that it's written back unchanged.
+	attrs xlsxDefinedName
+}
+
+// SheetIndex returns the zero based index of the Sheet that the name
+// is local to, or -1 if the name is visible throughout the workbook.
+func (dn *DefinedName) SheetIndex() int {
+	if dn.localSheetID == nil {
+		return -1
+	}
+	return *dn.localSheetID
+}
+
+// readDefinedName makes a DefinedName from a definedName element.
+func readDefinedName(x xlsxDefinedName) *DefinedName {
+	return &DefinedName{
+		Name:         x.Name,
+		Data:         x.Data,
+		Comment:      x.Comment,
+		Hidden:       x.Hidden,
+		localSheetID: x.LocalSheetID,
+		attrs:        x,
+	}
+}
+
+// makeXLSXDefinedName returns the definedName element for the name.
+func (dn *DefinedName) makeXLSXDefinedName() xlsxDefinedName {
+	x := dn.attrs
+	x.Name = dn.Name
+	x.Data = dn.Data
+	x.Comment = dn.Comment
+	x.Hidden = dn.Hidden
+	x.LocalSheetID = dn.localSheetID
+	return x
+}
+
+// AddName defines a name, e.g. "Totals", that refers to a range, a
+// constant or a formula, e.g. "Sheet1!$B$10:$F$10".  If scope is nil
+// the name is visible throughout the workbook, otherwise it is local
+// to the given sheet, which must belong to the File.  Names must
+// follow Excel's rules: they must start with a letter, an underscore
+// or a backslash, may only contain letters, digits, underscores,
+// dots and backslashes, must not look like a cell reference, and must
+// be unique, ignoring case, within their scope.
+func (f *File) AddName(name, refersTo string, scope *Sheet) (*DefinedName, error) {
+	wrap := func(err error) (*DefinedName, error) {
+		return nil, fmt.Errorf("AddName(%q): %w", name, err)
+	}
+	if err := validateDefinedName(name); err != nil {
+		return wrap(err)
+	}
+	refersTo = strings.TrimPrefix(strings.TrimSpace(refersTo), "=")
+	if refersTo == "" {
+		return wrap(fmt.Errorf("a name must refer to something"))
+	}
+
+	var localSheetID *int
+	if scope != nil {
+		index := f.sheetIndex(scope)
+		if index < 0 {
+			return wrap(fmt.Errorf("sheet %q does not belong to this file", scope.Name))
+		}
+		localSheetID = &index
+	}
+	if f.findName(name, localSheetID) != nil {
+		return wrap(fmt.Errorf("the name already exists in this scope"))
+	}
+
+	dn := &DefinedName{
+		Name:         name,
+		Data:         refersTo,
+		localSheetID: localSheetID,
+	}
+	f.DefinedNames = append(f.DefinedNames, dn)
+	return dn, nil
+}
+
+// Name returns the workbook-wide defined name with the given name,
+// ignoring case, or nil if there is no such name.
+func (f *File) Name(name string) *DefinedName {
+	return f.findName(name, nil)
+}
+
+// ResolveName finds the named range that a formula on the given sheet
+// would see when using the name, i.e. a name local to that sheet takes
+// precedence over a workbook-wide one.  Pass a nil sheet to only
+// consider workbook-wide names.  The name must refer to a single,
+// contiguous range of cells.
+func (f *File) ResolveName(name string, scope *Sheet) (*Sheet, CellRange, error) {
+	wrap := func(err error) (*Sheet, CellRange, error) {
+		return nil, CellRange{}, fmt.Errorf("ResolveName(%q): %w", name, err)
+	}
+
+	var dn *DefinedName
+	if scope != nil {
+		index := f.sheetIndex(scope)
+		if index >= 0 {
+			dn = f.findName(name, &index)
+		}
+	}
+	if dn == nil {
+		dn = f.Name(name)
+	}
+	if dn == nil {
+		return wrap(fmt.Errorf("no such name"))
+	}
+
+	sheetName, ref, err := splitSheetRef(strings.TrimPrefix(dn.Data, "="))
+	if err != nil {
+		return wrap(err)
+	}
+	var sheet *Sheet
+	switch {
+	case sheetName != "":
+		sheet = f.sheetByName(sheetName)
+		if sheet == nil {
+			return wrap(fmt.Errorf("refers to unknown sheet %q", sheetName))
+		}
+	case dn.localSheetID != nil && *dn.localSheetID >= 0 && *dn.localSheetID < len(f.Sheets):
+		sheet = f.Sheets[*dn.localSheetID]
+	default:
+		return wrap(fmt.Errorf("%q does not refer to a sheet", dn.Data))
+	}
+
+	r, err := ParseCellRange(ref)
+	if err != nil {
+		return wrap(fmt.Errorf("%q does not refer to a single range of cells", dn.Data))
+	}
+	return sheet, r, nil
+}
+
+// Names returns the defined names that are local to the sheet.
+func (s *Sheet) Names() []*DefinedName {
+	if s.File == nil {
+		return nil
+	}
+	index := s.File.sheetIndex(s)
+	if index < 0 {
+		return nil
+	}
+	var names []*DefinedName
+	for _, dn := range s.File.DefinedNames {
+		if dn.localSheetID != nil && *dn.localSheetID == index {
+			names = append(names, dn)
+		}
+	}
+	return names
+}
+
+// visibleName returns the defined name that a formula on the given
+// sheet refers to, along with the sheet against which any references
+// without a sheet in the name's formula are resolved.  As in Excel, a
+// name that is local to the sheet hides a workbook-wide one.  It
+// returns nil if there is no such name.
+func (f *File) visibleName(n *NameNode, sheet *Sheet) (*DefinedName, *Sheet) {
+	if n.Workbook != "" {
+		return nil, nil
+	}
+	scope := sheet
+	if n.Sheet != "" {
+		scope = f.sheetByName(n.Sheet)
+		if scope == nil {
+			return nil, nil
+		}
+	}
+	index := f.sheetIndex(scope)
+	if dn := f.findName(n.Name, &index); dn != nil {
+		return dn, scope
+	}
+	if n.Sheet == "" {
+		return f.Name(n.Name), sheet
+	}
+	return nil, nil
+}
+
+// findName returns the defined name with the given name, ignoring
+// case, in the given scope, where nil is the workbook-wide scope.
+func (f *File) findName(name string, localSheetID *int) *DefinedName {
+	for _, dn := range f.DefinedNames {
+		if !strings.EqualFold(dn.Name, name) {
+			continue
+		}
+		if localSheetID == nil && dn.localSheetID == nil {
+			return dn
+		}
+		if localSheetID != nil && dn.localSheetID != nil && *localSheetID == *dn.localSheetID {
+			return dn
+		}
+	}
+	return nil
+}
+
+// sheetIndex returns the position of the sheet in the File, or -1 if
+// it doesn't belong to the File.
+func (f *File) sheetIndex(sheet *Sheet) int {
+	for i, s := range f.Sheets {
+		if s == sheet {
+			return i
+		}
+	}
+	return -1
+}
+
+// sheetByName returns the sheet with the given name, ignoring case
+// as Excel does, or nil if there is no such sheet.
+func (f *File) sheetByName(name string) *Sheet {
+	for _, s := range f.Sheets {
+		if strings.EqualFold(s.Name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// validateDefinedName checks the name against Excel's rules for
+// defined names.
+func validateDefinedName(name string) error {
+	if name == "" {
+		return fmt.Errorf("a name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > definedNameMaxLen {
+		return fmt.Errorf("a name cannot be longer than %d characters", definedNameMaxLen)
+	}
+	if strings.HasPrefix(strings.ToLower(name), builtInNamePrefix) {
+		return fmt.Errorf("names starting with %q are reserved for Excel", builtInNamePrefix)
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r), r == '_', r == '\\':
+		case i > 0 && (unicode.IsDigit(r) || r == '.' || r == '?'):
+		default:
+			return fmt.Errorf("a name cannot contain %q at position %d", r, i)
+		}
+	}
+	if col, row, err := parseRangePart(name); err == nil && col >= 0 && row >= 0 {
+		return fmt.Errorf("a name cannot look like a cell reference")
+	}
+	if looksLikeR1C1(name) {
+		return fmt.Errorf("a name cannot look like an R1C1 reference")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestDefinedName(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("ValidateDefinedName", func(c *qt.C) {
+		for _, name := range []string{"Totals", "_tax_rate", "\\path", "Q1.Sales", "Größe", "ABC", "Sheet1", "Is_it?"} {
+			c.Assert(validateDefinedName(name), qt.IsNil, qt.Commentf(name))
+		}
+		for _, name := range []string{"", "1st", ".dot", "has space", "a-b", "A1", "xfd1048576", "TAX2019", "R", "c", "R1C1", "r2c", "_xlnm.Print_Area", strings.Repeat("a", 256)} {
+			c.Assert(validateDefinedName(name), qt.Not(qt.IsNil), qt.Commentf(name))
+		}
+	})
+
+	csRunO(c, "AddAndResolveNames", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		summary, err := f.AddSheet("My Summary")
+		c.Assert(err, qt.IsNil)
+		other := &Sheet{Name: "Other"}
+
+		global, err := f.AddName("Sales", "=Data!$B$2:$B$20", nil)
+		c.Assert(err, qt.IsNil)
+		c.Assert(global.SheetIndex(), qt.Equals, -1)
+		c.Assert(global.Data, qt.Equals, "Data!$B$2:$B$20")
+
+		local, err := f.AddName("Sales", "'My Summary'!$A$1", summary)
+		c.Assert(err, qt.IsNil)
+		c.Assert(local.SheetIndex(), qt.Equals, 1)
+		firstSheetName, err := f.AddName("Header", "$A$1:$D$1", data)
+		c.Assert(err, qt.IsNil)
+		c.Assert(firstSheetName.SheetIndex(), qt.Equals, 0)
+
+		_, err = f.AddName("SALES", "Data!$C$2", nil)
+		c.Assert(err, qt.ErrorMatches, `AddName\("SALES"\): the name already exists in this scope`)
+		_, err = f.AddName("B2", "Data!$C$2", nil)
+		c.Assert(err, qt.Not(qt.IsNil))
+		_, err = f.AddName("Elsewhere", "Data!$C$2", other)
+		c.Assert(err, qt.Not(qt.IsNil))
+		_, err = f.AddName("Empty", " ", nil)
+		c.Assert(err, qt.Not(qt.IsNil))
+
+		c.Assert(f.Name("sales"), qt.Equals, global)
+		c.Assert(f.Name("Header"), qt.IsNil)
+		c.Assert(data.Names(), qt.HasLen, 1)
+		c.Assert(data.Names()[0], qt.Equals, firstSheetName)
+		c.Assert(summary.Names(), qt.HasLen, 1)
+		c.Assert(summary.Names()[0], qt.Equals, local)
+
+		sheet, r, err := f.ResolveName("Sales", nil)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet, qt.Equals, data)
+		c.Assert(r, qt.Equals, CellRange{1, 1, 1, 19})
+
+		sheet, r, err = f.ResolveName("Sales", summary)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet, qt.Equals, summary)
+		c.Assert(r, qt.Equals, CellRange{0, 0, 0, 0})
+
+		sheet, r, err = f.ResolveName("header", data)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet, qt.Equals, data)
+		c.Assert(r, qt.Equals, CellRange{0, 0, 3, 0})
+
+		_, _, err = f.ResolveName("Header", summary)
+		c.Assert(err, qt.Not(qt.IsNil))
+
+		rate, err := f.AddName("Rate", "0.2", nil)
+		c.Assert(err, qt.IsNil)
+		rate.Comment = "Tax rate"
+		rate.Hidden = true
+		_, _, err = f.ResolveName("Rate", nil)
+		c.Assert(err, qt.Not(qt.IsNil))
+
+		var buf bytes.Buffer
+		err = f.Write(&buf)
+		c.Assert(err, qt.IsNil)
+		f, err = OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+
+		c.Assert(f.DefinedNames, qt.HasLen, 4)
+		c.Assert(f.Sheets[0].Names(), qt.HasLen, 1)
+		c.Assert(f.Sheets[0].Names()[0].Name, qt.Equals, "Header")
+		sheet, r, err = f.ResolveName("Sales", f.Sheets[1])
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet, qt.Equals, f.Sheets[1])
+		c.Assert(r, qt.Equals, CellRange{0, 0, 0, 0})
+		rate = f.Name("Rate")
+		c.Assert(rate.Comment, qt.Equals, "Tax rate")
+		c.Assert(rate.Hidden, qt.IsTrue)
+	})
+
+	c.Run("XLSXDefinedName", func(c *qt.C) {
+		one := 1
+		x := xlsxDefinedName{
+			Data:         "Data!$A$1",
+			Name:         "Macro",
+			Description:  "Runs the report",
+			ShortcutKey:  "R",
+			LocalSheetID: &one,
+			Function:     true,
+		}
+		dn := readDefinedName(x)
+		c.Assert(dn.Name, qt.Equals, "Macro")
+		c.Assert(dn.SheetIndex(), qt.Equals, 1)
+		// Attributes without a field of their own are written back
+		c.Assert(dn.makeXLSXDefinedName(), qt.DeepEquals, x)
+		dn.Data = "Data!$B$1"
+		x.Data = "Data!$B$1"
+		c.Assert(dn.makeXLSXDefinedName(), qt.DeepEquals, x)
+	})
+}
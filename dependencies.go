@@ -0,0 +1,429 @@
+package xlsx
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Dependencies returns the cells that the formula in the cell at
+// cellRef, e.g. "Sheet1!B2", depends on, either directly or through
+// the formulas of other cells.  References to ranges and defined names
+// are followed, and only cells that hold a value or a formula are
+// included.  The cells are given as references like cellRef, ordered
+// by sheet, then row, then column.
+func (f *File) Dependencies(cellRef string) ([]string, error) {
+	wrap := func(err error) ([]string, error) {
+		return nil, fmt.Errorf("Dependencies(%q): %w", cellRef, err)
+	}
+	g, err := f.dependencyGraph()
+	if err != nil {
+		return wrap(err)
+	}
+	start, err := g.parseCellKey(cellRef)
+	if err != nil {
+		return wrap(err)
+	}
+
+	found := make(map[evalCellKey]bool)
+	queue := []evalCellKey{start}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, a := range g.precedents[key] {
+			for _, dep := range g.cellsWithin(a) {
+				if found[dep] {
+					continue
+				}
+				found[dep] = true
+				if _, ok := g.precedents[dep]; ok {
+					queue = append(queue, dep)
+				}
+			}
+		}
+	}
+	return g.cellRefs(g.sortedKeys(found)), nil
+}
+
+// Dependents returns the formula cells whose values depend on the cell
+// at cellRef, e.g. "Sheet1!B2", either directly or through the formulas
+// of other cells.  These are the cells that need to be recalculated
+// when the cell changes.  The cell need not hold anything itself.  The
+// cells are given as references like cellRef, ordered by sheet, then
+// row, then column.
+func (f *File) Dependents(cellRef string) ([]string, error) {
+	wrap := func(err error) ([]string, error) {
+		return nil, fmt.Errorf("Dependents(%q): %w", cellRef, err)
+	}
+	g, err := f.dependencyGraph()
+	if err != nil {
+		return wrap(err)
+	}
+	start, err := g.parseCellKey(cellRef)
+	if err != nil {
+		return wrap(err)
+	}
+
+	found := make(map[evalCellKey]bool)
+	queue := g.directDependents(start)
+	for _, key := range queue {
+		found[key] = true
+	}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, dep := range g.dependents[key] {
+			if !found[dep] {
+				found[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return g.cellRefs(g.sortedKeys(found)), nil
+}
+
+// CircularReferences finds the groups of formula cells that depend
+// upon themselves, which Excel can't calculate.  Each group holds the
+// cells of one circular chain, ordered as by Dependencies, and the
+// groups are ordered by their first cell.
+func (f *File) CircularReferences() ([][]string, error) {
+	g, err := f.dependencyGraph()
+	if err != nil {
+		return nil, fmt.Errorf("CircularReferences: %w", err)
+	}
+
+	// Tarjan's algorithm for finding strongly connected components
+	index := make(map[evalCellKey]int)
+	lowLink := make(map[evalCellKey]int)
+	onStack := make(map[evalCellKey]bool)
+	var stack []evalCellKey
+	var cycles []map[evalCellKey]bool
+	var connect func(key evalCellKey)
+	connect = func(key evalCellKey) {
+		index[key] = len(index)
+		lowLink[key] = index[key]
+		stack = append(stack, key)
+		onStack[key] = true
+		selfLoop := false
+		for _, dep := range g.formulaPrecedents[key] {
+			if dep == key {
+				selfLoop = true
+			}
+			if _, seen := index[dep]; !seen {
+				connect(dep)
+				lowLink[key] = minInt(lowLink[key], lowLink[dep])
+			} else if onStack[dep] {
+				lowLink[key] = minInt(lowLink[key], index[dep])
+			}
+		}
+		if lowLink[key] != index[key] {
+			return
+		}
+		component := make(map[evalCellKey]bool)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component[top] = true
+			if top == key {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			cycles = append(cycles, component)
+		}
+	}
+	for _, key := range g.order {
+		if _, seen := index[key]; !seen {
+			connect(key)
+		}
+	}
+
+	sorted := make([][]evalCellKey, len(cycles))
+	for i, cycle := range cycles {
+		sorted[i] = g.sortedKeys(cycle)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return g.less(sorted[i][0], sorted[j][0])
+	})
+	result := make([][]string, len(sorted))
+	for i, keys := range sorted {
+		result[i] = g.cellRefs(keys)
+	}
+	return result, nil
+}
+
+// refArea is a range of cells on a sheet.
+type refArea struct {
+	sheet *Sheet
+	r     CellRange
+}
+
+// formulaGraph records the ranges of cells that each formula in a File
+// refers to.
+type formulaGraph struct {
+	file       *File
+	sheetIndex map[*Sheet]int
+	cells      map[*Sheet]*sheetCells
+	precedents map[evalCellKey][]refArea
+	// formulaPrecedents holds the formula cells that each formula
+	// refers to directly, in order
+	formulaPrecedents map[evalCellKey][]evalCellKey
+	// dependents holds the formula cells that refer directly to each
+	// cell that holds a value or a formula
+	dependents map[evalCellKey][]evalCellKey
+	// order holds the formula cells ordered by sheet, row and column
+	order []evalCellKey
+}
+
+// dependencyGraph scans every formula in the File to build its
+// formulaGraph.
+func (f *File) dependencyGraph() (*formulaGraph, error) {
+	e := newEvaluator(f)
+	g := &formulaGraph{
+		file:              f,
+		sheetIndex:        make(map[*Sheet]int),
+		cells:             make(map[*Sheet]*sheetCells),
+		precedents:        make(map[evalCellKey][]refArea),
+		formulaPrecedents: make(map[evalCellKey][]evalCellKey),
+		dependents:        make(map[evalCellKey][]evalCellKey),
+	}
+	for i, sheet := range f.Sheets {
+		sd := e.sheetData(sheet)
+		if e.err != nil {
+			return nil, e.err
+		}
+		g.sheetIndex[sheet] = i
+		g.cells[sheet] = newSheetCells(sd)
+		for _, pos := range sd.formulaOrder {
+			key := evalCellKey{sheet: sheet, pos: pos}
+			node, err := ParseFormula(sd.formulas[pos])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", g.cellRef(key), err)
+			}
+			areas, err := g.references(node, sheet, make(map[*DefinedName]bool))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", g.cellRef(key), err)
+			}
+			g.precedents[key] = areas
+			g.order = append(g.order, key)
+		}
+	}
+	sort.SliceStable(g.order, func(i, j int) bool {
+		return g.less(g.order[i], g.order[j])
+	})
+	for _, key := range g.order {
+		for _, a := range g.precedents[key] {
+			for _, cell := range g.cellsWithin(a) {
+				g.dependents[cell] = append(g.dependents[cell], key)
+				if _, ok := g.precedents[cell]; ok {
+					g.formulaPrecedents[key] = append(g.formulaPrecedents[key], cell)
+				}
+			}
+		}
+		cells := g.formulaPrecedents[key]
+		sort.Slice(cells, func(i, j int) bool {
+			return g.less(cells[i], cells[j])
+		})
+	}
+	return g, nil
+}
+
+// sheetCells indexes the cells of a sheet that hold a value or a
+// formula by row, so that the cells within a range can be found
+// without looking at every cell of the sheet.
+type sheetCells struct {
+	// rows holds the rows that have cells, in order
+	rows []int
+	// cols holds the columns of the cells in each row, in order
+	cols map[int][]int
+}
+
+func newSheetCells(sd *evalSheet) *sheetCells {
+	sc := &sheetCells{cols: make(map[int][]int)}
+	add := func(pos evalPos) {
+		if _, ok := sc.cols[pos.row]; !ok {
+			sc.rows = append(sc.rows, pos.row)
+		}
+		sc.cols[pos.row] = append(sc.cols[pos.row], pos.col)
+	}
+	for pos := range sd.values {
+		add(pos)
+	}
+	for pos := range sd.formulas {
+		if _, ok := sd.values[pos]; !ok {
+			add(pos)
+		}
+	}
+	sort.Ints(sc.rows)
+	for _, cols := range sc.cols {
+		sort.Ints(cols)
+	}
+	return sc
+}
+
+// within calls fn for each of the cells within a range, in order.
+func (sc *sheetCells) within(r CellRange, fn func(pos evalPos)) {
+	for i := sort.SearchInts(sc.rows, r.MinRow); i < len(sc.rows) && sc.rows[i] <= r.MaxRow; i++ {
+		row := sc.rows[i]
+		cols := sc.cols[row]
+		for j := sort.SearchInts(cols, r.MinCol); j < len(cols) && cols[j] <= r.MaxCol; j++ {
+			fn(evalPos{col: cols[j], row: row})
+		}
+	}
+}
+
+// references collects the ranges that a formula on the given sheet
+// refers to, including those within the defined names it uses.
+// Anything that can only be known by evaluating the formula, such as
+// the result of INDEX, is covered by the ranges that it is taken from.
+func (g *formulaGraph) references(node FormulaNode, sheet *Sheet, names map[*DefinedName]bool) ([]refArea, error) {
+	var areas []refArea
+	var err error
+	WalkFormula(node, func(n FormulaNode) bool {
+		if err != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *ReferenceNode:
+			areas = append(areas, g.referenceAreas(n.Ref, sheet)...)
+		case *NameNode:
+			dn, scope := g.file.visibleName(n, sheet)
+			if dn == nil || names[dn] {
+				return false
+			}
+			var nameNode FormulaNode
+			nameNode, err = ParseFormula(dn.Data)
+			if err != nil {
+				err = fmt.Errorf("name %q: %w", dn.Name, err)
+				return false
+			}
+			names[dn] = true
+			var nameAreas []refArea
+			nameAreas, err = g.references(nameNode, scope, names)
+			delete(names, dn)
+			areas = append(areas, nameAreas...)
+		}
+		return true
+	})
+	return areas, err
+}
+
+// referenceAreas resolves a reference on the given sheet to the ranges
+// it covers, which span several sheets for a 3D reference.  References
+// to other workbooks and to unknown sheets cover nothing.
+func (g *formulaGraph) referenceAreas(ref Reference, sheet *Sheet) []refArea {
+	if ref.Workbook != "" {
+		return nil
+	}
+	first := g.file.sheetIndex(sheet)
+	if ref.Sheet != "" {
+		first = g.file.sheetIndex(g.file.sheetByName(ref.Sheet))
+	}
+	last := first
+	if ref.LastSheet != "" {
+		last = g.file.sheetIndex(g.file.sheetByName(ref.LastSheet))
+	}
+	if first < 0 || last < 0 {
+		return nil
+	}
+	if first > last {
+		first, last = last, first
+	}
+	var areas []refArea
+	for i := first; i <= last; i++ {
+		areas = append(areas, refArea{sheet: g.file.Sheets[i], r: ref.CellRange()})
+	}
+	return areas
+}
+
+// directDependents returns the formula cells that refer directly to a
+// cell, which need not hold anything itself.
+func (g *formulaGraph) directDependents(key evalCellKey) []evalCellKey {
+	if deps, ok := g.dependents[key]; ok {
+		return append([]evalCellKey(nil), deps...)
+	}
+	// An empty cell has no entry, so look for the ranges around it
+	var deps []evalCellKey
+	for _, formula := range g.order {
+		for _, a := range g.precedents[formula] {
+			if a.sheet == key.sheet && a.r.Contains(key.pos.col, key.pos.row) {
+				deps = append(deps, formula)
+				break
+			}
+		}
+	}
+	return deps
+}
+
+// cellsWithin returns the cells within a range that hold a value or a
+// formula.
+func (g *formulaGraph) cellsWithin(a refArea) []evalCellKey {
+	sc, ok := g.cells[a.sheet]
+	if !ok {
+		return nil
+	}
+	var cells []evalCellKey
+	sc.within(a.r, func(pos evalPos) {
+		cells = append(cells, evalCellKey{sheet: a.sheet, pos: pos})
+	})
+	return cells
+}
+
+// parseCellKey parses a reference to a single cell on a sheet of the
+// File, e.g. "Sheet1!B2".
+func (g *formulaGraph) parseCellKey(cellRef string) (evalCellKey, error) {
+	sheetName, ref, err := splitSheetRef(cellRef)
+	if err != nil {
+		return evalCellKey{}, err
+	}
+	if sheetName == "" {
+		return evalCellKey{}, fmt.Errorf("the reference must include a sheet")
+	}
+	sheet := g.file.sheetByName(sheetName)
+	if sheet == nil {
+		return evalCellKey{}, fmt.Errorf("no such sheet %q", sheetName)
+	}
+	r, err := ParseCellRange(ref)
+	if err != nil {
+		return evalCellKey{}, err
+	}
+	if r.Width() != 1 || r.Height() != 1 {
+		return evalCellKey{}, fmt.Errorf("a single cell is required")
+	}
+	return evalCellKey{sheet: sheet, pos: evalPos{col: r.MinCol, row: r.MinRow}}, nil
+}
+
+// less orders cells by sheet, then row, then column.
+func (g *formulaGraph) less(a, b evalCellKey) bool {
+	if a.sheet != b.sheet {
+		return g.sheetIndex[a.sheet] < g.sheetIndex[b.sheet]
+	}
+	if a.pos.row != b.pos.row {
+		return a.pos.row < b.pos.row
+	}
+	return a.pos.col < b.pos.col
+}
+
+func (g *formulaGraph) cellRef(key evalCellKey) string {
+	return sheetRef(key.sheet.Name, GetCellIDStringFromCoords(key.pos.col, key.pos.row))
+}
+
+// sortedKeys returns a set of cells in order.
+func (g *formulaGraph) sortedKeys(cells map[evalCellKey]bool) []evalCellKey {
+	keys := make([]evalCellKey, 0, len(cells))
+	for key := range cells {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return g.less(keys[i], keys[j])
+	})
+	return keys
+}
+
+func (g *formulaGraph) cellRefs(keys []evalCellKey) []string {
+	refs := make([]string, len(keys))
+	for i, key := range keys {
+		refs[i] = g.cellRef(key)
+	}
+	return refs
+}
@@ -0,0 +1,127 @@
+package xlsx
+
+import (
+	"fmt"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestDependencies(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "DependenciesAndDependents", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		inputs, err := f.AddSheet("Inputs")
+		c.Assert(err, qt.IsNil)
+		model, err := f.AddSheet("My Model")
+		c.Assert(err, qt.IsNil)
+
+		set := func(sheet *Sheet, ref string, value interface{}) {
+			col, row, err := GetCoordsFromCellIDString(ref)
+			c.Assert(err, qt.IsNil)
+			cell, err := sheet.Cell(row, col)
+			c.Assert(err, qt.IsNil)
+			if formula, ok := value.(string); ok {
+				cell.SetFormula(formula)
+				return
+			}
+			cell.SetValue(value)
+		}
+		set(inputs, "A1", 10)
+		set(inputs, "B1", 0.2)
+		set(inputs, "A2", 5)
+		set(inputs, "Z9", 1)
+		set(model, "A1", "Inputs!A1*Inputs!A2")
+		set(model, "B1", "A2+1")
+		set(model, "C1", "D1+1")
+		set(model, "D1", "C1*2")
+		set(model, "E1", "E1")
+		set(model, "F1", "SUM('Inputs:My Model'!Z9)")
+		set(model, "A2", "A1*(1+TaxRate)")
+		set(model, "A3", "SUM(Inputs!A:A)")
+		_, err = f.AddName("TaxRate", "Inputs!$B$1", nil)
+		c.Assert(err, qt.IsNil)
+
+		deps, err := f.Dependencies("'My Model'!B1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(deps, qt.DeepEquals, []string{"Inputs!A1", "Inputs!B1", "Inputs!A2", "'My Model'!A1", "'My Model'!A2"})
+
+		deps, err = f.Dependencies("'My Model'!F1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(deps, qt.DeepEquals, []string{"Inputs!Z9"})
+
+		deps, err = f.Dependencies("'My Model'!C1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(deps, qt.DeepEquals, []string{"'My Model'!C1", "'My Model'!D1"})
+
+		deps, err = f.Dependencies("Inputs!A1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(deps, qt.HasLen, 0)
+
+		dependents, err := f.Dependents("inputs!$A$1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(dependents, qt.DeepEquals, []string{"'My Model'!A1", "'My Model'!B1", "'My Model'!A2", "'My Model'!A3"})
+
+		dependents, err = f.Dependents("Inputs!B1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(dependents, qt.DeepEquals, []string{"'My Model'!B1", "'My Model'!A2"})
+
+		// An empty cell within a referenced range
+		dependents, err = f.Dependents("Inputs!A100")
+		c.Assert(err, qt.IsNil)
+		c.Assert(dependents, qt.DeepEquals, []string{"'My Model'!A3"})
+
+		cycles, err := f.CircularReferences()
+		c.Assert(err, qt.IsNil)
+		c.Assert(cycles, qt.DeepEquals, [][]string{
+			{"'My Model'!C1", "'My Model'!D1"},
+			{"'My Model'!E1"},
+		})
+
+		for _, ref := range []string{"A1", "Nowhere!A1", "Inputs!A1:B2", "Inputs!"} {
+			_, err = f.Dependencies(ref)
+			c.Assert(err, qt.Not(qt.IsNil), qt.Commentf(ref))
+			_, err = f.Dependents(ref)
+			c.Assert(err, qt.Not(qt.IsNil), qt.Commentf(ref))
+		}
+	})
+
+	c.Run("LongChain", func(c *qt.C) {
+		f := NewFile()
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		const n = 5000
+		for i := 0; i < n; i++ {
+			cell, err := sheet.Cell(i, 0)
+			c.Assert(err, qt.IsNil)
+			cell.SetInt(i)
+			cell, err = sheet.Cell(i, 1)
+			c.Assert(err, qt.IsNil)
+			if i == 0 {
+				cell.SetFormula("A1")
+			} else {
+				cell.SetFormula(fmt.Sprintf("A%d+B%d", i+1, i))
+			}
+		}
+		dependents, err := f.Dependents("Sheet1!A1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(dependents, qt.HasLen, n)
+		c.Assert(dependents[n-1], qt.Equals, fmt.Sprintf("Sheet1!B%d", n))
+		deps, err := f.Dependencies(fmt.Sprintf("Sheet1!B%d", n))
+		c.Assert(err, qt.IsNil)
+		c.Assert(deps, qt.HasLen, 2*n-1)
+		c.Assert(deps[:3], qt.DeepEquals, []string{"Sheet1!A1", "Sheet1!B1", "Sheet1!A2"})
+	})
+
+	c.Run("InvalidFormula", func(c *qt.C) {
+		f := NewFile()
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		cell, err := sheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		cell.SetFormula("SUM(")
+		_, err = f.CircularReferences()
+		c.Assert(err, qt.ErrorMatches, `CircularReferences: Sheet1!A1: .*`)
+	})
+}
@@ -67,163 +67,24 @@ func (dvr *DiskVRow) AddCell() *Cell {
 }
 
 func (dvr *DiskVRow) readCell(key string) (*Cell, error) {
-	var err error
-	var cellType int
-	var hasStyle, hasDataValidation bool
-	var cellIsNil bool
-
 	b, err := dvr.store.Read(key)
 	if err != nil {
 		return nil, err
 	}
-
-	buf := bytes.NewReader(b)
-	if cellIsNil, err = readBool(buf); err != nil {
-		return nil, err
-	}
-	if cellIsNil {
-		if err = readEndOfRecord(buf); err != nil {
-			return nil, err
-		}
-		return nil, nil
-	}
-	c := &Cell{}
-	if c.Value, err = readString(buf); err != nil {
-		return c, err
-	}
-	if c.formula, err = readString(buf); err != nil {
-		return c, err
-	}
-	if hasStyle, err = readBool(buf); err != nil {
-		return c, err
-	}
-	if c.NumFmt, err = readString(buf); err != nil {
-		return c, err
-	}
-	if c.date1904, err = readBool(buf); err != nil {
-		return c, err
-	}
-	if c.Hidden, err = readBool(buf); err != nil {
-		return c, err
-	}
-	if c.HMerge, err = readInt(buf); err != nil {
-		return c, err
-	}
-	if c.VMerge, err = readInt(buf); err != nil {
-		return c, err
-	}
-	if cellType, err = readInt(buf); err != nil {
-		return c, err
-	}
-	c.cellType = CellType(cellType)
-	if hasDataValidation, err = readBool(buf); err != nil {
-		return c, err
-	}
-	if c.Hyperlink.DisplayString, err = readString(buf); err != nil {
-		return c, err
-	}
-	if c.Hyperlink.Link, err = readString(buf); err != nil {
-		return c, err
-	}
-	if c.Hyperlink.Tooltip, err = readString(buf); err != nil {
-		return c, err
-	}
-	if c.num, err = readInt(buf); err != nil {
-		return c, err
-	}
-	if c.RichText, err = readRichText(buf); err != nil {
-		return c, err
-	}
-	if err = readEndOfRecord(buf); err != nil {
-		return c, err
-	}
-	if hasStyle {
-		if c.style, err = readStyle(buf); err != nil {
-			return c, err
-		}
-	}
-	if hasDataValidation {
-		if c.DataValidation, err = readDataValidation(buf); err != nil {
-			return c, err
-		}
+	cell, err := readCell(bytes.NewReader(b))
+	if cell != nil {
+		cell.Row = dvr.row
 	}
-	return c, nil
+	return cell, err
 }
 
 func (dvr *DiskVRow) writeCell(c *Cell) error {
-	var err error
 	dvr.buf.Reset()
-	if c == nil {
-		if err := writeBool(&dvr.buf, true); err != nil {
-
-			return err
-		}
-		return writeEndOfRecord(&dvr.buf)
-	}
-	if err := writeBool(&dvr.buf, false); err != nil {
-		return err
-	}
-	if err = writeString(&dvr.buf, c.Value); err != nil {
-		return err
-	}
-	if err = writeString(&dvr.buf, c.formula); err != nil {
-		return err
-	}
-	if err = writeBool(&dvr.buf, c.style != nil); err != nil {
-		return err
-	}
-	if err = writeString(&dvr.buf, c.NumFmt); err != nil {
-		return err
-	}
-	if err = writeBool(&dvr.buf, c.date1904); err != nil {
-		return err
-	}
-	if err = writeBool(&dvr.buf, c.Hidden); err != nil {
-		return err
-	}
-	if err = writeInt(&dvr.buf, c.HMerge); err != nil {
-		return err
-	}
-	if err = writeInt(&dvr.buf, c.VMerge); err != nil {
-		return err
-	}
-	if err = writeInt(&dvr.buf, int(c.cellType)); err != nil {
-		return err
-	}
-	if err = writeBool(&dvr.buf, c.DataValidation != nil); err != nil {
-		return err
-	}
-	if err = writeString(&dvr.buf, c.Hyperlink.DisplayString); err != nil {
+	if err := writeCell(&dvr.buf, c); err != nil {
 		return err
 	}
-	if err = writeString(&dvr.buf, c.Hyperlink.Link); err != nil {
-		return err
-	}
-	if err = writeString(&dvr.buf, c.Hyperlink.Tooltip); err != nil {
-		return err
-	}
-	if err = writeInt(&dvr.buf, c.num); err != nil {
-		return err
-	}
-	if err = writeRichText(&dvr.buf, c.RichText); err != nil {
-		return err
-	}
-	if err = writeEndOfRecord(&dvr.buf); err != nil {
-		return err
-	}
-	if c.style != nil {
-		if err = writeStyle(&dvr.buf, c.style); err != nil {
-			return err
-		}
-	}
-	if c.DataValidation != nil {
-		if err = writeDataValidation(&dvr.buf, c.DataValidation); err != nil {
-			return err
-		}
-	}
 	key := dvr.row.makeCellKey(c.num)
 	return dvr.store.Write(key, dvr.buf.Bytes())
-
 }
 
 func (dvr *DiskVRow) setCurrentCell(cell *Cell) {
@@ -245,6 +106,59 @@ func (dvr *DiskVRow) PushCell(c *Cell) {
 	dvr.setCurrentCell(c)
 }
 
+// ShiftCells moves the cells at or beyond the column index at by n
+// columns, removing the -n cells from index at onwards when n is
+// negative.  The cells are moved within the persistant store.
+func (dvr *DiskVRow) ShiftCells(at, n int) {
+	if dvr.currentCell != nil && dvr.currentCell.Modified() {
+		if err := dvr.writeCell(dvr.currentCell); err != nil {
+			panic(err.Error())
+		}
+	}
+	dvr.currentCell = nil
+	move := func(from, to int) {
+		key := dvr.row.makeCellKey(from)
+		cell, err := dvr.readCell(key)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return
+			}
+			panic(err.Error())
+		}
+		dvr.store.Erase(key)
+		if cell == nil {
+			return
+		}
+		cell.Row = dvr.row
+		cell.num = to
+		if err := dvr.writeCell(cell); err != nil {
+			panic(err.Error())
+		}
+	}
+	if n > 0 {
+		for ci := dvr.maxCol; ci >= at; ci-- {
+			move(ci, ci+n)
+		}
+		if dvr.maxCol >= at {
+			dvr.maxCol += n
+		}
+		return
+	}
+	end := at - n
+	for ci := at; ci < end && ci <= dvr.maxCol; ci++ {
+		dvr.store.Erase(dvr.row.makeCellKey(ci))
+	}
+	for ci := end; ci <= dvr.maxCol; ci++ {
+		move(ci, ci+n)
+	}
+	switch {
+	case dvr.maxCol >= end:
+		dvr.maxCol += n
+	case dvr.maxCol >= at:
+		dvr.maxCol = at - 1
+	}
+}
+
 func (dvr *DiskVRow) GetCell(colIdx int) *Cell {
 	if dvr.currentCell != nil {
 		if dvr.currentCell.num == colIdx {
@@ -297,6 +211,9 @@ func (dvr *DiskVRow) ForEachCell(cvf CellVisitorFunc, option ...CellVisitorOptio
 			if err != nil {
 				return err
 			}
+			if cell != nil {
+				cell.Row = dvr.row
+			}
 		}
 
 		err = fn(ci, cell)
@@ -426,6 +343,7 @@ func (cs *DiskVCellStore) MoveRow(r *Row, index int) error {
 				return err
 			}
 			c.Row = r
+			cBuf.Reset()
 			err = writeCell(&cBuf, c)
 			if err != nil {
 				return err
@@ -880,6 +798,14 @@ func writeStyle(buf *bytes.Buffer, s *Style) error {
 	if err = writeBool(buf, s.ApplyAlignment); err != nil {
 		return err
 	}
+	if err = writeBool(buf, s.NamedStyleIndex != nil); err != nil {
+		return err
+	}
+	if s.NamedStyleIndex != nil {
+		if err = writeInt(buf, *s.NamedStyleIndex); err != nil {
+			return err
+		}
+	}
 	if err = writeEndOfRecord(buf); err != nil {
 		return err
 	}
@@ -913,6 +839,17 @@ func readStyle(reader *bytes.Reader) (*Style, error) {
 	if s.ApplyAlignment, err = readBool(reader); err != nil {
 		return s, err
 	}
+	hasNamedStyle, err := readBool(reader)
+	if err != nil {
+		return s, err
+	}
+	if hasNamedStyle {
+		index, err := readInt(reader)
+		if err != nil {
+			return s, err
+		}
+		s.NamedStyleIndex = &index
+	}
 	if err = readEndOfRecord(reader); err != nil {
 		return s, err
 	}
@@ -1029,6 +966,15 @@ func writeRow(buf *bytes.Buffer, r *Row) error {
 	if err = writeBool(buf, r.isCustom); err != nil {
 		return err
 	}
+	if err = writeBool(buf, r.customHeight); err != nil {
+		return err
+	}
+	if err = writeBool(buf, r.Collapsed); err != nil {
+		return err
+	}
+	if err = writeBool(buf, r.style != nil); err != nil {
+		return err
+	}
 	if err = writeInt(buf, r.num); err != nil {
 		return err
 	}
@@ -1038,6 +984,11 @@ func writeRow(buf *bytes.Buffer, r *Row) error {
 	if err = writeEndOfRecord(buf); err != nil {
 		return err
 	}
+	if r.style != nil {
+		if err = writeStyle(buf, r.style); err != nil {
+			return err
+		}
+	}
 	return writeGroupSeparator(buf)
 }
 
@@ -1092,6 +1043,15 @@ func writeCell(buf *bytes.Buffer, c *Cell) error {
 	if err = writeString(buf, c.Hyperlink.Tooltip); err != nil {
 		return err
 	}
+	if err = writeString(buf, c.Hyperlink.Location); err != nil {
+		return err
+	}
+	if err = writeString(buf, c.arrayRef); err != nil {
+		return err
+	}
+	if err = writeBool(buf, c.dynamicArray); err != nil {
+		return err
+	}
 	if err = writeInt(buf, c.num); err != nil {
 		return err
 	}
@@ -1144,6 +1104,18 @@ func readRow(reader *bytes.Reader, store *diskv.Diskv, sheet *Sheet) (*Row, erro
 	if err != nil {
 		return nil, err
 	}
+	r.customHeight, err = readBool(reader)
+	if err != nil {
+		return nil, err
+	}
+	r.Collapsed, err = readBool(reader)
+	if err != nil {
+		return nil, err
+	}
+	hasStyle, err := readBool(reader)
+	if err != nil {
+		return nil, err
+	}
 	r.num, err = readInt(reader)
 	if err != nil {
 		return nil, err
@@ -1156,6 +1128,11 @@ func readRow(reader *bytes.Reader, store *diskv.Diskv, sheet *Sheet) (*Row, erro
 	if err != nil {
 		return r, err
 	}
+	if hasStyle {
+		if r.style, err = readStyle(reader); err != nil {
+			return r, err
+		}
+	}
 	return r, nil
 }
 
@@ -1214,6 +1191,15 @@ func readCell(reader *bytes.Reader) (*Cell, error) {
 	if c.Hyperlink.Tooltip, err = readString(reader); err != nil {
 		return c, err
 	}
+	if c.Hyperlink.Location, err = readString(reader); err != nil {
+		return c, err
+	}
+	if c.arrayRef, err = readString(reader); err != nil {
+		return c, err
+	}
+	if c.dynamicArray, err = readBool(reader); err != nil {
+		return c, err
+	}
 	if c.num, err = readInt(reader); err != nil {
 		return c, err
 	}
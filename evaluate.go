@@ -0,0 +1,891 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The error values that a formula can produce, in the form that an
+// evalValue holds them.
+const (
+	errNull  = string(ErrNull)
+	errDiv0  = string(ErrDiv0)
+	errValue = string(ErrValue)
+	errRef   = string(ErrRef)
+	errName  = string(ErrName)
+	errNum   = string(ErrNum)
+	errNA    = string(ErrNA)
+)
+
+// FormulaValue is the result of evaluating a cell.  Type is one of
+// CellTypeNumeric, CellTypeString, CellTypeBool or CellTypeError, and
+// Value is held in the same form as the Value of a Cell of that type,
+// e.g. "1" for TRUE, or "#DIV/0!" for an error.
+type FormulaValue struct {
+	Type  CellType
+	Value string
+}
+
+// Evaluate calculates the value of the cell at cellRef, e.g. "B2", on
+// the given sheet.  The formulas of any cells that the cell depends
+// upon are evaluated as needed, using the File as it is now rather than
+// any cached values, but nothing in the File is changed.  The value of
+// a cell without a formula is returned as it is.
+//
+// An error is returned if a formula can't be parsed or is part of a
+// circular reference.  Errors within the calculation itself, such as a
+// division by zero, are returned as a FormulaValue of type
+// CellTypeError, just as Excel would show them.
+func (f *File) Evaluate(sheet *Sheet, cellRef string) (FormulaValue, error) {
+	wrap := func(err error) (FormulaValue, error) {
+		return FormulaValue{}, fmt.Errorf("Evaluate(%q): %w", cellRef, err)
+	}
+	if f.sheetIndex(sheet) < 0 {
+		return wrap(fmt.Errorf("sheet %q does not belong to this file", sheet.Name))
+	}
+	r, err := ParseCellRange(cellRef)
+	if err != nil {
+		return wrap(err)
+	}
+	if r.Width() != 1 || r.Height() != 1 {
+		return wrap(fmt.Errorf("a single cell is required"))
+	}
+	e := newEvaluator(f)
+	v := e.cellValue(sheet, r.MinCol, r.MinRow)
+	if e.err != nil {
+		return wrap(e.err)
+	}
+	return v.formulaValue(), nil
+}
+
+// Recalculate evaluates every formula in the File and stores the
+// results in their cells, from where they are written out as the
+// cached values of the formulas.  Nothing is changed if an error is
+// returned.
+func (f *File) Recalculate() error {
+	wrap := func(err error) error {
+		return fmt.Errorf("Recalculate: %w", err)
+	}
+	e := newEvaluator(f)
+	results := make([]map[evalPos]evalValue, len(f.Sheets))
+	for i, sheet := range f.Sheets {
+		sd := e.sheetData(sheet)
+		if e.err != nil {
+			return wrap(e.err)
+		}
+		results[i] = make(map[evalPos]evalValue, len(sd.formulas))
+		for _, pos := range sd.formulaOrder {
+			results[i][pos] = e.cellValue(sheet, pos.col, pos.row)
+		}
+		if e.err != nil {
+			return wrap(e.err)
+		}
+	}
+	for i, sheet := range f.Sheets {
+		for _, pos := range e.sheets[sheet].formulaOrder {
+			row, err := sheet.Row(pos.row)
+			if err != nil {
+				return wrap(err)
+			}
+			cell := row.GetCell(pos.col)
+			cell.setFormulaResult(results[i][pos].formulaValue())
+			// Pushing the cell back makes stores that only hold the
+			// current cell in memory, such as DiskV, persist it
+			row.PushCell(cell)
+		}
+	}
+	return nil
+}
+
+// setFormulaResult sets the cached value of a formula cell, without
+// changing its formula.
+func (c *Cell) setFormulaResult(v FormulaValue) {
+	c.updatable()
+	c.Value = v.Value
+	c.RichText = nil
+	c.cellType = v.Type
+	if v.Type == CellTypeString {
+		c.cellType = CellTypeStringFormula
+	}
+	c.modified = true
+}
+
+type evalKind int
+
+const (
+	evalBlank evalKind = iota
+	evalNumber
+	evalString
+	evalBool
+	evalError
+	evalArray
+	evalReference
+)
+
+// evalValue is a value within the evaluation of a formula.  Depending
+// on its kind it holds a number, a string or error code in str, a
+// boolean, an array, or a reference to cells that have yet to be read.
+type evalValue struct {
+	kind evalKind
+	num  float64
+	str  string
+	b    bool
+	arr  [][]evalValue
+	ref  *evalRef
+}
+
+// evalRef is a reference to one or more ranges of cells on a sheet.
+type evalRef struct {
+	sheet *Sheet
+	areas []CellRange
+}
+
+var blankValue = evalValue{}
+
+func numberValue(n float64) evalValue {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return errorValue(errNum)
+	}
+	return evalValue{kind: evalNumber, num: n}
+}
+
+func stringValue(s string) evalValue {
+	return evalValue{kind: evalString, str: s}
+}
+
+func boolValue(b bool) evalValue {
+	return evalValue{kind: evalBool, b: b}
+}
+
+func errorValue(code string) evalValue {
+	return evalValue{kind: evalError, str: code}
+}
+
+func refValue(sheet *Sheet, areas ...CellRange) evalValue {
+	return evalValue{kind: evalReference, ref: &evalRef{sheet: sheet, areas: areas}}
+}
+
+func (v evalValue) isError() bool {
+	return v.kind == evalError
+}
+
+// formulaValue converts a scalar value to the form in which it is held
+// in a Cell.
+func (v evalValue) formulaValue() FormulaValue {
+	switch v.kind {
+	case evalNumber:
+		return FormulaValue{Type: CellTypeNumeric, Value: strconv.FormatFloat(v.num, 'f', -1, 64)}
+	case evalString:
+		return FormulaValue{Type: CellTypeString, Value: v.str}
+	case evalBool:
+		if v.b {
+			return FormulaValue{Type: CellTypeBool, Value: "1"}
+		}
+		return FormulaValue{Type: CellTypeBool, Value: "0"}
+	case evalError:
+		return FormulaValue{Type: CellTypeError, Value: v.str}
+	}
+	return FormulaValue{Type: CellTypeString}
+}
+
+// toNumber converts a scalar value to a number, as the arithmetic
+// operators do.  If that isn't possible the error code is returned.
+func toNumber(v evalValue) (float64, string) {
+	switch v.kind {
+	case evalBlank:
+		return 0, ""
+	case evalNumber:
+		return v.num, ""
+	case evalBool:
+		if v.b {
+			return 1, ""
+		}
+		return 0, ""
+	case evalString:
+		s := strings.TrimSpace(v.str)
+		percent := strings.HasSuffix(s, "%")
+		if percent {
+			s = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+		}
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, errValue
+		}
+		if percent {
+			n /= 100
+		}
+		return n, ""
+	case evalError:
+		return 0, v.str
+	}
+	return 0, errValue
+}
+
+// toText converts a scalar value to a string, as the "&" operator does.
+func toText(v evalValue) (string, string) {
+	switch v.kind {
+	case evalNumber:
+		return formatNumberText(v.num), ""
+	case evalString:
+		return v.str, ""
+	case evalBool:
+		if v.b {
+			return "TRUE", ""
+		}
+		return "FALSE", ""
+	case evalError:
+		return "", v.str
+	}
+	return "", ""
+}
+
+// toBool converts a scalar value to a boolean, as IF does with its
+// condition.
+func toBool(v evalValue) (bool, string) {
+	switch v.kind {
+	case evalBlank:
+		return false, ""
+	case evalNumber:
+		return v.num != 0, ""
+	case evalBool:
+		return v.b, ""
+	case evalString:
+		switch strings.ToUpper(v.str) {
+		case "TRUE":
+			return true, ""
+		case "FALSE":
+			return false, ""
+		}
+		return false, errValue
+	case evalError:
+		return false, v.str
+	}
+	return false, errValue
+}
+
+// formatNumberText formats a number the way Excel does when it
+// converts one to text, i.e. to at most 15 significant digits.
+func formatNumberText(n float64) string {
+	n, _ = strconv.ParseFloat(strconv.FormatFloat(n, 'g', 15, 64), 64)
+	if n != 0 && (math.Abs(n) >= 1e21 || math.Abs(n) < 1e-9) {
+		return strconv.FormatFloat(n, 'E', -1, 64)
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// compareValues orders two scalar values the way Excel's comparison
+// operators do: numbers come before strings, which come before
+// booleans, strings are compared ignoring case, and a blank is
+// treated as the zero value of whatever it is compared with.
+func compareValues(a, b evalValue) int {
+	if a.kind == evalBlank {
+		a = zeroValueLike(b)
+	}
+	if b.kind == evalBlank {
+		b = zeroValueLike(a)
+	}
+	rank := func(v evalValue) int {
+		switch v.kind {
+		case evalString:
+			return 1
+		case evalBool:
+			return 2
+		}
+		return 0
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	switch a.kind {
+	case evalString:
+		return strings.Compare(strings.ToLower(a.str), strings.ToLower(b.str))
+	case evalBool:
+		switch {
+		case a.b == b.b:
+			return 0
+		case b.b:
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case a.num < b.num:
+		return -1
+	case a.num > b.num:
+		return 1
+	}
+	return 0
+}
+
+func zeroValueLike(v evalValue) evalValue {
+	switch v.kind {
+	case evalString:
+		return stringValue("")
+	case evalBool:
+		return boolValue(false)
+	}
+	return numberValue(0)
+}
+
+type evalPos struct {
+	col, row int
+}
+
+type evalCellKey struct {
+	sheet *Sheet
+	pos   evalPos
+}
+
+// evalSheet is a snapshot of the populated cells of a sheet, so that
+// cells can be read in any order without disturbing the CellStore.
+type evalSheet struct {
+	values       map[evalPos]evalValue
+	formulas     map[evalPos]string
+	formulaOrder []evalPos
+	maxCol       int
+	maxRow       int
+}
+
+// evalContext identifies the cell whose formula is being evaluated.
+type evalContext struct {
+	sheet *Sheet
+	col   int
+	row   int
+}
+
+// evaluator calculates the values of formulas over the cells of a
+// File, remembering the value of each formula cell once it is known.
+type evaluator struct {
+	file       *File
+	sheets     map[*Sheet]*evalSheet
+	results    map[evalCellKey]evalValue
+	inProgress map[evalCellKey]bool
+	names      map[*DefinedName]bool
+	// err is the first error that stopped a formula from being
+	// evaluated at all
+	err error
+}
+
+func newEvaluator(f *File) *evaluator {
+	return &evaluator{
+		file:       f,
+		sheets:     make(map[*Sheet]*evalSheet),
+		results:    make(map[evalCellKey]evalValue),
+		inProgress: make(map[evalCellKey]bool),
+		names:      make(map[*DefinedName]bool),
+	}
+}
+
+func (e *evaluator) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// sheetData returns the snapshot of the sheet, taking it on first use.
+func (e *evaluator) sheetData(sheet *Sheet) *evalSheet {
+	if sd, ok := e.sheets[sheet]; ok {
+		return sd
+	}
+	sd := &evalSheet{
+		values:   make(map[evalPos]evalValue),
+		formulas: make(map[evalPos]string),
+		maxCol:   -1,
+		maxRow:   -1,
+	}
+	e.sheets[sheet] = sd
+	err := sheet.ForEachRow(func(r *Row) error {
+		return r.ForEachCell(func(c *Cell) error {
+			pos := evalPos{col: c.num, row: r.num}
+			if c.formula != "" {
+				sd.formulas[pos] = c.formula
+				sd.formulaOrder = append(sd.formulaOrder, pos)
+			} else {
+				v := e.cellContent(c)
+				if v.kind == evalBlank {
+					return nil
+				}
+				sd.values[pos] = v
+			}
+			if pos.col > sd.maxCol {
+				sd.maxCol = pos.col
+			}
+			if pos.row > sd.maxRow {
+				sd.maxRow = pos.row
+			}
+			return nil
+		}, SkipEmptyCells)
+	}, SkipEmptyRows)
+	if err != nil {
+		e.fail(err)
+	}
+	return sd
+}
+
+// cellContent returns the value held in a cell without a formula.
+func (e *evaluator) cellContent(c *Cell) evalValue {
+	switch c.cellType {
+	case CellTypeBool:
+		return boolValue(c.Value == "1" || strings.EqualFold(c.Value, "TRUE"))
+	case CellTypeError:
+		return errorValue(c.Value)
+	case CellTypeNumeric:
+		if strings.TrimSpace(c.Value) == "" {
+			return blankValue
+		}
+		if n, err := strconv.ParseFloat(strings.TrimSpace(c.Value), 64); err == nil {
+			return numberValue(n)
+		}
+		return stringValue(c.Value)
+	case CellTypeDate:
+		if t, err := time.Parse(time.RFC3339, c.Value); err == nil {
+			return numberValue(TimeToExcelTime(t, e.file.Date1904))
+		}
+	}
+	if len(c.RichText) > 0 {
+		return stringValue(richTextToPlainText(c.RichText))
+	}
+	if c.Value == "" {
+		return blankValue
+	}
+	return stringValue(c.Value)
+}
+
+// cellValue returns the value of a cell, evaluating its formula if it
+// has one.
+func (e *evaluator) cellValue(sheet *Sheet, col, row int) evalValue {
+	sd := e.sheetData(sheet)
+	pos := evalPos{col: col, row: row}
+	formula, ok := sd.formulas[pos]
+	if !ok {
+		return sd.values[pos]
+	}
+
+	key := evalCellKey{sheet: sheet, pos: pos}
+	if v, ok := e.results[key]; ok {
+		return v
+	}
+	if e.inProgress[key] {
+		e.fail(fmt.Errorf("circular reference involving %s",
+			sheetRef(sheet.Name, GetCellIDStringFromCoords(col, row))))
+		return numberValue(0)
+	}
+	node, err := ParseFormula(formula)
+	if err != nil {
+		e.fail(err)
+		return errorValue(errName)
+	}
+	e.inProgress[key] = true
+	ctx := evalContext{sheet: sheet, col: col, row: row}
+	v := e.scalar(e.eval(node, ctx), ctx)
+	delete(e.inProgress, key)
+	if v.kind == evalBlank {
+		v = numberValue(0)
+	}
+	e.results[key] = v
+	return v
+}
+
+// scalar reduces a value to a single one.  A reference to a single
+// cell gives the value of that cell, and a reference to a row or
+// column of cells gives the cell in the same column or row as the
+// formula, i.e. the implicit intersection.  An array gives its first
+// element.
+func (e *evaluator) scalar(v evalValue, ctx evalContext) evalValue {
+	switch v.kind {
+	case evalArray:
+		if len(v.arr) == 0 || len(v.arr[0]) == 0 {
+			return blankValue
+		}
+		return v.arr[0][0]
+	case evalReference:
+		if len(v.ref.areas) != 1 {
+			return errorValue(errValue)
+		}
+		a := v.ref.areas[0]
+		switch {
+		case a.Width() == 1 && a.Height() == 1:
+			return e.cellValue(v.ref.sheet, a.MinCol, a.MinRow)
+		case a.Width() == 1 && ctx.row >= a.MinRow && ctx.row <= a.MaxRow:
+			return e.cellValue(v.ref.sheet, a.MinCol, ctx.row)
+		case a.Height() == 1 && ctx.col >= a.MinCol && ctx.col <= a.MaxCol:
+			return e.cellValue(v.ref.sheet, ctx.col, a.MinRow)
+		}
+		return errorValue(errValue)
+	}
+	return v
+}
+
+// evalGrid gives access to the cells of a single range, or the
+// elements of an array, by row and column.  usedRows and usedCols
+// limit a range to the part of it that could hold values, so that whole
+// rows and columns can be visited cheaply.
+type evalGrid struct {
+	rows, cols         int
+	usedRows, usedCols int
+	get                func(row, col int) evalValue
+}
+
+// grid returns the grid for a value, or nil if it refers to more than
+// one range.  A scalar gives a grid of one element.
+func (e *evaluator) grid(v evalValue) *evalGrid {
+	switch v.kind {
+	case evalArray:
+		g := &evalGrid{rows: len(v.arr)}
+		if g.rows > 0 {
+			g.cols = len(v.arr[0])
+		}
+		g.usedRows, g.usedCols = g.rows, g.cols
+		g.get = func(row, col int) evalValue {
+			return v.arr[row][col]
+		}
+		return g
+	case evalReference:
+		if len(v.ref.areas) != 1 {
+			return nil
+		}
+		return e.areaGrid(v.ref.sheet, v.ref.areas[0])
+	}
+	return &evalGrid{
+		rows: 1, cols: 1, usedRows: 1, usedCols: 1,
+		get: func(row, col int) evalValue { return v },
+	}
+}
+
+func (e *evaluator) areaGrid(sheet *Sheet, a CellRange) *evalGrid {
+	sd := e.sheetData(sheet)
+	clip := func(size, min, max int) int {
+		if used := max - min + 1; used < size {
+			if used < 0 {
+				return 0
+			}
+			return used
+		}
+		return size
+	}
+	return &evalGrid{
+		rows:     a.Height(),
+		cols:     a.Width(),
+		usedRows: clip(a.Height(), a.MinRow, sd.maxRow),
+		usedCols: clip(a.Width(), a.MinCol, sd.maxCol),
+		get: func(row, col int) evalValue {
+			return e.cellValue(sheet, a.MinCol+col, a.MinRow+row)
+		},
+	}
+}
+
+// forEachValue calls fn with every value within a range, array or
+// scalar, skipping the unused parts of large ranges.  It stops early if
+// fn returns false.
+func (e *evaluator) forEachValue(v evalValue, fn func(evalValue) bool) {
+	var grids []*evalGrid
+	if v.kind == evalReference {
+		for _, a := range v.ref.areas {
+			grids = append(grids, e.areaGrid(v.ref.sheet, a))
+		}
+	} else {
+		grids = append(grids, e.grid(v))
+	}
+	for _, g := range grids {
+		for row := 0; row < g.usedRows; row++ {
+			for col := 0; col < g.usedCols; col++ {
+				if !fn(g.get(row, col)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// isMulti reports whether a value holds more than one element.
+func isMulti(v evalValue) bool {
+	switch v.kind {
+	case evalArray:
+		return len(v.arr) > 1 || (len(v.arr) == 1 && len(v.arr[0]) > 1)
+	case evalReference:
+		return len(v.ref.areas) > 1 || v.ref.areas[0].Width() > 1 || v.ref.areas[0].Height() > 1
+	}
+	return false
+}
+
+// eval evaluates a node of a formula.  The result may be a reference,
+// which is left to the caller to read, as functions such as SUM and
+// INDEX work with ranges rather than the values within them.
+func (e *evaluator) eval(node FormulaNode, ctx evalContext) evalValue {
+	switch n := node.(type) {
+	case *NumberNode:
+		return numberValue(n.Value)
+	case *StringNode:
+		return stringValue(n.Value)
+	case *BoolNode:
+		return boolValue(n.Value)
+	case *ErrorNode:
+		return errorValue(n.Value)
+	case *MissingArgNode:
+		return blankValue
+	case *ParenNode:
+		return e.eval(n.Expr, ctx)
+	case *ReferenceNode:
+		return e.reference(n.Ref, ctx)
+	case *NameNode:
+		return e.name(n, ctx)
+	case *FunctionNode:
+		return e.call(n, ctx)
+	case *ArrayNode:
+		arr := make([][]evalValue, len(n.Rows))
+		for i, row := range n.Rows {
+			arr[i] = make([]evalValue, len(row))
+			for j, el := range row {
+				arr[i][j] = e.scalar(e.eval(el, ctx), ctx)
+			}
+		}
+		return evalValue{kind: evalArray, arr: arr}
+	case *UnaryOpNode:
+		v := e.eval(n.Operand, ctx)
+		switch n.Op {
+		case "@":
+			return e.scalar(v, ctx)
+		case "-":
+			return e.elementwise(v, v, ctx, func(a, _ evalValue) evalValue {
+				x, code := toNumber(a)
+				if code != "" {
+					return errorValue(code)
+				}
+				return numberValue(-x)
+			})
+		}
+		return v
+	case *PostfixOpNode:
+		if n.Op == "%" {
+			v := e.eval(n.Operand, ctx)
+			return e.elementwise(v, v, ctx, func(a, _ evalValue) evalValue {
+				x, code := toNumber(a)
+				if code != "" {
+					return errorValue(code)
+				}
+				return numberValue(x / 100)
+			})
+		}
+		// The extent of a spilled range isn't known without Excel
+		return errorValue(errRef)
+	case *BinaryOpNode:
+		switch n.Op {
+		case ":", " ", ",":
+			return e.referenceOp(n, ctx)
+		}
+		l := e.eval(n.Left, ctx)
+		r := e.eval(n.Right, ctx)
+		return e.elementwise(l, r, ctx, func(a, b evalValue) evalValue {
+			return binaryOp(n.Op, a, b)
+		})
+	}
+	// Structured references need tables, which aren't supported
+	return errorValue(errRef)
+}
+
+// elementwise applies an operator to two values.  If either holds more
+// than one element the operator is applied to each pair of elements in
+// turn, and the result is an array, with a single row or column being
+// repeated to match the other operand.
+func (e *evaluator) elementwise(l, r evalValue, ctx evalContext, op func(a, b evalValue) evalValue) evalValue {
+	if !isMulti(l) && !isMulti(r) {
+		return op(e.scalar(l, ctx), e.scalar(r, ctx))
+	}
+	lg, rg := e.grid(l), e.grid(r)
+	if lg == nil || rg == nil {
+		return errorValue(errValue)
+	}
+	size := func(l, r int) int {
+		if l == 1 {
+			return r
+		}
+		if r == 1 || l < r {
+			return l
+		}
+		return r
+	}
+	rows, cols := size(lg.usedRows, rg.usedRows), size(lg.usedCols, rg.usedCols)
+	at := func(g *evalGrid, row, col int) evalValue {
+		if g.rows == 1 {
+			row = 0
+		}
+		if g.cols == 1 {
+			col = 0
+		}
+		if row >= g.rows || col >= g.cols {
+			return errorValue(errNA)
+		}
+		return g.get(row, col)
+	}
+	arr := make([][]evalValue, rows)
+	for row := range arr {
+		arr[row] = make([]evalValue, cols)
+		for col := range arr[row] {
+			arr[row][col] = op(at(lg, row, col), at(rg, row, col))
+		}
+	}
+	return evalValue{kind: evalArray, arr: arr}
+}
+
+// binaryOp applies an arithmetic, comparison or concatenation operator
+// to two scalar values.
+func binaryOp(op string, a, b evalValue) evalValue {
+	if a.isError() {
+		return a
+	}
+	if b.isError() {
+		return b
+	}
+	switch op {
+	case "&":
+		as, _ := toText(a)
+		bs, _ := toText(b)
+		return stringValue(as + bs)
+	case "=", "<>", "<", ">", "<=", ">=":
+		return boolValue(compareOp(op, compareValues(a, b)))
+	}
+	x, code := toNumber(a)
+	if code != "" {
+		return errorValue(code)
+	}
+	y, code := toNumber(b)
+	if code != "" {
+		return errorValue(code)
+	}
+	switch op {
+	case "+":
+		return numberValue(x + y)
+	case "-":
+		return numberValue(x - y)
+	case "*":
+		return numberValue(x * y)
+	case "/":
+		if y == 0 {
+			return errorValue(errDiv0)
+		}
+		return numberValue(x / y)
+	case "^":
+		if x == 0 && y == 0 {
+			return errorValue(errNum)
+		}
+		if x == 0 && y < 0 {
+			return errorValue(errDiv0)
+		}
+		return numberValue(math.Pow(x, y))
+	}
+	return errorValue(errValue)
+}
+
+// compareOp applies a comparison operator to the result of comparing
+// two values.
+func compareOp(op string, cmp int) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// reference resolves a reference within a formula to the cells it
+// covers.
+func (e *evaluator) reference(ref Reference, ctx evalContext) evalValue {
+	if ref.Workbook != "" || ref.LastSheet != "" {
+		// External and 3D references aren't supported
+		return errorValue(errRef)
+	}
+	sheet := ctx.sheet
+	if ref.Sheet != "" {
+		sheet = e.file.sheetByName(ref.Sheet)
+		if sheet == nil {
+			return errorValue(errRef)
+		}
+	}
+	return refValue(sheet, ref.CellRange())
+}
+
+// referenceOp applies the range, intersection or union operator to two
+// references on the same sheet.
+func (e *evaluator) referenceOp(n *BinaryOpNode, ctx evalContext) evalValue {
+	l := e.eval(n.Left, ctx)
+	if l.isError() {
+		return l
+	}
+	r := e.eval(n.Right, ctx)
+	if r.isError() {
+		return r
+	}
+	if l.kind != evalReference || r.kind != evalReference || l.ref.sheet != r.ref.sheet {
+		return errorValue(errValue)
+	}
+	areas := append(append([]CellRange{}, l.ref.areas...), r.ref.areas...)
+	switch n.Op {
+	case ":":
+		bounds := areas[0]
+		for _, a := range areas[1:] {
+			bounds = NewCellRange(minInt(bounds.MinCol, a.MinCol), minInt(bounds.MinRow, a.MinRow),
+				maxInt(bounds.MaxCol, a.MaxCol), maxInt(bounds.MaxRow, a.MaxRow))
+		}
+		return refValue(l.ref.sheet, bounds)
+	case " ":
+		if len(areas) != 2 {
+			return errorValue(errValue)
+		}
+		a, b := areas[0], areas[1]
+		minCol, minRow := maxInt(a.MinCol, b.MinCol), maxInt(a.MinRow, b.MinRow)
+		maxCol, maxRow := minInt(a.MaxCol, b.MaxCol), minInt(a.MaxRow, b.MaxRow)
+		if minCol > maxCol || minRow > maxRow {
+			return errorValue(errNull)
+		}
+		return refValue(l.ref.sheet, CellRange{minCol, minRow, maxCol, maxRow})
+	}
+	return refValue(l.ref.sheet, areas...)
+}
+
+// name evaluates what a defined name refers to.
+func (e *evaluator) name(n *NameNode, ctx evalContext) evalValue {
+	dn, sheet := e.file.visibleName(n, ctx.sheet)
+	if dn == nil {
+		return errorValue(errName)
+	}
+	if e.names[dn] {
+		e.fail(fmt.Errorf("circular reference involving the name %q", dn.Name))
+		return errorValue(errRef)
+	}
+	node, err := ParseFormula(dn.Data)
+	if err != nil {
+		e.fail(fmt.Errorf("name %q: %w", dn.Name, err))
+		return errorValue(errName)
+	}
+	ctx.sheet = sheet
+	e.names[dn] = true
+	v := e.eval(node, ctx)
+	delete(e.names, dn)
+	return v
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,266 @@
+package xlsx
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestEvaluate(t *testing.T) {
+	c := qt.New(t)
+
+	// setUpEvalFile makes a File with a "Data" sheet holding numbers in
+	// A1:A5, names in B1:B5 and amounts in C1:C5, and an empty "Calc"
+	// sheet.
+	setUpEvalFile := func(c *qt.C, option FileOption) (*File, *Sheet, *Sheet) {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		calc, err := f.AddSheet("Calc")
+		c.Assert(err, qt.IsNil)
+		names := []string{"apple", "banana", "cherry", "apple", "date"}
+		for i := 0; i < 5; i++ {
+			cell, err := data.Cell(i, 0)
+			c.Assert(err, qt.IsNil)
+			cell.SetInt(i + 1)
+			cell, err = data.Cell(i, 1)
+			c.Assert(err, qt.IsNil)
+			cell.SetString(names[i])
+			cell, err = data.Cell(i, 2)
+			c.Assert(err, qt.IsNil)
+			cell.SetInt((i + 1) * 10)
+		}
+		return f, data, calc
+	}
+
+	setFormula := func(c *qt.C, sheet *Sheet, row, col int, formula string) {
+		cell, err := sheet.Cell(row, col)
+		c.Assert(err, qt.IsNil)
+		cell.SetFormula(formula)
+	}
+
+	csRunO(c, "Formulas", func(c *qt.C, option FileOption) {
+		f, _, calc := setUpEvalFile(c, option)
+		_, err := f.AddName("Amounts", "Data!$C$1:$C$5", nil)
+		c.Assert(err, qt.IsNil)
+		_, err = f.AddName("Rate", "0.5", nil)
+		c.Assert(err, qt.IsNil)
+
+		cases := []struct {
+			formula  string
+			expected FormulaValue
+		}{
+			{"1+2*3", FormulaValue{CellTypeNumeric, "7"}},
+			{"-2^2", FormulaValue{CellTypeNumeric, "4"}},
+			{"10/4", FormulaValue{CellTypeNumeric, "2.5"}},
+			{"50%", FormulaValue{CellTypeNumeric, "0.5"}},
+			{"1/0", FormulaValue{CellTypeError, "#DIV/0!"}},
+			{`"a"+1`, FormulaValue{CellTypeError, "#VALUE!"}},
+			{`"2"*3`, FormulaValue{CellTypeNumeric, "6"}},
+			{`"x"&1.5&TRUE`, FormulaValue{CellTypeString, "x1.5TRUE"}},
+			{"0.1+0.2&\"\"", FormulaValue{CellTypeString, "0.3"}},
+			{`"abc"="ABC"`, FormulaValue{CellTypeBool, "1"}},
+			{`"b">"a"`, FormulaValue{CellTypeBool, "1"}},
+			{`1<"a"`, FormulaValue{CellTypeBool, "1"}},
+			{"Data!A2*Data!C5", FormulaValue{CellTypeNumeric, "100"}},
+			{"Data!Z99+1", FormulaValue{CellTypeNumeric, "1"}},
+			{"Nowhere!A1", FormulaValue{CellTypeError, "#REF!"}},
+			{"SUM(Data!A1:A5)", FormulaValue{CellTypeNumeric, "15"}},
+			{"SUM(Data!A:A,Data!C1,2)", FormulaValue{CellTypeNumeric, "27"}},
+			{"SUM(Data!A1:B5)", FormulaValue{CellTypeNumeric, "15"}},
+			{"SUM(Amounts)*Rate", FormulaValue{CellTypeNumeric, "75"}},
+			{"SUM(Data!A1:A3 Data!A2:C2)", FormulaValue{CellTypeNumeric, "2"}},
+			{"SUM((Data!A1,Data!C1))", FormulaValue{CellTypeNumeric, "11"}},
+			{"Missing+1", FormulaValue{CellTypeError, "#NAME?"}},
+			{"NOSUCHFUNCTION(1)", FormulaValue{CellTypeError, "#NAME?"}},
+			{"AVERAGE(Data!C1:C5)", FormulaValue{CellTypeNumeric, "30"}},
+			{"AVERAGE(Data!D1:D5)", FormulaValue{CellTypeError, "#DIV/0!"}},
+			{"MIN(Data!C:C)+MAX(Data!A:A)", FormulaValue{CellTypeNumeric, "15"}},
+			{"COUNT(Data!A1:C5)", FormulaValue{CellTypeNumeric, "10"}},
+			{"COUNTA(Data!A1:C5)", FormulaValue{CellTypeNumeric, "15"}},
+			{"COUNTBLANK(Data!A1:D5)", FormulaValue{CellTypeNumeric, "5"}},
+			{"PRODUCT(Data!A1:A4)", FormulaValue{CellTypeNumeric, "24"}},
+			{"SUMPRODUCT(Data!A1:A5,Data!C1:C5)", FormulaValue{CellTypeNumeric, "550"}},
+			{"SUMPRODUCT((Data!B1:B5=\"apple\")*Data!C1:C5)", FormulaValue{CellTypeNumeric, "50"}},
+			{`SUMIF(Data!B1:B5,"apple",Data!C1:C5)`, FormulaValue{CellTypeNumeric, "50"}},
+			{`SUMIF(Data!A1:A5,">2")`, FormulaValue{CellTypeNumeric, "12"}},
+			{`SUMIFS(Data!C:C,Data!B:B,"a*",Data!A:A,"<>1")`, FormulaValue{CellTypeNumeric, "40"}},
+			{`COUNTIF(Data!B1:B5,"?????")`, FormulaValue{CellTypeNumeric, "2"}},
+			{`COUNTIFS(Data!A1:A5,">=2",Data!C1:C5,"<50")`, FormulaValue{CellTypeNumeric, "3"}},
+			{`COUNTIF(Data!D1:D10,"")`, FormulaValue{CellTypeNumeric, "10"}},
+			{`AVERAGEIF(Data!B1:B5,"apple",Data!A1:A5)`, FormulaValue{CellTypeNumeric, "2.5"}},
+			{`IF(Data!A2>1,"yes","no")`, FormulaValue{CellTypeString, "yes"}},
+			{"IF(FALSE,1)", FormulaValue{CellTypeBool, "0"}},
+			{"IF(TRUE,1,1/0)", FormulaValue{CellTypeNumeric, "1"}},
+			{"IFERROR(1/0,-1)", FormulaValue{CellTypeNumeric, "-1"}},
+			{"IFNA(NA(),0)", FormulaValue{CellTypeNumeric, "0"}},
+			{"IFNA(1/0,0)", FormulaValue{CellTypeError, "#DIV/0!"}},
+			{"AND(Data!A1:A5)", FormulaValue{CellTypeBool, "1"}},
+			{"OR(FALSE,0)", FormulaValue{CellTypeBool, "0"}},
+			{"NOT(1)", FormulaValue{CellTypeBool, "0"}},
+			{"CHOOSE(2,\"a\",\"b\")", FormulaValue{CellTypeString, "b"}},
+			{`VLOOKUP("cherry",Data!B1:C5,2,FALSE)`, FormulaValue{CellTypeNumeric, "30"}},
+			{`VLOOKUP("fig",Data!B1:C5,2,FALSE)`, FormulaValue{CellTypeError, "#N/A"}},
+			{"VLOOKUP(3.5,Data!A1:C5,3)", FormulaValue{CellTypeNumeric, "30"}},
+			{"VLOOKUP(1,Data!A1:C5,4,FALSE)", FormulaValue{CellTypeError, "#REF!"}},
+			{"HLOOKUP(2,{1,2,3;\"a\",\"b\",\"c\"},2,FALSE)", FormulaValue{CellTypeString, "b"}},
+			{`MATCH("date",Data!B:B,0)`, FormulaValue{CellTypeNumeric, "5"}},
+			{"MATCH(25,Data!C1:C5)", FormulaValue{CellTypeNumeric, "2"}},
+			{"MATCH(25,{50,40,30,20},-1)", FormulaValue{CellTypeNumeric, "3"}},
+			{"INDEX(Data!A1:C5,4,2)", FormulaValue{CellTypeString, "apple"}},
+			{"INDEX(Data!C1:C5,MATCH(\"banana\",Data!B1:B5,0))", FormulaValue{CellTypeNumeric, "20"}},
+			{"SUM(INDEX(Data!A1:C5,0,3))", FormulaValue{CellTypeNumeric, "150"}},
+			{"SUM(Data!A1:INDEX(Data!A:A,3))", FormulaValue{CellTypeNumeric, "6"}},
+			{"INDEX({1,2;3,4},2,1)", FormulaValue{CellTypeNumeric, "3"}},
+			{"INDEX(Data!A1:A5,6)", FormulaValue{CellTypeError, "#REF!"}},
+			{"ROW(Data!C3)+COLUMN()+ROWS(Data!A1:C5)+COLUMNS(Data!A:C)", FormulaValue{CellTypeNumeric, "12"}},
+			{`CONCATENATE("a",1,TRUE)`, FormulaValue{CellTypeString, "a1TRUE"}},
+			{"CONCAT(Data!B1:B2)", FormulaValue{CellTypeString, "applebanana"}},
+			{`LEN("héllo")&LEFT("abc")&RIGHT("abc",2)&MID("abcdef",2,3)`, FormulaValue{CellTypeString, "5abcbcd"}},
+			{`UPPER("a")&LOWER("B")&TRIM("  x   y ")`, FormulaValue{CellTypeString, "Abx y"}},
+			{`EXACT("a","A")`, FormulaValue{CellTypeBool, "0"}},
+			{`FIND("b","abcb",3)+SEARCH("B","abc")`, FormulaValue{CellTypeNumeric, "6"}},
+			{`FIND("z","abc")`, FormulaValue{CellTypeError, "#VALUE!"}},
+			{`SUBSTITUTE("a-b-c","-","+")&SUBSTITUTE("a-b-c","-","+",2)`, FormulaValue{CellTypeString, "a+b+ca-b+c"}},
+			{`REPT("ab",3)`, FormulaValue{CellTypeString, "ababab"}},
+			{`TEXT(1234.567,"0.00")`, FormulaValue{CellTypeString, "1234.57"}},
+			{`TEXT(1234.5,"#,##0.00")`, FormulaValue{CellTypeString, "1,234.50"}},
+			{`TEXT(-1234567,"#,##0;(#,##0)")`, FormulaValue{CellTypeString, "(1,234,567)"}},
+			{`TEXT(999,"#,##0")`, FormulaValue{CellTypeString, "999"}},
+			{`TEXT(DATE(2020,3,15),"yyyy-mm-dd")`, FormulaValue{CellTypeString, "2020-03-15"}},
+			{`VALUE(" 12.5 ")+VALUE("50%")`, FormulaValue{CellTypeNumeric, "13"}},
+			{"DATE(2020,1,1)", FormulaValue{CellTypeNumeric, "43831"}},
+			{"DATE(2019,13,1)", FormulaValue{CellTypeNumeric, "43831"}},
+			{"DATE(1900,1,1)", FormulaValue{CellTypeNumeric, "1"}},
+			{"YEAR(43831)*10000+MONTH(43831+31)*100+DAY(43831+31)", FormulaValue{CellTypeNumeric, "20200201"}},
+			{"ROUND(2.675,2)", FormulaValue{CellTypeNumeric, "2.68"}},
+			{"ROUND(-2.5,0)", FormulaValue{CellTypeNumeric, "-3"}},
+			{"ROUND(1234.5,-2)", FormulaValue{CellTypeNumeric, "1200"}},
+			{"ROUNDUP(1.21,1)&ROUNDDOWN(-1.29,1)", FormulaValue{CellTypeString, "1.3-1.2"}},
+			{"INT(-1.5)+MOD(-7,3)+ABS(-2)+POWER(2,3)+SQRT(16)", FormulaValue{CellTypeNumeric, "14"}},
+			{"SQRT(-1)", FormulaValue{CellTypeError, "#NUM!"}},
+			{"POWER(0,-1)", FormulaValue{CellTypeError, "#DIV/0!"}},
+			{"POWER(0,0)", FormulaValue{CellTypeError, "#NUM!"}},
+			{"ISBLANK(Data!Z1)", FormulaValue{CellTypeBool, "1"}},
+			{"ISNUMBER(Data!A1)", FormulaValue{CellTypeBool, "1"}},
+			{"ISTEXT(Data!A1)", FormulaValue{CellTypeBool, "0"}},
+			{"ISERROR(1/0)", FormulaValue{CellTypeBool, "1"}},
+			{"ISERR(NA())", FormulaValue{CellTypeBool, "0"}},
+			{"_xlfn.CONCAT(\"a\",\"b\")", FormulaValue{CellTypeString, "ab"}},
+			{"{1,2;3,4}", FormulaValue{CellTypeNumeric, "1"}},
+			{"Data!Z1", FormulaValue{CellTypeNumeric, "0"}},
+		}
+		for i, tc := range cases {
+			setFormula(c, calc, i, 0, tc.formula)
+		}
+		for i, tc := range cases {
+			v, err := f.Evaluate(calc, GetCellIDStringFromCoords(0, i))
+			c.Assert(err, qt.IsNil, qt.Commentf(tc.formula))
+			c.Assert(v, qt.Equals, tc.expected, qt.Commentf(tc.formula))
+		}
+	})
+
+	csRunO(c, "ImplicitIntersection", func(c *qt.C, option FileOption) {
+		f, _, calc := setUpEvalFile(c, option)
+		setFormula(c, calc, 2, 0, "Data!C1:C5*2")
+		setFormula(c, calc, 3, 0, "@Data!A1:A5")
+		setFormula(c, calc, 4, 0, "Data!B1:C1")
+
+		// An array gives its first element
+		v, err := f.Evaluate(calc, "A3")
+		c.Assert(err, qt.IsNil)
+		c.Assert(v, qt.Equals, FormulaValue{CellTypeNumeric, "20"})
+		v, err = f.Evaluate(calc, "$A$4")
+		c.Assert(err, qt.IsNil)
+		c.Assert(v, qt.Equals, FormulaValue{CellTypeNumeric, "4"})
+		v, err = f.Evaluate(calc, "A5")
+		c.Assert(err, qt.IsNil)
+		c.Assert(v, qt.Equals, FormulaValue{CellTypeError, "#VALUE!"})
+	})
+
+	csRunO(c, "ChainsAndLocalNames", func(c *qt.C, option FileOption) {
+		f, data, calc := setUpEvalFile(c, option)
+		_, err := f.AddName("Factor", "2", nil)
+		c.Assert(err, qt.IsNil)
+		_, err = f.AddName("Factor", "3", calc)
+		c.Assert(err, qt.IsNil)
+		setFormula(c, data, 0, 3, "A1*Factor")
+		setFormula(c, calc, 0, 0, "Data!D1*Factor")
+		setFormula(c, calc, 0, 1, "A1+Data!Factor")
+
+		v, err := f.Evaluate(calc, "A1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(v, qt.Equals, FormulaValue{CellTypeNumeric, "6"})
+		v, err = f.Evaluate(calc, "B1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(v, qt.Equals, FormulaValue{CellTypeError, "#NAME?"})
+
+		// A cell without a formula gives its own value
+		v, err = f.Evaluate(data, "B2")
+		c.Assert(err, qt.IsNil)
+		c.Assert(v, qt.Equals, FormulaValue{CellTypeString, "banana"})
+	})
+
+	csRunO(c, "Errors", func(c *qt.C, option FileOption) {
+		f, data, calc := setUpEvalFile(c, option)
+		setFormula(c, calc, 0, 0, "B1+1")
+		setFormula(c, calc, 0, 1, "SUM(A1:A2)")
+		setFormula(c, calc, 1, 0, "1+")
+
+		_, err := f.Evaluate(calc, "A1")
+		c.Assert(err, qt.ErrorMatches, `Evaluate\("A1"\): circular reference involving Calc!A1`)
+		_, err = f.Evaluate(calc, "A2")
+		c.Assert(err, qt.Not(qt.IsNil))
+		_, err = f.Evaluate(calc, "A1:B2")
+		c.Assert(err, qt.Not(qt.IsNil))
+		_, err = f.Evaluate(&Sheet{Name: "Other"}, "A1")
+		c.Assert(err, qt.Not(qt.IsNil))
+		err = f.Recalculate()
+		c.Assert(err, qt.Not(qt.IsNil))
+		cell, err := data.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "1")
+	})
+
+	csRunO(c, "Recalculate", func(c *qt.C, option FileOption) {
+		f, data, calc := setUpEvalFile(c, option)
+		setFormula(c, calc, 0, 0, "SUM(Data!C:C)")
+		setFormula(c, calc, 1, 0, `VLOOKUP("banana",Data!B1:C5,2,FALSE)&" kg"`)
+		setFormula(c, calc, 2, 0, "A1>100")
+		setFormula(c, calc, 3, 0, "A1/0")
+		setFormula(c, data, 5, 2, "C5*2")
+
+		err := f.Recalculate()
+		c.Assert(err, qt.IsNil)
+
+		var buf bytes.Buffer
+		err = f.Write(&buf)
+		c.Assert(err, qt.IsNil)
+		f, err = OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+
+		expected := []struct {
+			sheet    *Sheet
+			row      int
+			formula  string
+			cellType CellType
+			value    string
+		}{
+			{f.Sheets[1], 0, "SUM(Data!C:C)", CellTypeNumeric, "250"},
+			{f.Sheets[1], 1, `VLOOKUP("banana",Data!B1:C5,2,FALSE)&" kg"`, CellTypeStringFormula, "20 kg"},
+			{f.Sheets[1], 2, "A1>100", CellTypeBool, "1"},
+			{f.Sheets[1], 3, "A1/0", CellTypeError, "#DIV/0!"},
+		}
+		for _, e := range expected {
+			cell, err := e.sheet.Cell(e.row, 0)
+			c.Assert(err, qt.IsNil)
+			c.Assert(cell.Formula(), qt.Equals, e.formula)
+			c.Assert(cell.Type(), qt.Equals, e.cellType)
+			c.Assert(cell.Value, qt.Equals, e.value)
+		}
+		cell, err := f.Sheets[0].Cell(5, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "100")
+	})
+}
@@ -10,7 +10,6 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"unicode/utf8"
 )
 
 // File is a high level structure providing a slice of Sheet structs
@@ -20,14 +19,19 @@ type File struct {
 	worksheetRels        map[string]*zip.File
 	referenceTable       *RefTable
 	Date1904             bool
+	FullCalcOnLoad       bool
 	styles               *xlsxStyleSheet
 	Sheets               []*Sheet
 	Sheet                map[string]*Sheet
 	theme                *theme
-	DefinedNames         []*xlsxDefinedName
+	DefinedNames         []*DefinedName
+	namedStyles          []*namedStyle
 	cellStoreConstructor CellStoreConstructor
 	rowLimit             int
 	valueOnly            bool
+	sharedFormulas       bool
+	r1c1                 bool
+	activeSheet          *Sheet
 }
 
 const NoRowLimit int = -1
@@ -52,13 +56,33 @@ func ValueOnly() FileOption {
 	}
 }
 
+// SharedFormulas makes the File write each run of cells down a column
+// whose formulas only differ in their relative references, as formulas
+// filled down in Excel do, as a single shared formula.  This makes the
+// files of large sheets considerably smaller.
+func SharedFormulas() FileOption {
+	return func(f *File) {
+		f.sharedFormulas = true
+	}
+}
+
+// R1C1ReferenceStyle makes Excel open the File with references shown
+// in R1C1 notation.  Formulas are always stored in A1 notation, so
+// this doesn't change how formulas are given to, or read from, a
+// Cell; FormulaR1C1ToA1 converts formulas written in R1C1 notation.
+func R1C1ReferenceStyle() FileOption {
+	return func(f *File) {
+		f.r1c1 = true
+	}
+}
+
 // NewFile creates a new File struct. You may pass it zero, one or
 // many FileOption functions that affect the behaviour of the file.
 func NewFile(options ...FileOption) *File {
 	f := &File{
 		Sheet:                make(map[string]*Sheet),
 		Sheets:               make([]*Sheet, 0),
-		DefinedNames:         make([]*xlsxDefinedName, 0),
+		DefinedNames:         make([]*DefinedName, 0),
 		rowLimit:             NoRowLimit,
 		cellStoreConstructor: NewMemoryCellStore,
 	}
@@ -190,16 +214,8 @@ func (f *File) AddSheetWithCellStore(sheetName string, constructor CellStoreCons
 	if _, exists := f.Sheet[sheetName]; exists {
 		return nil, fmt.Errorf("duplicate sheet name '%s'.", sheetName)
 	}
-	runeLength := utf8.RuneCountInString(sheetName)
-	if runeLength > 31 || runeLength == 0 {
-		return nil, fmt.Errorf("sheet name must be 31 or fewer characters long.  It is currently '%d' characters long", runeLength)
-	}
-	// Iterate over the runes
-	for _, r := range sheetName {
-		// Excel forbids : \ / ? * [ ]
-		if r == ':' || r == '\\' || r == '/' || r == '?' || r == '*' || r == '[' || r == ']' {
-			return nil, fmt.Errorf("sheet name must not contain any restricted characters : \\ / ? * [ ] but contains '%s'", string(r))
-		}
+	if err := checkSheetName(sheetName); err != nil {
+		return nil, err
 	}
 	sheet := &Sheet{
 		Name:     sheetName,
@@ -222,6 +238,9 @@ func (f *File) AppendSheet(sheet Sheet, sheetName string) (*Sheet, error) {
 	if _, exists := f.Sheet[sheetName]; exists {
 		return nil, fmt.Errorf("duplicate sheet name '%s'.", sheetName)
 	}
+	if err := checkSheetName(sheetName); err != nil {
+		return nil, err
+	}
 	sheet.Name = sheetName
 	sheet.File = f
 	sheet.Selected = len(f.Sheets) == 0
@@ -230,6 +249,116 @@ func (f *File) AppendSheet(sheet Sheet, sheetName string) (*Sheet, error) {
 	return &sheet, nil
 }
 
+// CopySheetFrom adds a copy of the Sheet src, which may belong to
+// another File, to the end of the File with the name newName.  Unlike
+// AppendSheet, the copy is independent of src: it has a cell store of
+// its own, and its own copies of the styles, data validations and
+// column settings.  Merged cells, hyperlinks, relations, print
+// settings and the defined names that are local to src are copied as
+// well.  Named cell styles are only kept when src belongs to the File,
+// as another File doesn't define them.
+func (f *File) CopySheetFrom(src *Sheet, newName string) (*Sheet, error) {
+	src.mustBeOpen()
+	sheet, err := f.AddSheet(newName)
+	if err != nil {
+		return nil, fmt.Errorf("CopySheetFrom(%q): %w", newName, err)
+	}
+	err = sheet.copyFrom(src)
+	if err != nil {
+		sheet.Close()
+		delete(f.Sheet, newName)
+		f.Sheets = f.Sheets[:len(f.Sheets)-1]
+		return nil, fmt.Errorf("CopySheetFrom(%q): %w", newName, err)
+	}
+	if src.File == nil {
+		return sheet, nil
+	}
+	srcIndex := src.File.sheetIndex(src)
+	for _, dn := range src.File.DefinedNames {
+		if dn.localSheetID == nil || *dn.localSheetID != srcIndex {
+			continue
+		}
+		newDN := *dn
+		sheetIndex := len(f.Sheets) - 1
+		newDN.localSheetID = &sheetIndex
+		// The copy's names refer to the copy, as Excel's do
+		newDN.Data = sheetEdit{file: src.File, sheet: src, oldName: src.Name, newName: sheet.Name}.formula(dn.Data)
+		f.DefinedNames = append(f.DefinedNames, &newDN)
+	}
+	return sheet, nil
+}
+
+// MoveSheet moves the Sheet at the zero based index from to the index
+// to, shifting the Sheets in between along.  The defined names that
+// are local to a Sheet move with it.
+func (f *File) MoveSheet(from, to int) error {
+	if from < 0 || from >= len(f.Sheets) || to < 0 || to >= len(f.Sheets) {
+		return fmt.Errorf("MoveSheet(%d, %d): the File has %d sheets", from, to, len(f.Sheets))
+	}
+	newIndex := func(i int) int {
+		switch {
+		case i == from:
+			return to
+		case from < to && i > from && i <= to:
+			return i - 1
+		case to < from && i >= to && i < from:
+			return i + 1
+		}
+		return i
+	}
+	sheets := make([]*Sheet, len(f.Sheets))
+	for i, sheet := range f.Sheets {
+		sheets[newIndex(i)] = sheet
+	}
+	f.Sheets = sheets
+	for _, dn := range f.DefinedNames {
+		if dn.localSheetID != nil {
+			id := newIndex(*dn.localSheetID)
+			dn.localSheetID = &id
+		}
+	}
+	return nil
+}
+
+// ActiveSheet returns the zero based index of the Sheet that Excel
+// shows when it opens the File.  Unless SetActiveSheet was called, or
+// the workbook the File was read from names one, this is the first
+// selected Sheet.
+func (f *File) ActiveSheet() int {
+	if i := f.sheetIndex(f.activeSheet); i >= 0 {
+		return i
+	}
+	for i, sheet := range f.Sheets {
+		if sheet.Selected {
+			return i
+		}
+	}
+	return 0
+}
+
+// SetActiveSheet makes the Sheet at the zero based index the one that
+// Excel shows when it opens the File, and the only selected Sheet.
+// The active Sheet follows the Sheet if it moves, and more Sheets may
+// be selected afterwards without changing it.
+func (f *File) SetActiveSheet(index int) error {
+	if index < 0 || index >= len(f.Sheets) {
+		return fmt.Errorf("SetActiveSheet(%d): the File has %d sheets", index, len(f.Sheets))
+	}
+	for i, sheet := range f.Sheets {
+		sheet.Selected = i == index
+	}
+	f.activeSheet = f.Sheets[index]
+	return nil
+}
+
+// refMode returns the reference style Excel should open the File in.
+func (f *File) refMode() string {
+	if f.r1c1 {
+		return "R1C1"
+	}
+	return "A1"
+}
+
 func (f *File) makeWorkbook() xlsxWorkbook {
 	return xlsxWorkbook{
 		FileVersion: xlsxFileVersion{AppName: "Go XLSX"},
@@ -237,6 +366,7 @@ func (f *File) makeWorkbook() xlsxWorkbook {
 		BookViews: xlsxBookViews{
 			WorkBookView: []xlsxWorkBookView{
 				{
+					ActiveTab:            f.ActiveSheet(),
 					ShowHorizontalScroll: true,
 					ShowSheetTabs:        true,
 					ShowVerticalScroll:   true,
@@ -248,16 +378,43 @@ func (f *File) makeWorkbook() xlsxWorkbook {
 				},
 			},
 		},
-		Sheets: xlsxSheets{Sheet: make([]xlsxSheet, len(f.Sheets))},
+		Sheets:       xlsxSheets{Sheet: make([]xlsxSheet, len(f.Sheets))},
+		DefinedNames: xlsxDefinedNames{DefinedName: f.makeDefinedNames()},
 		CalcPr: xlsxCalcPr{
-			IterateCount: 100,
-			RefMode:      "A1",
-			Iterate:      false,
-			IterateDelta: 0.001,
+			IterateCount:   100,
+			RefMode:        f.refMode(),
+			Iterate:        false,
+			IterateDelta:   0.001,
+			FullCalcOnLoad: f.FullCalcOnLoad,
 		},
 	}
 }
 
+// makeDefinedNames returns all the defined names of the workbook,
+// including the built-in names that are generated from the
+// properties of each sheet.  Built-in names are generated using the
+// current position of their sheet, so they stay correct when the
+// sheets are reordered.
+func (f *File) makeDefinedNames() []xlsxDefinedName {
+	var names []xlsxDefinedName
+	for i, sheet := range f.Sheets {
+		names = append(names, sheet.makeBuiltInDefinedNames(i)...)
+	}
+	for _, dn := range f.DefinedNames {
+		if dn.localSheetID != nil && *dn.localSheetID >= 0 && *dn.localSheetID < len(f.Sheets) {
+			sheet := f.Sheets[*dn.localSheetID]
+			if dn.Name == printAreaName && sheet.PrintArea != nil {
+				continue
+			}
+			if dn.Name == printTitlesName && (sheet.PrintTitleRows != nil || sheet.PrintTitleCols != nil) {
+				continue
+			}
+		}
+		names = append(names, dn.makeXLSXDefinedName())
+	}
+	return names
+}
+
 // Some tools that read XLSX files have very strict requirements about
 // the structure of the input XML.  In particular both Numbers on the Mac
 // and SAS dislike inline XML namespace declarations, or namespace
@@ -314,6 +471,7 @@ func (f *File) MakeStreamParts() (map[string]string, error) {
 		f.styles = newXlsxStyleSheet(f.theme)
 	}
 	f.styles.reset()
+	f.makeNamedStyles()
 	if len(f.Sheets) == 0 {
 		err := errors.New("Workbook must contains atleast one worksheet")
 		return nil, err
@@ -325,8 +483,14 @@ func (f *File) MakeStreamParts() (map[string]string, error) {
 			return nil, err
 		}
 
+		if err := sheet.checkLimits(); err != nil {
+			return nil, err
+		}
 		xSheetRels := sheet.makeXLSXSheetRelations()
-		xSheet := sheet.makeXLSXSheet(refTable, f.styles, xSheetRels)
+		xSheet, err := sheet.makeXLSXSheet(refTable, f.styles, xSheetRels)
+		if err != nil {
+			return nil, err
+		}
 		rId := fmt.Sprintf("rId%d", sheetIndex)
 		sheetId := strconv.Itoa(sheetIndex)
 		sheetPath := fmt.Sprintf("worksheets/sheet%d.xml", sheetIndex)
@@ -386,6 +550,9 @@ func (f *File) MakeStreamParts() (map[string]string, error) {
 	}
 
 	xWRel := workbookRels.MakeXLSXWorkbookRels()
+	if f.addMetadata(&xWRel, &types) {
+		parts[metadataPartName] = TEMPLATE_XL_METADATA
+	}
 
 	parts["xl/_rels/workbook.xml.rels"], err = marshal(xWRel)
 	if err != nil {
@@ -405,6 +572,31 @@ func (f *File) MakeStreamParts() (map[string]string, error) {
 	return parts, nil
 }
 
+const metadataPartName = "xl/metadata.xml"
+
+// addMetadata adds the relationship and content type of the cell
+// metadata part, if any sheet was written with dynamic array formulas,
+// and reports whether it's needed.
+func (f *File) addMetadata(rels *xlsxWorkbookRels, types *xlsxTypes) bool {
+	needed := false
+	for _, sheet := range f.Sheets {
+		needed = needed || sheet.dynamicArrays
+	}
+	if !needed {
+		return false
+	}
+	rels.Relationships = append(rels.Relationships, xlsxWorkbookRelation{
+		Id:     fmt.Sprintf("rId%d", len(rels.Relationships)+1),
+		Target: "metadata.xml",
+		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sheetMetadata",
+	})
+	types.Overrides = append(types.Overrides, xlsxOverride{
+		PartName:    "/" + metadataPartName,
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheetMetadata+xml",
+	})
+	return true
+}
+
 // MarshallParts constructs a map of file name to XML content representing the file
 // in terms of the structure of an XLSX file.
 func (f *File) MarshallParts(zipWriter *zip.Writer) error {
@@ -447,6 +639,7 @@ func (f *File) MarshallParts(zipWriter *zip.Writer) error {
 		f.styles = newXlsxStyleSheet(f.theme)
 	}
 	f.styles.reset()
+	f.makeNamedStyles()
 	if len(f.Sheets) == 0 {
 		err := errors.New("MarshalParts: Workbook must contain at least one worksheet")
 		return wrap(err)
@@ -459,6 +652,9 @@ func (f *File) MarshallParts(zipWriter *zip.Writer) error {
 				return wrap(err)
 			}
 		}
+		if err := sheet.checkLimits(); err != nil {
+			return wrap(err)
+		}
 
 		xSheetRels := sheet.makeXLSXSheetRelations()
 		rId := fmt.Sprintf("rId%d", sheetIndex)
@@ -540,6 +736,12 @@ func (f *File) MarshallParts(zipWriter *zip.Writer) error {
 	}
 
 	xWRel := workbookRels.MakeXLSXWorkbookRels()
+	if f.addMetadata(&xWRel, &types) {
+		err = writePart(metadataPartName, TEMPLATE_XL_METADATA)
+		if err != nil {
+			return err
+		}
+	}
 	relPart, err := marshal(xWRel)
 	if err != nil {
 		return err
@@ -1238,3 +1238,121 @@ func TestSliceReader(t *testing.T) {
 	})
 
 }
+
+func TestCopySheet(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "CopySheetFrom", func(c *qt.C, option FileOption) {
+		src := NewFile(option)
+		srcSheet, err := src.AddSheet("Source")
+		c.Assert(err, qt.IsNil)
+		_, err = src.AddSheet("Other")
+		c.Assert(err, qt.IsNil)
+		style := NewStyle()
+		style.Font.Bold = true
+		style.ApplyFont = true
+		row := srcSheet.AddRow()
+		row.SetHeight(30)
+		cell := row.AddCell()
+		cell.SetString("Title")
+		cell.SetStyle(style)
+		cell.Merge(1, 0)
+		row = srcSheet.AddRow()
+		row.AddCell().SetInt(2)
+		row.AddCell().SetFormula("A2*2")
+		cell = row.AddCell()
+		cell.SetHyperlink("http://example.com", "Example", "")
+		dv := NewDataValidation(1, 0, 1, 0, true)
+		c.Assert(dv.SetDropList([]string{"1", "2"}), qt.IsNil)
+		row.GetCell(0).SetDataValidation(dv)
+		srcSheet.SetColWidth(1, 2, 20)
+		srcSheet.Cols.FindColByIndex(1).SetStyle(style)
+		c.Assert(srcSheet.SetPrintArea("A1:C2"), qt.IsNil)
+		srcSheet.HeaderFooter = &HeaderFooter{}
+		srcSheet.HeaderFooter.OddHeader.Left.Text("Confidential")
+		local := 0
+		src.DefinedNames = append(src.DefinedNames, &DefinedName{Name: "Total", Data: "Source!$B$2", localSheetID: &local})
+
+		dest := NewFile(option)
+		_, err = dest.AddSheet("First")
+		c.Assert(err, qt.IsNil)
+		sheet, err := dest.CopySheetFrom(srcSheet, "Copy")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.File, qt.Equals, dest)
+		c.Assert(dest.DefinedNames, qt.HasLen, 1)
+		c.Assert(*dest.DefinedNames[0].localSheetID, qt.Equals, 1)
+		c.Assert(dest.DefinedNames[0].Data, qt.Equals, "Copy!$B$2")
+		c.Assert(src.DefinedNames[0].Data, qt.Equals, "Source!$B$2")
+
+		// The header and footer are copied, not shared
+		sheet.HeaderFooter.OddHeader.Left.Items[0].Value = "Copy"
+		c.Assert(srcSheet.HeaderFooter.OddHeader.Left.Items[0].Value, qt.Equals, "Confidential")
+
+		// Changing the copy leaves the source as it was
+		cell, err = sheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.GetStyle(), qt.Not(qt.Equals), style)
+		cell.GetStyle().Font.Italic = true
+		c.Assert(style.Font.Italic, qt.IsFalse)
+		cell.SetString("Changed")
+		cell, err = srcSheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "Title")
+		c.Assert(sheet.Cols.FindColByIndex(1).GetStyle(), qt.Not(qt.Equals), style)
+
+		_, b := writeFileParts(c, dest)
+		f, err := OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		sheet = f.Sheet["Copy"]
+		c.Assert(sheet, qt.Not(qt.IsNil))
+		row, err = sheet.Row(0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(row.GetHeight(), qt.Equals, 30.0)
+		cell, err = sheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "Changed")
+		c.Assert(cell.GetStyle().Font.Bold, qt.IsTrue)
+		c.Assert(cell.HMerge, qt.Equals, 1)
+		cell, err = sheet.Cell(1, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Formula(), qt.Equals, "A2*2")
+		cell, err = sheet.Cell(1, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Hyperlink.Link, qt.Equals, "http://example.com")
+		c.Assert(sheet.DataValidations, qt.HasLen, 1)
+		c.Assert(*sheet.Cols.FindColByIndex(2).Width, qt.Equals, 20.0)
+		c.Assert(sheet.PrintArea.String(), qt.Equals, "A1:C2")
+
+		_, err = dest.CopySheetFrom(srcSheet, "Copy")
+		c.Assert(err, qt.ErrorMatches, `CopySheetFrom\("Copy"\): duplicate sheet name .*`)
+	})
+
+	c.Run("MoveSheet", func(c *qt.C) {
+		f := NewFile()
+		for _, name := range []string{"A", "B", "C", "D"} {
+			_, err := f.AddSheet(name)
+			c.Assert(err, qt.IsNil)
+		}
+		names := func() []string {
+			var s []string
+			for _, sheet := range f.Sheets {
+				s = append(s, sheet.Name)
+			}
+			return s
+		}
+		ids := []int{0, 1, 3}
+		for i := range ids {
+			f.DefinedNames = append(f.DefinedNames, &DefinedName{Name: "N", localSheetID: &ids[i]})
+		}
+		c.Assert(f.MoveSheet(0, 2), qt.IsNil)
+		c.Assert(names(), qt.DeepEquals, []string{"B", "C", "A", "D"})
+		c.Assert(*f.DefinedNames[0].localSheetID, qt.Equals, 2)
+		c.Assert(*f.DefinedNames[1].localSheetID, qt.Equals, 0)
+		c.Assert(*f.DefinedNames[2].localSheetID, qt.Equals, 3)
+		c.Assert(f.MoveSheet(3, 0), qt.IsNil)
+		c.Assert(names(), qt.DeepEquals, []string{"D", "B", "C", "A"})
+		c.Assert(*f.DefinedNames[0].localSheetID, qt.Equals, 3)
+		c.Assert(*f.DefinedNames[2].localSheetID, qt.Equals, 0)
+		c.Assert(f.MoveSheet(1, 4), qt.ErrorMatches, `MoveSheet\(1, 4\): the File has 4 sheets`)
+	})
+}
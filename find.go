@@ -0,0 +1,239 @@
+package xlsx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FindOptions control how File.Find and File.Replace match the
+// contents of cells.
+type FindOptions struct {
+	// Regexp makes the pattern a regular expression, in the syntax
+	// of the regexp package, rather than literal text.  The
+	// replacement may then refer to submatches, as in "$1".
+	Regexp bool
+	// MatchCase makes matching sensitive to case.
+	MatchCase bool
+	// WholeCell only matches cells whose entire contents match.
+	WholeCell bool
+	// Formulas matches the formulas of cells, rather than their
+	// values.
+	Formulas bool
+}
+
+// compile returns the regular expression that matches the pattern.
+func (opts FindOptions) compile(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("the pattern is empty")
+	}
+	if !opts.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.WholeCell {
+		pattern = `^(?:` + pattern + `)$`
+	}
+	if !opts.MatchCase {
+		pattern = `(?i)` + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// FoundCell is a cell that File.Find matched.
+type FoundCell struct {
+	Sheet *Sheet
+	// Row and Col are zero based
+	Row int
+	Col int
+}
+
+// String returns the cell's reference, qualified with the name of its
+// sheet, e.g. "'My Sheet'!B2".
+func (fc FoundCell) String() string {
+	return sheetRef(fc.Sheet.Name, GetCellIDStringFromCoords(fc.Col, fc.Row))
+}
+
+// searchedText returns the text of a cell that is searched, and whether
+// the cell is searched at all.  Cells with formulas are only searched
+// for formulas.  When values is set, cells that hold numbers, dates
+// or booleans are searched by their formatted value, as they appear
+// in Excel; otherwise only strings are searched.
+func searchedText(cell *Cell, opts FindOptions, values bool) (string, bool) {
+	if opts.Formulas {
+		return cell.formula, cell.formula != ""
+	}
+	if cell.formula != "" {
+		return "", false
+	}
+	if cell.cellType != CellTypeString && cell.cellType != CellTypeInline {
+		if !values || cell.cellType == CellTypeError {
+			return "", false
+		}
+		text, err := cell.FormattedValue()
+		if err != nil {
+			return cell.Value, true
+		}
+		return text, true
+	}
+	if len(cell.RichText) > 0 {
+		return richTextToPlainText(cell.RichText), true
+	}
+	return cell.Value, true
+}
+
+// forEachMatch calls fn for every cell of every sheet whose text
+// matches re, searching the formatted values of cells that don't hold
+// strings when values is set.
+func (f *File) forEachMatch(re *regexp.Regexp, opts FindOptions, values bool, fn func(sheet *Sheet, cell *Cell) error) error {
+	for _, sheet := range f.Sheets {
+		err := sheet.ForEachRow(func(row *Row) error {
+			return row.ForEachCell(func(cell *Cell) error {
+				if text, ok := searchedText(cell, opts, values); ok && re.MatchString(text) {
+					cell.Row = row
+					return fn(sheet, cell)
+				}
+				return nil
+			}, SkipEmptyCells)
+		}, SkipEmptyRows)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find returns the cells of every sheet that match the pattern, sheet
+// by sheet and row by row.  Cells that hold numbers, dates or booleans
+// are matched by their formatted value, so that 0.5 formatted as a
+// percentage is found as "50%".  An empty pattern is an error.
+func (f *File) Find(pattern string, opts FindOptions) ([]FoundCell, error) {
+	re, err := opts.compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("Find(%q): %w", pattern, err)
+	}
+	var found []FoundCell
+	err = f.forEachMatch(re, opts, true, func(sheet *Sheet, cell *Cell) error {
+		found = append(found, FoundCell{Sheet: sheet, Row: cell.Row.num, Col: cell.num})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Find(%q): %w", pattern, err)
+	}
+	return found, nil
+}
+
+// Replace replaces every match of the pattern in the cells of every
+// sheet, and returns the number of cells changed.  The formatting of
+// rich text is kept: text that replaces a match takes on the format
+// of the run in which the match starts.  Only the text of cells that
+// hold strings, or formulas when FindOptions.Formulas is set, is
+// replaced: cells that hold numbers, dates or booleans are left as
+// they are.  The cached results of formulas are left as they are;
+// File.Recalculate brings them up to date.  An empty pattern is an
+// error.
+func (f *File) Replace(pattern, replacement string, opts FindOptions) (int, error) {
+	wrap := func(err error) (int, error) {
+		return 0, fmt.Errorf("Replace(%q, %q): %w", pattern, replacement, err)
+	}
+	re, err := opts.compile(pattern)
+	if err != nil {
+		return wrap(err)
+	}
+	replace := func(text string) string {
+		if opts.Regexp {
+			return re.ReplaceAllString(text, replacement)
+		}
+		return re.ReplaceAllLiteralString(text, replacement)
+	}
+
+	type edit struct {
+		sheet    *Sheet
+		row, col int
+		text     string
+		richText []RichTextRun
+	}
+	var edits []edit
+	err = f.forEachMatch(re, opts, false, func(sheet *Sheet, cell *Cell) error {
+		e := edit{sheet: sheet, row: cell.Row.num, col: cell.num}
+		switch {
+		case opts.Formulas:
+			e.text = replace(cell.formula)
+		case len(cell.RichText) > 0:
+			e.richText = replaceRichText(cell.RichText, re, replacement, opts.Regexp)
+		default:
+			e.text = replace(cell.Value)
+		}
+		edits = append(edits, e)
+		return nil
+	})
+	if err != nil {
+		return wrap(err)
+	}
+
+	for _, e := range edits {
+		row, err := e.sheet.Row(e.row)
+		if err != nil {
+			return wrap(err)
+		}
+		cell := row.GetCell(e.col)
+		cell.Row = row
+		switch {
+		case opts.Formulas:
+			cell.updatable()
+			cell.formula = e.text
+			cell.modified = true
+		case e.richText != nil:
+			cell.SetRichText(e.richText)
+		default:
+			cell.SetString(e.text)
+		}
+		// Pushing the cell back makes stores that only hold the
+		// current cell in memory, such as DiskV, persist it
+		row.PushCell(cell)
+	}
+	return len(edits), nil
+}
+
+// replaceRichText replaces every match of re in the text of the runs.
+// The text that replaces a match goes into the run in which the match
+// starts, and runs left without any text are dropped.
+func replaceRichText(runs []RichTextRun, re *regexp.Regexp, replacement string, expand bool) []RichTextRun {
+	plain := richTextToPlainText(runs)
+	matches := re.FindAllStringSubmatchIndex(plain, -1)
+	texts := make([]string, len(matches))
+	for i, m := range matches {
+		if expand {
+			texts[i] = string(re.ExpandString(nil, replacement, plain, m))
+		} else {
+			texts[i] = replacement
+		}
+	}
+
+	result := []RichTextRun{}
+	mi, skipUntil, start := 0, 0, 0
+	for ri, run := range runs {
+		end := start + len(run.Text)
+		last := ri == len(runs)-1
+		var b strings.Builder
+		for i := start; i <= end; i++ {
+			// A match at the very end of a run belongs to the
+			// next run, unless this is the last
+			for mi < len(matches) && matches[mi][0] == i && (i < end || last) {
+				b.WriteString(texts[mi])
+				if matches[mi][1] > skipUntil {
+					skipUntil = matches[mi][1]
+				}
+				mi++
+			}
+			if i < end && i >= skipUntil {
+				b.WriteByte(plain[i])
+			}
+		}
+		start = end
+		if b.Len() > 0 {
+			run.Text = b.String()
+			result = append(result, run)
+		}
+	}
+	return result
+}
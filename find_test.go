@@ -0,0 +1,144 @@
+package xlsx
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestFind(t *testing.T) {
+	c := qt.New(t)
+
+	// makeFile returns a File with two sheets of text, numbers,
+	// formulas and rich text that mention Acme.
+	makeFile := func(c *qt.C, option FileOption) *File {
+		f := NewFile(option)
+		sheet1, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		sheet2, err := f.AddSheet("My Sheet")
+		c.Assert(err, qt.IsNil)
+		row := sheet1.AddRow()
+		row.AddCell().SetString("Acme Ltd")
+		row.AddCell().SetString("acme")
+		row.AddCell().SetInt(42)
+		row.AddCell().SetStringFormula(`"Acme "&A1`)
+		row = sheet2.AddRow()
+		row.AddCell().SetRichText([]RichTextRun{
+			{Text: "Made by Ac", Font: &RichTextFont{Bold: true}},
+			{Text: "me. Acme", Font: &RichTextFont{Italic: true}},
+			{Text: " rocks"},
+		})
+		row.AddCell().SetString("A-142")
+		row.AddCell().SetFloatWithFormat(0.125, "0.0%")
+		return f
+	}
+
+	csRunO(c, "Find", func(c *qt.C, option FileOption) {
+		f := makeFile(c, option)
+		refs := func(found []FoundCell) []string {
+			var s []string
+			for _, fc := range found {
+				s = append(s, fc.String())
+			}
+			return s
+		}
+
+		found, err := f.Find("acme", FindOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(refs(found), qt.DeepEquals, []string{"Sheet1!A1", "Sheet1!B1", "'My Sheet'!A1"})
+
+		found, err = f.Find("acme", FindOptions{MatchCase: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(refs(found), qt.DeepEquals, []string{"Sheet1!B1"})
+
+		found, err = f.Find("acme", FindOptions{WholeCell: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(refs(found), qt.DeepEquals, []string{"Sheet1!B1"})
+
+		found, err = f.Find("Acme", FindOptions{Formulas: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(refs(found), qt.DeepEquals, []string{"Sheet1!D1"})
+
+		found, err = f.Find(`A-\d+`, FindOptions{Regexp: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(refs(found), qt.DeepEquals, []string{"'My Sheet'!B1"})
+		// Numbers are found by their formatted value
+		found, err = f.Find("42", FindOptions{WholeCell: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(refs(found), qt.DeepEquals, []string{"Sheet1!C1"})
+		found, err = f.Find("12.5%", FindOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(refs(found), qt.DeepEquals, []string{"'My Sheet'!C1"})
+		found, err = f.Find("0.125", FindOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(found, qt.HasLen, 0)
+
+		_, err = f.Find("", FindOptions{})
+		c.Assert(err, qt.ErrorMatches, `Find\(""\): the pattern is empty`)
+
+		_, err = f.Find("(", FindOptions{Regexp: true})
+		c.Assert(err, qt.ErrorMatches, `Find\("\("\): .*`)
+	})
+
+	csRunO(c, "Replace", func(c *qt.C, option FileOption) {
+		f := makeFile(c, option)
+		n, err := f.Replace("acme", "Zenith", FindOptions{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(n, qt.Equals, 3)
+		n, err = f.Replace(`A-(\d+)`, "Z-$1", FindOptions{Regexp: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(n, qt.Equals, 1)
+		n, err = f.Replace("Acme ", "Zenith ", FindOptions{Formulas: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(n, qt.Equals, 1)
+		// Numbers are left alone
+		n, err = f.Replace("42", "x", FindOptions{WholeCell: true})
+		c.Assert(err, qt.IsNil)
+		c.Assert(n, qt.Equals, 0)
+		_, err = f.Replace("", "x", FindOptions{})
+		c.Assert(err, qt.ErrorMatches, `Replace\("", "x"\): the pattern is empty`)
+
+		var buf bytes.Buffer
+		c.Assert(f.Write(&buf), qt.IsNil)
+		f, err = OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+		cell, err := f.Sheets[0].Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "Zenith Ltd")
+		cell, err = f.Sheets[0].Cell(0, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "Zenith")
+		cell, err = f.Sheets[0].Cell(0, 3)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Formula(), qt.Equals, `"Zenith "&A1`)
+		cell, err = f.Sheets[1].Cell(0, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "Z-142")
+
+		// The match that spans two runs takes the format of the
+		// first, and the emptied run is dropped
+		cell, err = f.Sheets[1].Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.RichText, qt.HasLen, 3)
+		c.Assert(cell.RichText[0].Text, qt.Equals, "Made by Zenith")
+		c.Assert(cell.RichText[0].Font.Bold, qt.IsTrue)
+		c.Assert(cell.RichText[1].Text, qt.Equals, ". Zenith")
+		c.Assert(cell.RichText[1].Font.Italic, qt.IsTrue)
+		c.Assert(cell.RichText[2].Text, qt.Equals, " rocks")
+	})
+
+	c.Run("ReplaceRichText", func(c *qt.C) {
+		runs := []RichTextRun{{Text: "ab"}, {Text: "cd"}, {Text: "ef"}}
+		opts := FindOptions{}
+		re, err := opts.compile("bcde")
+		c.Assert(err, qt.IsNil)
+		c.Assert(replaceRichText(runs, re, "X", false), qt.DeepEquals, []RichTextRun{{Text: "aX"}, {Text: "f"}})
+		re, err = opts.compile("ef")
+		c.Assert(err, qt.IsNil)
+		c.Assert(replaceRichText(runs, re, "", false), qt.DeepEquals, []RichTextRun{{Text: "ab"}, {Text: "cd"}})
+		re, err = opts.compile("cd")
+		c.Assert(err, qt.IsNil)
+		c.Assert(replaceRichText(runs, re, "$1", false), qt.DeepEquals, []RichTextRun{{Text: "ab"}, {Text: "$1"}, {Text: "ef"}})
+	})
+}
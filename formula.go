@@ -0,0 +1,1348 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormulaTokenType identifies the kind of a FormulaToken.
+type FormulaTokenType int
+
+// Formula token types
+const (
+	FormulaTokenNumber FormulaTokenType = iota
+	FormulaTokenString
+	FormulaTokenBool
+	FormulaTokenError
+	// FormulaTokenReference is a cell or range reference, possibly
+	// qualified with a sheet or workbook, e.g. "'My Sheet'!$A$1:B2".
+	FormulaTokenReference
+	// FormulaTokenName is a defined name, possibly qualified with
+	// a sheet or workbook.
+	FormulaTokenName
+	// FormulaTokenStructuredReference is a reference into a table,
+	// e.g. "Table1[[#Headers],[Amount]]" or "[@Amount]".
+	FormulaTokenStructuredReference
+	// FormulaTokenFunction is the name of a function.  It is always
+	// followed by a FormulaTokenOpenParen.
+	FormulaTokenFunction
+	FormulaTokenOperator
+	FormulaTokenOpenParen
+	FormulaTokenCloseParen
+	FormulaTokenOpenArray
+	FormulaTokenCloseArray
+	FormulaTokenComma
+	FormulaTokenSemicolon
+	FormulaTokenWhitespace
+)
+
+// FormulaToken is a single lexical element of a formula.  Joining the
+// Values of all the tokens of a formula gives back the formula.
+type FormulaToken struct {
+	Type  FormulaTokenType
+	Value string
+	Pos   int
+}
+
+// formulaErrors are the error values that may appear in a formula.
+var formulaErrors = []string{
+	"#NULL!", "#DIV/0!", "#VALUE!", "#REF!", "#NAME?", "#NUM!", "#N/A",
+	"#GETTING_DATA", "#SPILL!", "#CALC!", "#FIELD!", "#BLOCKED!", "#UNKNOWN!",
+}
+
+// TokenizeFormula splits a formula written in A1 notation into its
+// tokens.  Whitespace is kept, as a space between two references is
+// Excel's intersection operator.
+func TokenizeFormula(formula string) ([]FormulaToken, error) {
+	return tokenizeFormula(formula, false)
+}
+
+// TokenizeFormulaR1C1 splits a formula written in R1C1 notation into
+// its tokens.
+func TokenizeFormulaR1C1(formula string) ([]FormulaToken, error) {
+	return tokenizeFormula(formula, true)
+}
+
+type formulaLexer struct {
+	s      string
+	pos    int
+	r1c1   bool
+	tokens []FormulaToken
+}
+
+func tokenizeFormula(formula string, r1c1 bool) ([]FormulaToken, error) {
+	l := &formulaLexer{s: formula, r1c1: r1c1}
+	for l.pos < len(l.s) {
+		if err := l.next(); err != nil {
+			return nil, fmt.Errorf("TokenizeFormula(%q): %w", formula, err)
+		}
+	}
+	return l.tokens, nil
+}
+
+func (l *formulaLexer) emit(t FormulaTokenType, end int) {
+	l.tokens = append(l.tokens, FormulaToken{Type: t, Value: l.s[l.pos:end], Pos: l.pos})
+	l.pos = end
+}
+
+func (l *formulaLexer) next() error {
+	s, i := l.s, l.pos
+	c := s[i]
+	switch {
+	case isFormulaSpace(c):
+		end := i
+		for end < len(s) && isFormulaSpace(s[end]) {
+			end++
+		}
+		l.emit(FormulaTokenWhitespace, end)
+	case c == '"':
+		end, err := scanQuoted(s, i)
+		if err != nil {
+			return err
+		}
+		l.emit(FormulaTokenString, end)
+	case c == '#':
+		if end := matchFormulaError(s, i); end > 0 {
+			l.emit(FormulaTokenError, end)
+		} else {
+			// The spill operator, as in A1#
+			l.emit(FormulaTokenOperator, i+1)
+		}
+	case c == '(':
+		l.emit(FormulaTokenOpenParen, i+1)
+	case c == ')':
+		l.emit(FormulaTokenCloseParen, i+1)
+	case c == '{':
+		l.emit(FormulaTokenOpenArray, i+1)
+	case c == '}':
+		l.emit(FormulaTokenCloseArray, i+1)
+	case c == ',':
+		l.emit(FormulaTokenComma, i+1)
+	case c == ';':
+		l.emit(FormulaTokenSemicolon, i+1)
+	case c == '<' || c == '>':
+		if i+1 < len(s) && (s[i+1] == '=' || (c == '<' && s[i+1] == '>')) {
+			l.emit(FormulaTokenOperator, i+2)
+		} else {
+			l.emit(FormulaTokenOperator, i+1)
+		}
+	case strings.IndexByte("+-*/^&=%:@", c) >= 0:
+		l.emit(FormulaTokenOperator, i+1)
+	case isDigit(c) || (c == '.' && i+1 < len(s) && isDigit(s[i+1])):
+		// A row range, such as 1:3, also starts with a digit
+		if end := l.matchRef(i); end > 0 {
+			l.emit(FormulaTokenReference, end)
+		} else {
+			l.emit(FormulaTokenNumber, scanNumber(s, i))
+		}
+	default:
+		return l.operand()
+	}
+	return nil
+}
+
+// operand lexes references, names, functions and booleans, all of
+// which may start with a word character, or with a sheet prefix.
+func (l *formulaLexer) operand() error {
+	s, i := l.s, l.pos
+	body, prefixed, err := scanRefPrefix(s, i)
+	if err != nil {
+		return err
+	}
+	if !prefixed && s[i] == '[' {
+		// A structured reference to the current table, e.g. [@Amount]
+		end, err := scanBrackets(s, i)
+		if err != nil {
+			return err
+		}
+		l.emit(FormulaTokenStructuredReference, end)
+		return nil
+	}
+	if prefixed && body < len(s) && s[body] == '#' {
+		// e.g. Sheet1!#REF!
+		end := matchFormulaError(s, body)
+		if end < 0 {
+			return fmt.Errorf("unexpected %q at position %d", s[body], body)
+		}
+		l.emit(FormulaTokenReference, end)
+		return nil
+	}
+	if end := l.matchRef(body); end > 0 {
+		l.emit(FormulaTokenReference, end)
+		return nil
+	}
+	end := scanWord(s, body)
+	if end == body {
+		return fmt.Errorf("unexpected %q at position %d", s[body], body)
+	}
+	word := s[body:end]
+	switch {
+	case !prefixed && end < len(s) && s[end] == '(':
+		l.emit(FormulaTokenFunction, end)
+	case end < len(s) && s[end] == '[':
+		end, err = scanBrackets(s, end)
+		if err != nil {
+			return err
+		}
+		l.emit(FormulaTokenStructuredReference, end)
+	case !prefixed && (strings.EqualFold(word, "TRUE") || strings.EqualFold(word, "FALSE")):
+		l.emit(FormulaTokenBool, end)
+	default:
+		l.emit(FormulaTokenName, end)
+	}
+	return nil
+}
+
+func (l *formulaLexer) matchRef(i int) int {
+	if l.r1c1 {
+		return matchR1C1Ref(l.s, i)
+	}
+	return matchA1Ref(l.s, i)
+}
+
+func isFormulaSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// isWordChar returns true for the characters that may appear in
+// names, references and unquoted sheet names.  Any non-ASCII byte is
+// accepted, so that names in other scripts survive.
+func isWordChar(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '_' || c == '.' || c == '\\' || c == '?' || c == '$' || c >= 0x80
+}
+
+func scanWord(s string, i int) int {
+	for i < len(s) && isWordChar(s[i]) {
+		i++
+	}
+	return i
+}
+
+// scanQuoted returns the position after the quoted text starting at
+// i, where the quote character is escaped by doubling it.
+func scanQuoted(s string, i int) (int, error) {
+	q := s[i]
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != q {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == q {
+			j++
+			continue
+		}
+		return j + 1, nil
+	}
+	return 0, fmt.Errorf("unterminated %c at position %d", q, i)
+}
+
+// scanBrackets returns the position after the balanced square
+// brackets starting at i.  Within brackets an apostrophe escapes the
+// following character.
+func scanBrackets(s string, i int) (int, error) {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\'':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return j + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated [ at position %d", i)
+}
+
+func scanNumber(s string, i int) int {
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+	}
+	if i < len(s) && (s[i] == 'E' || s[i] == 'e') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			for i = j; i < len(s) && isDigit(s[i]); i++ {
+			}
+		}
+	}
+	return i
+}
+
+// matchFormulaError returns the position after the error value
+// starting at i, or -1 if there isn't one.
+func matchFormulaError(s string, i int) int {
+	for _, e := range formulaErrors {
+		if len(s)-i >= len(e) && strings.EqualFold(s[i:i+len(e)], e) {
+			return i + len(e)
+		}
+	}
+	return -1
+}
+
+// scanRefPrefix looks for a sheet or workbook prefix, such as
+// "Sheet1!", "'My Sheet'!", "Sheet1:Sheet3!" or "[1]Sheet1!", starting
+// at i.  It returns the position after the "!" if there is one.
+func scanRefPrefix(s string, i int) (int, bool, error) {
+	if s[i] == '\'' {
+		end, err := scanQuoted(s, i)
+		if err != nil {
+			return 0, false, err
+		}
+		if end >= len(s) || s[end] != '!' {
+			return 0, false, fmt.Errorf("expected ! after the quoted sheet name at position %d", i)
+		}
+		return end + 1, true, nil
+	}
+	j := i
+	if s[j] == '[' {
+		k := strings.IndexByte(s[j:], ']')
+		if k < 0 {
+			return i, false, nil
+		}
+		j += k + 1
+	}
+	w := scanWord(s, j)
+	if w < len(s) && s[w] == '!' {
+		return w + 1, true, nil
+	}
+	if w > j && w < len(s) && s[w] == ':' {
+		// A 3D reference, e.g. Sheet1:Sheet3!A1
+		w2 := scanWord(s, w+1)
+		if w2 > w+1 && w2 < len(s) && s[w2] == '!' {
+			return w2 + 1, true, nil
+		}
+	}
+	return i, false, nil
+}
+
+// isRefBoundary returns true if a reference may end at position i,
+// rather than being the start of a longer name or a function.
+func isRefBoundary(s string, i int) bool {
+	if i >= len(s) {
+		return true
+	}
+	c := s[i]
+	return !isWordChar(c) && c != '(' && c != '[' && c != '!'
+}
+
+// matchA1Ref returns the position after the A1 style reference
+// starting at i, or -1 if there isn't one.
+func matchA1Ref(s string, i int) int {
+	if e := matchA1Cell(s, i); e > 0 {
+		if e < len(s) && s[e] == ':' {
+			if e2 := matchA1Cell(s, e+1); e2 > 0 && isRefBoundary(s, e2) {
+				return e2
+			}
+		}
+		if isRefBoundary(s, e) {
+			return e
+		}
+		return -1
+	}
+	for _, match := range []func(string, int) int{matchA1Col, matchA1Row} {
+		if e := match(s, i); e > 0 && e < len(s) && s[e] == ':' {
+			if e2 := match(s, e+1); e2 > 0 && isRefBoundary(s, e2) {
+				return e2
+			}
+		}
+	}
+	return -1
+}
+
+func matchA1Cell(s string, i int) int {
+	e := matchA1Col(s, i)
+	if e < 0 {
+		return -1
+	}
+	return matchA1Row(s, e)
+}
+
+func matchA1Col(s string, i int) int {
+	if i < len(s) && s[i] == '$' {
+		i++
+	}
+	start := i
+	for i < len(s) && isLetter(s[i]) {
+		i++
+	}
+	if i == start || i-start > 3 || ColLettersToIndex(s[start:i]) > Excel2006MaxColIndex {
+		return -1
+	}
+	return i
+}
+
+func matchA1Row(s string, i int) int {
+	if i < len(s) && s[i] == '$' {
+		i++
+	}
+	start := i
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i == start || i-start > 7 {
+		return -1
+	}
+	row, _ := strconv.Atoi(s[start:i])
+	if row < 1 || row > Excel2006MaxRowCount {
+		return -1
+	}
+	return i
+}
+
+// matchR1C1Ref returns the position after the R1C1 style reference
+// starting at i, or -1 if there isn't one.
+func matchR1C1Ref(s string, i int) int {
+	e := matchR1C1Part(s, i)
+	if e < 0 {
+		return -1
+	}
+	if e < len(s) && s[e] == ':' {
+		if e2 := matchR1C1Part(s, e+1); e2 > 0 && isRefBoundary(s, e2) {
+			return e2
+		}
+	}
+	if isRefBoundary(s, e) {
+		return e
+	}
+	return -1
+}
+
+func matchR1C1Part(s string, i int) int {
+	start := i
+	for _, letter := range []byte{'R', 'C'} {
+		if i < len(s) && (s[i] == letter || s[i] == letter+'a'-'A') {
+			i = matchR1C1Offset(s, i+1)
+			if i < 0 {
+				return -1
+			}
+		}
+	}
+	if i == start {
+		return -1
+	}
+	return i
+}
+
+func matchR1C1Offset(s string, i int) int {
+	if i < len(s) && s[i] == '[' {
+		j := i + 1
+		if j < len(s) && (s[j] == '-' || s[j] == '+') {
+			j++
+		}
+		k := j
+		for k < len(s) && isDigit(s[k]) {
+			k++
+		}
+		if k == j || k >= len(s) || s[k] != ']' {
+			return -1
+		}
+		return k + 1
+	}
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// CellRef is one end of a Reference.  In A1 notation Col and Row are
+// zero based indices.  In R1C1 notation they are zero based indices
+// when absolute, and offsets from the cell holding the formula when
+// relative.
+type CellRef struct {
+	Col         int
+	Row         int
+	ColAbsolute bool
+	RowAbsolute bool
+	// NoCol is set when only a row is referenced, as in "1:3"
+	NoCol bool
+	// NoRow is set when only a column is referenced, as in "A:C"
+	NoRow bool
+}
+
+// Reference is a cell or range reference within a formula.
+type Reference struct {
+	// Workbook is the prefix that identifies an external
+	// workbook, e.g. "[1]" or "C:\Reports\[Budget.xlsx]"
+	Workbook string
+	Sheet    string
+	// LastSheet is set for 3D references, e.g. Sheet1:Sheet3!A1
+	LastSheet string
+	First     CellRef
+	Last      CellRef
+	IsRange   bool
+	R1C1      bool
+}
+
+// ParseReference parses a reference in A1 notation, such as "B2",
+// "$A$1:$C$3", "A:C", "1:3" or "'My Sheet'!A1".
+func ParseReference(ref string) (Reference, error) {
+	return parseReference(ref, false)
+}
+
+// ParseReferenceR1C1 parses a reference in R1C1 notation, such as
+// "R2C2", "R[-1]C", "C1:C3" or "'My Sheet'!R1C1".
+func ParseReferenceR1C1(ref string) (Reference, error) {
+	return parseReference(ref, true)
+}
+
+func parseReference(text string, r1c1 bool) (Reference, error) {
+	wrap := func(err error) (Reference, error) {
+		return Reference{}, fmt.Errorf("ParseReference(%q): %w", text, err)
+	}
+	r := Reference{R1C1: r1c1}
+	if text == "" {
+		return wrap(fmt.Errorf("empty reference"))
+	}
+	body, prefixed, err := scanRefPrefix(text, 0)
+	if err != nil {
+		return wrap(err)
+	}
+	if prefixed {
+		r.Workbook, r.Sheet, r.LastSheet = parseRefPrefix(text[:body-1])
+	}
+
+	parse := parseA1CellRef
+	if r1c1 {
+		parse = parseR1C1CellRef
+	}
+	parts := strings.Split(text[body:], cellRangeChar)
+	if len(parts) > 2 {
+		return wrap(fmt.Errorf("too many %q", cellRangeChar))
+	}
+	r.First, err = parse(parts[0])
+	if err != nil {
+		return wrap(err)
+	}
+	r.Last = r.First
+	if len(parts) == 2 {
+		r.IsRange = true
+		r.Last, err = parse(parts[1])
+		if err != nil {
+			return wrap(err)
+		}
+		if r.First.NoCol != r.Last.NoCol || r.First.NoRow != r.Last.NoRow {
+			return wrap(fmt.Errorf("both ends of a range must be of the same kind"))
+		}
+	} else if !r1c1 && (r.First.NoCol || r.First.NoRow) {
+		return wrap(fmt.Errorf("a whole row or column must be given as a range"))
+	}
+	return r, nil
+}
+
+// parseRefPrefix splits the part of a reference before the "!" into
+// the workbook, sheet and last sheet.
+func parseRefPrefix(prefix string) (workbook, sheet, lastSheet string) {
+	if strings.HasPrefix(prefix, "'") {
+		prefix = strings.ReplaceAll(prefix[1:len(prefix)-1], "''", "'")
+	}
+	if i := strings.LastIndexByte(prefix, ']'); i >= 0 {
+		workbook, prefix = prefix[:i+1], prefix[i+1:]
+	}
+	sheet = prefix
+	if i := strings.IndexByte(prefix, ':'); i >= 0 {
+		sheet, lastSheet = prefix[:i], prefix[i+1:]
+	}
+	return workbook, sheet, lastSheet
+}
+
+// formatRefPrefix returns the prefix, including the "!", for a
+// reference to the given workbook and sheets, quoted if necessary.
+func formatRefPrefix(workbook, sheet, lastSheet string) string {
+	if workbook == "" && sheet == "" {
+		return ""
+	}
+	names := sheet
+	if lastSheet != "" {
+		names += ":" + lastSheet
+	}
+	quote := sheet != "" && quoteSheetName(sheet) != sheet
+	quote = quote || (lastSheet != "" && quoteSheetName(lastSheet) != lastSheet)
+	if workbook != "" {
+		digits := strings.Trim(workbook, "[]")
+		quote = quote || workbook != "["+digits+"]" || strings.TrimLeft(digits, "0123456789") != ""
+	}
+	if quote {
+		return "'" + strings.ReplaceAll(workbook+names, "'", "''") + "'" + externalSheetBangChar
+	}
+	return workbook + names + externalSheetBangChar
+}
+
+func parseA1CellRef(s string) (CellRef, error) {
+	var ref CellRef
+	i := 0
+	if i < len(s) && s[i] == '$' {
+		ref.ColAbsolute = true
+		i++
+	}
+	start := i
+	for i < len(s) && isLetter(s[i]) {
+		i++
+	}
+	letters := s[start:i]
+	if i < len(s) && s[i] == '$' {
+		ref.RowAbsolute = true
+		i++
+	}
+	digits := s[i:]
+	if letters == "" {
+		// A whole row; the $ that was taken for the column
+		// belongs to the row.
+		ref.NoCol = true
+		ref.RowAbsolute = ref.RowAbsolute || ref.ColAbsolute
+		ref.ColAbsolute = false
+	}
+	if digits == "" {
+		ref.NoRow = true
+	}
+	if (ref.NoCol && ref.NoRow) || (ref.NoCol && ref.RowAbsolute && strings.Count(s, "$") > 1) {
+		return ref, fmt.Errorf("invalid reference %q", s)
+	}
+	if !ref.NoCol {
+		if matchA1Col(letters, 0) != len(letters) {
+			return ref, fmt.Errorf("invalid column in %q", s)
+		}
+		ref.Col = ColLettersToIndex(letters)
+	}
+	if !ref.NoRow {
+		if matchA1Row(digits, 0) != len(digits) {
+			return ref, fmt.Errorf("invalid row in %q", s)
+		}
+		row, _ := strconv.Atoi(digits)
+		ref.Row = row - 1
+	}
+	return ref, nil
+}
+
+func parseR1C1CellRef(s string) (CellRef, error) {
+	ref := CellRef{NoRow: true, NoCol: true}
+	i := 0
+	parseOffset := func() (int, bool, error) {
+		if i < len(s) && s[i] == '[' {
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return 0, false, fmt.Errorf("invalid reference %q", s)
+			}
+			n, err := strconv.Atoi(strings.TrimPrefix(s[i+1:i+end], "+"))
+			if err != nil {
+				return 0, false, fmt.Errorf("invalid offset in %q", s)
+			}
+			i += end + 1
+			return n, false, nil
+		}
+		start := i
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		if i == start {
+			return 0, false, nil
+		}
+		n, err := strconv.Atoi(s[start:i])
+		if err != nil || n < 1 {
+			return 0, false, fmt.Errorf("invalid index in %q", s)
+		}
+		return n - 1, true, nil
+	}
+	var err error
+	if i < len(s) && (s[i] == 'R' || s[i] == 'r') {
+		i++
+		ref.NoRow = false
+		ref.Row, ref.RowAbsolute, err = parseOffset()
+		if err != nil {
+			return ref, err
+		}
+	}
+	if i < len(s) && (s[i] == 'C' || s[i] == 'c') {
+		i++
+		ref.NoCol = false
+		ref.Col, ref.ColAbsolute, err = parseOffset()
+		if err != nil {
+			return ref, err
+		}
+	}
+	if i != len(s) || (ref.NoRow && ref.NoCol) {
+		return ref, fmt.Errorf("invalid reference %q", s)
+	}
+	if (ref.RowAbsolute && ref.Row > Excel2006MaxRowIndex) || (ref.ColAbsolute && ref.Col > Excel2006MaxColIndex) {
+		return ref, fmt.Errorf("reference %q is out of range", s)
+	}
+	return ref, nil
+}
+
+func (r CellRef) a1String() string {
+	var b strings.Builder
+	if !r.NoCol {
+		if r.ColAbsolute {
+			b.WriteString(fixedCellRefChar)
+		}
+		b.WriteString(ColIndexToLetters(r.Col))
+	}
+	if !r.NoRow {
+		if r.RowAbsolute {
+			b.WriteString(fixedCellRefChar)
+		}
+		b.WriteString(RowIndexToString(r.Row))
+	}
+	return b.String()
+}
+
+func (r CellRef) r1c1String() string {
+	part := func(letter string, n int, absolute bool) string {
+		switch {
+		case absolute:
+			return letter + strconv.Itoa(n+1)
+		case n == 0:
+			return letter
+		}
+		return letter + "[" + strconv.Itoa(n) + "]"
+	}
+	s := ""
+	if !r.NoRow {
+		s += part("R", r.Row, r.RowAbsolute)
+	}
+	if !r.NoCol {
+		s += part("C", r.Col, r.ColAbsolute)
+	}
+	return s
+}
+
+// String returns the reference in the notation it was written in.
+func (r Reference) String() string {
+	format := CellRef.a1String
+	if r.R1C1 {
+		format = CellRef.r1c1String
+	}
+	s := formatRefPrefix(r.Workbook, r.Sheet, r.LastSheet) + format(r.First)
+	if r.IsRange {
+		s += cellRangeChar + format(r.Last)
+	}
+	return s
+}
+
+// CellRange returns the cells covered by a reference in A1 notation.
+func (r Reference) CellRange() CellRange {
+	last := r.First
+	if r.IsRange {
+		last = r.Last
+	}
+	if r.First.NoCol {
+		return RowsRange(r.First.Row, last.Row)
+	}
+	if r.First.NoRow {
+		return ColsRange(r.First.Col, last.Col)
+	}
+	return NewCellRange(r.First.Col, r.First.Row, last.Col, last.Row)
+}
+
+// offset moves the relative parts of a reference in A1 notation by
+// the given number of columns and rows.  It returns false if the
+// result would lie outside the sheet.
+func (r Reference) offset(dx, dy int) (Reference, bool) {
+	move := func(c CellRef) (CellRef, bool) {
+		if !c.NoCol && !c.ColAbsolute {
+			c.Col += dx
+		}
+		if !c.NoRow && !c.RowAbsolute {
+			c.Row += dy
+		}
+		ok := (c.NoCol || (c.Col >= 0 && c.Col <= Excel2006MaxColIndex)) &&
+			(c.NoRow || (c.Row >= 0 && c.Row <= Excel2006MaxRowIndex))
+		return c, ok
+	}
+	var ok1, ok2 bool
+	r.First, ok1 = move(r.First)
+	r.Last, ok2 = move(r.Last)
+	return r, ok1 && ok2
+}
+
+// FormulaNode is a node of the syntax tree produced by ParseFormula.
+// String returns the node, including its children, as formula text.
+type FormulaNode interface {
+	String() string
+}
+
+// NumberNode is a numeric constant.  Text holds the number as it was
+// written, and is used in preference to Value when it is set.
+type NumberNode struct {
+	Value float64
+	Text  string
+}
+
+// StringNode is a string constant.
+type StringNode struct {
+	Value string
+}
+
+// BoolNode is a TRUE or FALSE constant.
+type BoolNode struct {
+	Value bool
+}
+
+// ErrorNode is an error constant, e.g. #N/A.
+type ErrorNode struct {
+	Value string
+}
+
+// ReferenceNode is a cell or range reference.
+type ReferenceNode struct {
+	Ref Reference
+}
+
+// NameNode is a defined name, possibly qualified with a sheet or
+// workbook.
+type NameNode struct {
+	Workbook string
+	Sheet    string
+	Name     string
+}
+
+// StructuredRefNode is a reference into a table, e.g.
+// Table1[[#Headers],[Amount]], where Table is "Table1" and Specifier
+// is "[[#Headers],[Amount]]".  Table is empty for references to the
+// current table, e.g. [@Amount].
+type StructuredRefNode struct {
+	Table     string
+	Specifier string
+}
+
+// FunctionNode is a call of a function.  Missing arguments, as in
+// IF(A1,,1), are represented by a MissingArgNode.
+type FunctionNode struct {
+	Name string
+	Args []FormulaNode
+}
+
+// UnaryOpNode is a prefix operator: "-", "+" or the implicit
+// intersection operator "@".
+type UnaryOpNode struct {
+	Op      string
+	Operand FormulaNode
+}
+
+// PostfixOpNode is a postfix operator: "%" or the spill operator "#".
+type PostfixOpNode struct {
+	Op      string
+	Operand FormulaNode
+}
+
+// BinaryOpNode is an infix operator.  As well as the arithmetic,
+// comparison and "&" operators, Op may be one of the reference
+// operators: ":" for a range, " " for an intersection and "," for a
+// union.
+type BinaryOpNode struct {
+	Op    string
+	Left  FormulaNode
+	Right FormulaNode
+}
+
+// ParenNode is an expression in parentheses.
+type ParenNode struct {
+	Expr FormulaNode
+}
+
+// ArrayNode is an array constant, e.g. {1,2;3,4}.
+type ArrayNode struct {
+	Rows [][]FormulaNode
+}
+
+// MissingArgNode is an omitted function argument.
+type MissingArgNode struct{}
+
+func (n *NumberNode) String() string {
+	if n.Text != "" {
+		return n.Text
+	}
+	return strconv.FormatFloat(n.Value, 'G', -1, 64)
+}
+
+func (n *StringNode) String() string {
+	return `"` + strings.ReplaceAll(n.Value, `"`, `""`) + `"`
+}
+
+func (n *BoolNode) String() string {
+	if n.Value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+func (n *ErrorNode) String() string {
+	return n.Value
+}
+
+func (n *ReferenceNode) String() string {
+	return n.Ref.String()
+}
+
+func (n *NameNode) String() string {
+	return formatRefPrefix(n.Workbook, n.Sheet, "") + n.Name
+}
+
+func (n *StructuredRefNode) String() string {
+	return n.Table + n.Specifier
+}
+
+func (n *FunctionNode) String() string {
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = arg.String()
+	}
+	return n.Name + "(" + strings.Join(args, ",") + ")"
+}
+
+func (n *UnaryOpNode) String() string {
+	return n.Op + n.Operand.String()
+}
+
+func (n *PostfixOpNode) String() string {
+	return n.Operand.String() + n.Op
+}
+
+func (n *BinaryOpNode) String() string {
+	return n.Left.String() + n.Op + n.Right.String()
+}
+
+func (n *ParenNode) String() string {
+	return "(" + n.Expr.String() + ")"
+}
+
+func (n *ArrayNode) String() string {
+	rows := make([]string, len(n.Rows))
+	for i, row := range n.Rows {
+		cols := make([]string, len(row))
+		for j, v := range row {
+			cols[j] = v.String()
+		}
+		rows[i] = strings.Join(cols, ",")
+	}
+	return "{" + strings.Join(rows, ";") + "}"
+}
+
+func (n *MissingArgNode) String() string {
+	return ""
+}
+
+// WalkFormula calls fn for node and then, if fn returns true, for
+// each of its children in turn, depth first.
+func WalkFormula(node FormulaNode, fn func(FormulaNode) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *FunctionNode:
+		for _, arg := range n.Args {
+			WalkFormula(arg, fn)
+		}
+	case *UnaryOpNode:
+		WalkFormula(n.Operand, fn)
+	case *PostfixOpNode:
+		WalkFormula(n.Operand, fn)
+	case *BinaryOpNode:
+		WalkFormula(n.Left, fn)
+		WalkFormula(n.Right, fn)
+	case *ParenNode:
+		WalkFormula(n.Expr, fn)
+	case *ArrayNode:
+		for _, row := range n.Rows {
+			for _, v := range row {
+				WalkFormula(v, fn)
+			}
+		}
+	}
+}
+
+// ParseFormula parses a formula written in A1 notation, with or
+// without a leading "=", into a syntax tree.  Operators follow
+// Excel's precedence, from highest to lowest: the reference operators
+// ":", " " and ",", negation, "%", "^", "*" and "/", "+" and "-",
+// "&", and finally the comparisons.
+func ParseFormula(formula string) (FormulaNode, error) {
+	return parseFormula(formula, false)
+}
+
+// ParseFormulaR1C1 parses a formula written in R1C1 notation into a
+// syntax tree.
+func ParseFormulaR1C1(formula string) (FormulaNode, error) {
+	return parseFormula(formula, true)
+}
+
+type formulaParser struct {
+	tokens []FormulaToken
+	// spaced records whether each token was preceded by whitespace
+	spaced []bool
+	pos    int
+	r1c1   bool
+}
+
+func parseFormula(formula string, r1c1 bool) (FormulaNode, error) {
+	wrap := func(err error) (FormulaNode, error) {
+		return nil, fmt.Errorf("ParseFormula(%q): %w", formula, err)
+	}
+	tokens, err := tokenizeFormula(strings.TrimPrefix(formula, "="), r1c1)
+	if err != nil {
+		return wrap(err)
+	}
+	p := &formulaParser{r1c1: r1c1}
+	space := false
+	for _, t := range tokens {
+		if t.Type == FormulaTokenWhitespace {
+			space = true
+			continue
+		}
+		p.tokens = append(p.tokens, t)
+		p.spaced = append(p.spaced, space)
+		space = false
+	}
+	if len(p.tokens) == 0 {
+		return wrap(fmt.Errorf("empty formula"))
+	}
+	node, err := p.parseExpr(true)
+	if err != nil {
+		return wrap(err)
+	}
+	if t := p.peek(); t != nil {
+		return wrap(fmt.Errorf("unexpected %q at position %d", t.Value, t.Pos))
+	}
+	return node, nil
+}
+
+func (p *formulaParser) peek() *FormulaToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *formulaParser) peekType(t FormulaTokenType) bool {
+	tok := p.peek()
+	return tok != nil && tok.Type == t
+}
+
+// peekOp returns the operator at the current position if it's one of
+// ops.
+func (p *formulaParser) peekOp(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok == nil || tok.Type != FormulaTokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.Value == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *formulaParser) unexpected() error {
+	if tok := p.peek(); tok != nil {
+		return fmt.Errorf("unexpected %q at position %d", tok.Value, tok.Pos)
+	}
+	return fmt.Errorf("unexpected end of formula")
+}
+
+// parseExpr parses a full expression.  allowUnion is false within the
+// arguments of a function, where a comma separates the arguments.
+func (p *formulaParser) parseExpr(allowUnion bool) (FormulaNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for allowUnion && p.peekType(FormulaTokenComma) {
+		p.pos++
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOpNode{Op: ",", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) parseBinary(next func() (FormulaNode, error), ops ...string) (FormulaNode, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekOp(ops...)
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOpNode{Op: op, Left: left, Right: right}
+	}
+}
+
+func (p *formulaParser) parseComparison() (FormulaNode, error) {
+	return p.parseBinary(p.parseConcat, "=", "<>", "<", ">", "<=", ">=")
+}
+
+func (p *formulaParser) parseConcat() (FormulaNode, error) {
+	return p.parseBinary(p.parseAdditive, "&")
+}
+
+func (p *formulaParser) parseAdditive() (FormulaNode, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *formulaParser) parseMultiplicative() (FormulaNode, error) {
+	return p.parseBinary(p.parsePower, "*", "/")
+}
+
+func (p *formulaParser) parsePower() (FormulaNode, error) {
+	return p.parseBinary(p.parsePercent, "^")
+}
+
+func (p *formulaParser) parsePercent() (FormulaNode, error) {
+	node, err := p.parsePrefix()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOp("%"); !ok {
+			return node, nil
+		}
+		p.pos++
+		node = &PostfixOpNode{Op: "%", Operand: node}
+	}
+}
+
+func (p *formulaParser) parsePrefix() (FormulaNode, error) {
+	if op, ok := p.peekOp("-", "+", "@"); ok {
+		p.pos++
+		operand, err := p.parsePrefix()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryOpNode{Op: op, Operand: operand}, nil
+	}
+	return p.parseIntersection()
+}
+
+func (p *formulaParser) parseIntersection() (FormulaNode, error) {
+	left, err := p.parseRange()
+	if err != nil {
+		return nil, err
+	}
+	for p.pos < len(p.tokens) && p.spaced[p.pos] && startsReference(p.tokens[p.pos]) {
+		right, err := p.parseRange()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOpNode{Op: " ", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// startsReference returns true for the tokens that may begin an
+// operand of the intersection operator.
+func startsReference(t FormulaToken) bool {
+	switch t.Type {
+	case FormulaTokenReference, FormulaTokenName, FormulaTokenStructuredReference,
+		FormulaTokenFunction, FormulaTokenOpenParen:
+		return true
+	}
+	return false
+}
+
+func (p *formulaParser) parseRange() (FormulaNode, error) {
+	return p.parseBinary(p.parsePrimary, ":")
+}
+
+func (p *formulaParser) parsePrimary() (FormulaNode, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, p.unexpected()
+	}
+	var node FormulaNode
+	switch tok.Type {
+	case FormulaTokenNumber:
+		v, err := strconv.ParseFloat(tok.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.Value, tok.Pos)
+		}
+		node = &NumberNode{Value: v, Text: tok.Value}
+	case FormulaTokenString:
+		node = &StringNode{Value: strings.ReplaceAll(tok.Value[1:len(tok.Value)-1], `""`, `"`)}
+	case FormulaTokenBool:
+		node = &BoolNode{Value: strings.EqualFold(tok.Value, "TRUE")}
+	case FormulaTokenError:
+		node = &ErrorNode{Value: strings.ToUpper(tok.Value)}
+	case FormulaTokenReference:
+		ref, err := parseReference(tok.Value, p.r1c1)
+		if err != nil {
+			if !strings.HasSuffix(strings.ToUpper(tok.Value), "#REF!") {
+				return nil, err
+			}
+			node = &ErrorNode{Value: "#REF!"}
+		} else {
+			node = &ReferenceNode{Ref: ref}
+		}
+	case FormulaTokenName:
+		n := &NameNode{Name: tok.Value}
+		if body, prefixed, _ := scanRefPrefix(tok.Value, 0); prefixed {
+			n.Workbook, n.Sheet, _ = parseRefPrefix(tok.Value[:body-1])
+			n.Name = tok.Value[body:]
+		}
+		node = n
+	case FormulaTokenStructuredReference:
+		i := strings.IndexByte(tok.Value, '[')
+		node = &StructuredRefNode{Table: tok.Value[:i], Specifier: tok.Value[i:]}
+	case FormulaTokenFunction:
+		p.pos++
+		return p.parseFunction(tok.Value)
+	case FormulaTokenOpenParen:
+		p.pos++
+		expr, err := p.parseExpr(true)
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekType(FormulaTokenCloseParen) {
+			return nil, p.unexpected()
+		}
+		node = &ParenNode{Expr: expr}
+	case FormulaTokenOpenArray:
+		p.pos++
+		return p.parseArray()
+	default:
+		return nil, p.unexpected()
+	}
+	p.pos++
+	return p.maybeSpill(node), nil
+}
+
+// maybeSpill applies the spill operator, as in A1#, if it follows.
+func (p *formulaParser) maybeSpill(node FormulaNode) FormulaNode {
+	if _, ok := p.peekOp("#"); ok {
+		p.pos++
+		return &PostfixOpNode{Op: "#", Operand: node}
+	}
+	return node
+}
+
+func (p *formulaParser) parseFunction(name string) (FormulaNode, error) {
+	if !p.peekType(FormulaTokenOpenParen) {
+		return nil, p.unexpected()
+	}
+	p.pos++
+	fn := &FunctionNode{Name: name}
+	if p.peekType(FormulaTokenCloseParen) {
+		p.pos++
+		return p.maybeSpill(fn), nil
+	}
+	for {
+		var arg FormulaNode = &MissingArgNode{}
+		if !p.peekType(FormulaTokenComma) && !p.peekType(FormulaTokenCloseParen) {
+			var err error
+			arg, err = p.parseExpr(false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		fn.Args = append(fn.Args, arg)
+		switch {
+		case p.peekType(FormulaTokenComma):
+			p.pos++
+		case p.peekType(FormulaTokenCloseParen):
+			p.pos++
+			return p.maybeSpill(fn), nil
+		default:
+			return nil, p.unexpected()
+		}
+	}
+}
+
+func (p *formulaParser) parseArray() (FormulaNode, error) {
+	array := &ArrayNode{}
+	row := []FormulaNode{}
+	for {
+		value, err := p.parseArrayConstant()
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, value)
+		tok := p.peek()
+		if tok == nil {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		switch tok.Type {
+		case FormulaTokenComma:
+		case FormulaTokenSemicolon:
+			array.Rows = append(array.Rows, row)
+			row = []FormulaNode{}
+		case FormulaTokenCloseArray:
+			array.Rows = append(array.Rows, row)
+			return array, nil
+		default:
+			p.pos--
+			return nil, p.unexpected()
+		}
+	}
+}
+
+func (p *formulaParser) parseArrayConstant() (FormulaNode, error) {
+	if op, ok := p.peekOp("-", "+"); ok {
+		p.pos++
+		tok := p.peek()
+		if tok == nil || tok.Type != FormulaTokenNumber {
+			return nil, p.unexpected()
+		}
+		value, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryOpNode{Op: op, Operand: value}, nil
+	}
+	tok := p.peek()
+	if tok == nil {
+		return nil, p.unexpected()
+	}
+	switch tok.Type {
+	case FormulaTokenNumber, FormulaTokenString, FormulaTokenBool, FormulaTokenError:
+		return p.parsePrimary()
+	}
+	return nil, p.unexpected()
+}
+
+// shiftFormula moves the relative references of a formula in A1
+// notation by the given number of columns and rows, as Excel does when
+// it fills a shared formula into the cells of its range.  Everything
+// other than the references, including whitespace, is kept as it was.
+// References that would leave the sheet become #REF!.
+func shiftFormula(formula string, dx, dy int) string {
+	tokens, err := TokenizeFormula(formula)
+	if err != nil {
+		return formula
+	}
+	var b strings.Builder
+	for _, tok := range tokens {
+		if tok.Type == FormulaTokenReference {
+			if ref, err := ParseReference(tok.Value); err == nil {
+				if shifted, ok := ref.offset(dx, dy); ok {
+					b.WriteString(shifted.String())
+				} else {
+					b.WriteString("#REF!")
+				}
+				continue
+			}
+		}
+		b.WriteString(tok.Value)
+	}
+	return b.String()
+}
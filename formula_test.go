@@ -0,0 +1,243 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestFormula(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("TokenizeFormula", func(c *qt.C) {
+		tokens, err := TokenizeFormula(`SUM('My Sheet'!$A$1:B2, Totals) & " kg" <> [1]Sheet1!C3`)
+		c.Assert(err, qt.IsNil)
+		var types []FormulaTokenType
+		var values []string
+		for _, tok := range tokens {
+			types = append(types, tok.Type)
+			values = append(values, tok.Value)
+		}
+		c.Assert(types, qt.DeepEquals, []FormulaTokenType{
+			FormulaTokenFunction, FormulaTokenOpenParen, FormulaTokenReference,
+			FormulaTokenComma, FormulaTokenWhitespace, FormulaTokenName,
+			FormulaTokenCloseParen, FormulaTokenWhitespace, FormulaTokenOperator,
+			FormulaTokenWhitespace, FormulaTokenString, FormulaTokenWhitespace,
+			FormulaTokenOperator, FormulaTokenWhitespace, FormulaTokenReference,
+		})
+		c.Assert(values, qt.DeepEquals, []string{
+			"SUM", "(", "'My Sheet'!$A$1:B2", ",", " ", "Totals", ")", " ", "&",
+			" ", `" kg"`, " ", "<>", " ", "[1]Sheet1!C3",
+		})
+		c.Assert(tokens[14].Pos, qt.Equals, 43)
+	})
+
+	c.Run("TokenizeDistinguishesRefsFromNamesAndFunctions", func(c *qt.C) {
+		cases := map[string]FormulaTokenType{
+			"A1":             FormulaTokenReference,
+			"$XFD$1048576":   FormulaTokenReference,
+			"A:C":            FormulaTokenReference,
+			"$1:$3":          FormulaTokenReference,
+			"XFE1":           FormulaTokenName,
+			"A1B":            FormulaTokenName,
+			"Tax_Rate":       FormulaTokenName,
+			"Sheet1!Rate":    FormulaTokenName,
+			"TRUE":           FormulaTokenBool,
+			"false":          FormulaTokenBool,
+			"#N/A":           FormulaTokenError,
+			"Sheet1!#REF!":   FormulaTokenReference,
+			"1.5E+3":         FormulaTokenNumber,
+			"Table1[Amount]": FormulaTokenStructuredReference,
+			"[@Amount]":      FormulaTokenStructuredReference,
+		}
+		for formula, expected := range cases {
+			tokens, err := TokenizeFormula(formula)
+			c.Assert(err, qt.IsNil, qt.Commentf(formula))
+			c.Assert(tokens, qt.HasLen, 1, qt.Commentf(formula))
+			c.Assert(tokens[0].Type, qt.Equals, expected, qt.Commentf(formula))
+		}
+		tokens, err := TokenizeFormula("LOG10(100)")
+		c.Assert(err, qt.IsNil)
+		c.Assert(tokens[0].Type, qt.Equals, FormulaTokenFunction)
+	})
+
+	c.Run("TokenizeErrors", func(c *qt.C) {
+		for _, formula := range []string{`"unterminated`, `'Sheet 1`, `'Sheet 1'A1`, "Table1[Amount", "A1~B1"} {
+			_, err := TokenizeFormula(formula)
+			c.Assert(err, qt.Not(qt.IsNil), qt.Commentf(formula))
+		}
+	})
+
+	c.Run("RoundTrip", func(c *qt.C) {
+		formulas := []string{
+			"1+2*3",
+			`IF(A1>=10,"big","small")`,
+			"SUM(Sheet1!A1:A10,'Other Sheet'!$B$2)",
+			"Sheet1:Sheet3!A1",
+			"'[Budget 2020.xlsx]Q1'!$A$1",
+			"[1]Sheet1!A1+[1]!Rate",
+			"-2^2",
+			"50%*A1",
+			"A1:B2 B1:C3",
+			"SUM((A1,C1))",
+			"A1:INDEX(A:A,5)",
+			"{1,2,3;-4,\"x\",TRUE}",
+			"IF(A1,,)",
+			"NOW()",
+			"_xlfn.XLOOKUP(A1,B:B,C:C)",
+			"Table1[[#Headers],[Amount]]+[@Qty]",
+			"SORT(A1#)",
+			"@A1:A10",
+			`"say ""hi"""&B1`,
+			"#REF!+1",
+		}
+		for _, formula := range formulas {
+			node, err := ParseFormula(formula)
+			c.Assert(err, qt.IsNil, qt.Commentf(formula))
+			c.Assert(node.String(), qt.Equals, formula)
+		}
+		node, err := ParseFormula("= SUM( a1 , 'Sheet1'!b2 )")
+		c.Assert(err, qt.IsNil)
+		c.Assert(node.String(), qt.Equals, "SUM(A1,Sheet1!B2)")
+	})
+
+	c.Run("Precedence", func(c *qt.C) {
+		node, err := ParseFormula("1+2*3^2&\"x\"=A1")
+		c.Assert(err, qt.IsNil)
+		eq := node.(*BinaryOpNode)
+		c.Assert(eq.Op, qt.Equals, "=")
+		concat := eq.Left.(*BinaryOpNode)
+		c.Assert(concat.Op, qt.Equals, "&")
+		plus := concat.Left.(*BinaryOpNode)
+		c.Assert(plus.Op, qt.Equals, "+")
+		times := plus.Right.(*BinaryOpNode)
+		c.Assert(times.Op, qt.Equals, "*")
+		c.Assert(times.Right.(*BinaryOpNode).Op, qt.Equals, "^")
+
+		// Negation binds more tightly than exponentiation
+		node, err = ParseFormula("-2^2")
+		c.Assert(err, qt.IsNil)
+		c.Assert(node.(*BinaryOpNode).Left, qt.DeepEquals, FormulaNode(&UnaryOpNode{Op: "-", Operand: &NumberNode{Value: 2, Text: "2"}}))
+
+		// The range operator binds more tightly than intersection
+		node, err = ParseFormula("A1:INDEX(B:B,2) C1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(node.(*BinaryOpNode).Op, qt.Equals, " ")
+		c.Assert(node.(*BinaryOpNode).Left.(*BinaryOpNode).Op, qt.Equals, ":")
+
+		// Within a function a comma separates the arguments
+		node, err = ParseFormula("SUM(A1,B1)")
+		c.Assert(err, qt.IsNil)
+		c.Assert(node.(*FunctionNode).Args, qt.HasLen, 2)
+		node, err = ParseFormula("A1,B1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(node.(*BinaryOpNode).Op, qt.Equals, ",")
+	})
+
+	c.Run("ParseErrors", func(c *qt.C) {
+		for _, formula := range []string{"", "=", "1+", "SUM(1,2", "(1", "1)", "{1,A1}", "{1,2", "1 2"} {
+			_, err := ParseFormula(formula)
+			c.Assert(err, qt.Not(qt.IsNil), qt.Commentf(formula))
+		}
+	})
+
+	c.Run("WalkFormula", func(c *qt.C) {
+		node, err := ParseFormula("SUM(A1,Sheet2!B2:C3)*MAX({1,2},Rate)")
+		c.Assert(err, qt.IsNil)
+		var refs, names []string
+		WalkFormula(node, func(n FormulaNode) bool {
+			switch n := n.(type) {
+			case *ReferenceNode:
+				refs = append(refs, n.String())
+			case *NameNode:
+				names = append(names, n.Name)
+			}
+			return true
+		})
+		c.Assert(refs, qt.DeepEquals, []string{"A1", "Sheet2!B2:C3"})
+		c.Assert(names, qt.DeepEquals, []string{"Rate"})
+
+		// Modifying the tree changes its serialisation
+		WalkFormula(node, func(n FormulaNode) bool {
+			if ref, ok := n.(*ReferenceNode); ok {
+				ref.Ref.Sheet = "Data"
+			}
+			return true
+		})
+		c.Assert(node.String(), qt.Equals, "SUM(Data!A1,Data!B2:C3)*MAX({1,2},Rate)")
+	})
+
+	c.Run("ParseReference", func(c *qt.C) {
+		ref, err := ParseReference("'Bob''s data'!$B2:C$10")
+		c.Assert(err, qt.IsNil)
+		c.Assert(ref, qt.DeepEquals, Reference{
+			Sheet:   "Bob's data",
+			First:   CellRef{Col: 1, Row: 1, ColAbsolute: true},
+			Last:    CellRef{Col: 2, Row: 9, RowAbsolute: true},
+			IsRange: true,
+		})
+		c.Assert(ref.String(), qt.Equals, "'Bob''s data'!$B2:C$10")
+		c.Assert(ref.CellRange(), qt.Equals, CellRange{1, 1, 2, 9})
+
+		ref, err = ParseReference("$2:$4")
+		c.Assert(err, qt.IsNil)
+		c.Assert(ref.First, qt.DeepEquals, CellRef{Row: 1, RowAbsolute: true, NoCol: true})
+		c.Assert(ref.CellRange(), qt.Equals, RowsRange(1, 3))
+		c.Assert(ref.String(), qt.Equals, "$2:$4")
+
+		ref, err = ParseReference("C:C")
+		c.Assert(err, qt.IsNil)
+		c.Assert(ref.CellRange(), qt.Equals, ColsRange(2, 2))
+
+		for _, bad := range []string{"", "A", "1", "A1:2", "A1:B2:C3", "XFE1", "$$1:2"} {
+			_, err = ParseReference(bad)
+			c.Assert(err, qt.Not(qt.IsNil), qt.Commentf(bad))
+		}
+	})
+
+	c.Run("R1C1", func(c *qt.C) {
+		ref, err := ParseReferenceR1C1("R[-1]C2:R5C[3]")
+		c.Assert(err, qt.IsNil)
+		c.Assert(ref.First, qt.DeepEquals, CellRef{Row: -1, Col: 1, ColAbsolute: true})
+		c.Assert(ref.Last, qt.DeepEquals, CellRef{Row: 4, RowAbsolute: true, Col: 3})
+		c.Assert(ref.String(), qt.Equals, "R[-1]C2:R5C[3]")
+
+		ref, err = ParseReferenceR1C1("R")
+		c.Assert(err, qt.IsNil)
+		c.Assert(ref.First, qt.DeepEquals, CellRef{NoCol: true})
+
+		node, err := ParseFormulaR1C1("=SUM(R1C1:R[2]C,Sheet2!C3)+RC[-1]*Rate")
+		c.Assert(err, qt.IsNil)
+		c.Assert(node.String(), qt.Equals, "SUM(R1C1:R[2]C,Sheet2!C3)+RC[-1]*Rate")
+		var refs int
+		WalkFormula(node, func(n FormulaNode) bool {
+			if _, ok := n.(*ReferenceNode); ok {
+				refs++
+			}
+			return true
+		})
+		c.Assert(refs, qt.Equals, 3)
+
+		// ROUND is a function, not a reference to a row
+		node, err = ParseFormulaR1C1("ROUND(R1C1,2)")
+		c.Assert(err, qt.IsNil)
+		c.Assert(node.(*FunctionNode).Name, qt.Equals, "ROUND")
+	})
+
+	c.Run("ShiftFormula", func(c *qt.C) {
+		c.Assert(shiftFormula(`IF( A1 > $B$1, "A1", Sheet2!C3 )`, 1, 2), qt.Equals, `IF( B3 > $B$1, "A1", Sheet2!D5 )`)
+		c.Assert(shiftFormula("SUM(A:A,1:1)", 1, 1), qt.Equals, "SUM(B:B,2:2)")
+		c.Assert(shiftFormula("A1+B1", -1, 0), qt.Equals, "#REF!+A1")
+	})
+
+	c.Run("CellParsedFormula", func(c *qt.C) {
+		cell := &Cell{}
+		node, err := cell.ParsedFormula()
+		c.Assert(err, qt.IsNil)
+		c.Assert(node, qt.IsNil)
+		cell.formula = "A1*2"
+		node, err = cell.ParsedFormula()
+		c.Assert(err, qt.IsNil)
+		c.Assert(node.String(), qt.Equals, "A1*2")
+	})
+}
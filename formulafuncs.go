@@ -0,0 +1,1397 @@
+package xlsx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// formulaFunction describes a worksheet function that the evaluator
+// supports.
+type formulaFunction struct {
+	minArgs int
+	// maxArgs is negative for functions that take any number of
+	// arguments
+	maxArgs int
+	// ranges is set for functions that work with ranges and arrays.
+	// Their arguments are passed as they are, rather than being
+	// reduced to single values.
+	ranges bool
+	call   func(e *evaluator, ctx evalContext, args []evalValue) evalValue
+}
+
+var formulaFunctions map[string]formulaFunction
+
+func init() {
+	formulaFunctions = map[string]formulaFunction{
+		// Maths
+		"ABS":        mathFunction(math.Abs),
+		"INT":        mathFunction(math.Floor),
+		"SQRT":       mathFunction(math.Sqrt),
+		"SIGN":       mathFunction(sign),
+		"PI":         {0, 0, false, fnPi},
+		"MOD":        {2, 2, false, fnMod},
+		"POWER":      {2, 2, false, fnPower},
+		"ROUND":      roundFunction(math.Round),
+		"ROUNDUP":    roundFunction(roundUp),
+		"ROUNDDOWN":  roundFunction(math.Trunc),
+		"SUM":        {1, -1, true, fnSum},
+		"PRODUCT":    {1, -1, true, fnProduct},
+		"AVERAGE":    {1, -1, true, fnAverage},
+		"MIN":        {1, -1, true, fnMin},
+		"MAX":        {1, -1, true, fnMax},
+		"COUNT":      {1, -1, true, fnCount},
+		"COUNTA":     {1, -1, true, fnCountA},
+		"COUNTBLANK": {1, 1, true, fnCountBlank},
+		"SUMPRODUCT": {1, -1, true, fnSumProduct},
+		"SUMIF":      {2, 3, true, fnSumIf},
+		"SUMIFS":     {3, -1, true, fnSumIfs},
+		"COUNTIF":    {2, 2, true, fnCountIfs},
+		"COUNTIFS":   {2, -1, true, fnCountIfs},
+		"AVERAGEIF":  {2, 3, true, fnAverageIf},
+		"AVERAGEIFS": {3, -1, true, fnAverageIfs},
+
+		// Logic
+		"AND":   {1, -1, true, fnAnd},
+		"OR":    {1, -1, true, fnOr},
+		"NOT":   {1, 1, false, fnNot},
+		"TRUE":  {0, 0, false, fnTrue},
+		"FALSE": {0, 0, false, fnFalse},
+
+		// Lookup and reference
+		"VLOOKUP": {3, 4, true, fnVLookup},
+		"HLOOKUP": {3, 4, true, fnHLookup},
+		"MATCH":   {2, 3, true, fnMatch},
+		"INDEX":   {2, 4, true, fnIndex},
+		"ROW":     {0, 1, true, fnRow},
+		"COLUMN":  {0, 1, true, fnColumn},
+		"ROWS":    {1, 1, true, fnRows},
+		"COLUMNS": {1, 1, true, fnColumns},
+
+		// Text
+		"CONCATENATE": {1, -1, false, fnConcatenate},
+		"CONCAT":      {1, -1, true, fnConcat},
+		"LEN":         {1, 1, false, fnLen},
+		"LEFT":        {1, 2, false, fnLeft},
+		"RIGHT":       {1, 2, false, fnRight},
+		"MID":         {3, 3, false, fnMid},
+		"UPPER":       textFunction(strings.ToUpper),
+		"LOWER":       textFunction(strings.ToLower),
+		"TRIM":        textFunction(trimSpaces),
+		"EXACT":       {2, 2, false, fnExact},
+		"FIND":        {2, 3, false, fnFind},
+		"SEARCH":      {2, 3, false, fnSearch},
+		"SUBSTITUTE":  {3, 4, false, fnSubstitute},
+		"REPT":        {2, 2, false, fnRept},
+		"TEXT":        {2, 2, false, fnText},
+		"VALUE":       {1, 1, false, fnValue},
+
+		// Dates
+		"DATE":  {3, 3, false, fnDate},
+		"YEAR":  dateFunction(time.Time.Year),
+		"MONTH": dateFunction(func(t time.Time) int { return int(t.Month()) }),
+		"DAY":   dateFunction(time.Time.Day),
+		"TODAY": {0, 0, false, fnToday},
+		"NOW":   {0, 0, false, fnNow},
+
+		// Information
+		"ISBLANK":   isFunction(func(v evalValue) bool { return v.kind == evalBlank }),
+		"ISNUMBER":  isFunction(func(v evalValue) bool { return v.kind == evalNumber }),
+		"ISTEXT":    isFunction(func(v evalValue) bool { return v.kind == evalString }),
+		"ISLOGICAL": isFunction(func(v evalValue) bool { return v.kind == evalBool }),
+		"ISERROR":   isFunction(func(v evalValue) bool { return v.kind == evalError }),
+		"ISERR":     isFunction(func(v evalValue) bool { return v.kind == evalError && v.str != errNA }),
+		"ISNA":      isFunction(func(v evalValue) bool { return v.kind == evalError && v.str == errNA }),
+		"NA":        {0, 0, false, fnNA},
+	}
+}
+
+// call evaluates a function.  IF, IFERROR, IFNA and CHOOSE only
+// evaluate the arguments that they need, so they are handled here.
+func (e *evaluator) call(n *FunctionNode, ctx evalContext) evalValue {
+	name := strings.ToUpper(n.Name)
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "_XLFN."), "_XLWS.")
+	switch name {
+	case "IF":
+		return e.ifFunction(n.Args, ctx)
+	case "IFERROR", "IFNA":
+		if len(n.Args) != 2 {
+			return errorValue(errValue)
+		}
+		v := e.scalar(e.eval(n.Args[0], ctx), ctx)
+		if v.isError() && (name == "IFERROR" || v.str == errNA) {
+			return e.eval(n.Args[1], ctx)
+		}
+		return v
+	case "CHOOSE":
+		if len(n.Args) < 2 {
+			return errorValue(errValue)
+		}
+		i, code := toInt(e.scalar(e.eval(n.Args[0], ctx), ctx))
+		if code != "" {
+			return errorValue(code)
+		}
+		if i < 1 || i >= len(n.Args) {
+			return errorValue(errValue)
+		}
+		return e.eval(n.Args[i], ctx)
+	}
+
+	fn, ok := formulaFunctions[name]
+	if !ok {
+		return errorValue(errName)
+	}
+	if len(n.Args) < fn.minArgs || (fn.maxArgs >= 0 && len(n.Args) > fn.maxArgs) {
+		return errorValue(errValue)
+	}
+	args := make([]evalValue, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = e.eval(arg, ctx)
+		if !fn.ranges {
+			args[i] = e.scalar(args[i], ctx)
+		}
+	}
+	return fn.call(e, ctx, args)
+}
+
+func (e *evaluator) ifFunction(args []FormulaNode, ctx evalContext) evalValue {
+	if len(args) < 2 || len(args) > 3 {
+		return errorValue(errValue)
+	}
+	cond, code := toBool(e.scalar(e.eval(args[0], ctx), ctx))
+	if code != "" {
+		return errorValue(code)
+	}
+	if cond {
+		return e.eval(args[1], ctx)
+	}
+	if len(args) < 3 {
+		return boolValue(false)
+	}
+	return e.eval(args[2], ctx)
+}
+
+// toInt converts a scalar value to a whole number, truncating any
+// fraction.
+func toInt(v evalValue) (int, string) {
+	n, code := toNumber(v)
+	return int(n), code
+}
+
+// intArg returns an optional argument as a whole number, or def if the
+// argument was omitted.
+func intArg(args []evalValue, i, def int) (int, string) {
+	if i >= len(args) {
+		return def, ""
+	}
+	return toInt(args[i])
+}
+
+func mathFunction(fn func(float64) float64) formulaFunction {
+	return formulaFunction{1, 1, false, func(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+		x, code := toNumber(args[0])
+		if code != "" {
+			return errorValue(code)
+		}
+		return numberValue(fn(x))
+	}}
+}
+
+func sign(x float64) float64 {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
+
+func fnPi(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return numberValue(math.Pi)
+}
+
+func fnMod(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	x, code := toNumber(args[0])
+	if code != "" {
+		return errorValue(code)
+	}
+	y, code := toNumber(args[1])
+	if code != "" {
+		return errorValue(code)
+	}
+	if y == 0 {
+		return errorValue(errDiv0)
+	}
+	// The result has the sign of the divisor
+	return numberValue(x - y*math.Floor(x/y))
+}
+
+func fnPower(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return binaryOp("^", args[0], args[1])
+}
+
+// roundFunction makes ROUND and its variants, which round a number to
+// a number of decimal places, or to tens, hundreds and so on when
+// negative.
+func roundFunction(round func(float64) float64) formulaFunction {
+	return formulaFunction{1, 2, false, func(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+		x, code := toNumber(args[0])
+		if code != "" {
+			return errorValue(code)
+		}
+		digits, code := intArg(args, 1, 0)
+		if code != "" {
+			return errorValue(code)
+		}
+		p := math.Pow(10, math.Abs(float64(digits)))
+		if digits < 0 {
+			return numberValue(round(cleanFloat(x/p)) * p)
+		}
+		return numberValue(round(cleanFloat(x*p)) / p)
+	}}
+}
+
+// cleanFloat drops the noise beyond 15 significant digits left by
+// binary floating point, so that e.g. 2.675*100 rounds to 268, as it
+// does in Excel.
+func cleanFloat(x float64) float64 {
+	y, _ := strconv.ParseFloat(strconv.FormatFloat(x, 'g', 15, 64), 64)
+	return y
+}
+
+func roundUp(x float64) float64 {
+	if x < 0 {
+		return -math.Ceil(-x)
+	}
+	return math.Ceil(x)
+}
+
+// collectNumbers gathers the numbers among the arguments of functions
+// such as SUM.  Values given directly are converted to numbers, but
+// within ranges and arrays anything other than a number is ignored.
+func (e *evaluator) collectNumbers(args []evalValue) ([]float64, string) {
+	var nums []float64
+	for _, arg := range args {
+		if arg.kind == evalReference || arg.kind == evalArray {
+			code := ""
+			e.forEachValue(arg, func(v evalValue) bool {
+				switch v.kind {
+				case evalNumber:
+					nums = append(nums, v.num)
+				case evalError:
+					code = v.str
+					return false
+				}
+				return true
+			})
+			if code != "" {
+				return nil, code
+			}
+			continue
+		}
+		n, code := toNumber(arg)
+		if code != "" {
+			return nil, code
+		}
+		nums = append(nums, n)
+	}
+	return nums, ""
+}
+
+// aggregateFunction applies fn to the numbers collected from the
+// arguments.
+func aggregateFunction(args []evalValue, e *evaluator, fn func(nums []float64) evalValue) evalValue {
+	nums, code := e.collectNumbers(args)
+	if code != "" {
+		return errorValue(code)
+	}
+	return fn(nums)
+}
+
+func fnSum(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return aggregateFunction(args, e, func(nums []float64) evalValue {
+		var total float64
+		for _, n := range nums {
+			total += n
+		}
+		return numberValue(total)
+	})
+}
+
+func fnProduct(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return aggregateFunction(args, e, func(nums []float64) evalValue {
+		if len(nums) == 0 {
+			return numberValue(0)
+		}
+		product := 1.0
+		for _, n := range nums {
+			product *= n
+		}
+		return numberValue(product)
+	})
+}
+
+func fnAverage(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return aggregateFunction(args, e, func(nums []float64) evalValue {
+		if len(nums) == 0 {
+			return errorValue(errDiv0)
+		}
+		var total float64
+		for _, n := range nums {
+			total += n
+		}
+		return numberValue(total / float64(len(nums)))
+	})
+}
+
+func fnMin(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return aggregateFunction(args, e, func(nums []float64) evalValue {
+		if len(nums) == 0 {
+			return numberValue(0)
+		}
+		min := nums[0]
+		for _, n := range nums[1:] {
+			min = math.Min(min, n)
+		}
+		return numberValue(min)
+	})
+}
+
+func fnMax(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return aggregateFunction(args, e, func(nums []float64) evalValue {
+		if len(nums) == 0 {
+			return numberValue(0)
+		}
+		max := nums[0]
+		for _, n := range nums[1:] {
+			max = math.Max(max, n)
+		}
+		return numberValue(max)
+	})
+}
+
+func fnCount(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	count := 0
+	for _, arg := range args {
+		if arg.kind == evalReference || arg.kind == evalArray {
+			e.forEachValue(arg, func(v evalValue) bool {
+				if v.kind == evalNumber {
+					count++
+				}
+				return true
+			})
+			continue
+		}
+		if _, code := toNumber(arg); code == "" && arg.kind != evalBlank {
+			count++
+		}
+	}
+	return numberValue(float64(count))
+}
+
+func fnCountA(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	count := 0
+	for _, arg := range args {
+		e.forEachValue(arg, func(v evalValue) bool {
+			if v.kind != evalBlank {
+				count++
+			}
+			return true
+		})
+	}
+	return numberValue(float64(count))
+}
+
+func fnCountBlank(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	g := e.grid(args[0])
+	if g == nil || args[0].kind != evalReference {
+		return errorValue(errValue)
+	}
+	filled := 0
+	e.forEachValue(args[0], func(v evalValue) bool {
+		if v.kind != evalBlank && !(v.kind == evalString && v.str == "") {
+			filled++
+		}
+		return true
+	})
+	return numberValue(float64(g.rows*g.cols - filled))
+}
+
+func fnSumProduct(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	var grids []*evalGrid
+	usedRows, usedCols := 0, 0
+	for _, arg := range args {
+		g := e.grid(arg)
+		if g == nil || (len(grids) > 0 && (g.rows != grids[0].rows || g.cols != grids[0].cols)) {
+			return errorValue(errValue)
+		}
+		grids = append(grids, g)
+		usedRows, usedCols = maxInt(usedRows, g.usedRows), maxInt(usedCols, g.usedCols)
+	}
+	var total float64
+	for row := 0; row < usedRows; row++ {
+		for col := 0; col < usedCols; col++ {
+			product := 1.0
+			for _, g := range grids {
+				v := g.get(row, col)
+				switch v.kind {
+				case evalError:
+					return v
+				case evalNumber:
+					product *= v.num
+				default:
+					product = 0
+				}
+			}
+			total += product
+		}
+	}
+	return numberValue(total)
+}
+
+// criterion is a condition such as ">=10" or "a*", as used by SUMIF
+// and the like.
+type criterion struct {
+	op    string
+	value evalValue
+}
+
+func parseCriterion(v evalValue) criterion {
+	switch v.kind {
+	case evalString:
+	case evalBlank:
+		return criterion{op: "=", value: numberValue(0)}
+	default:
+		return criterion{op: "=", value: v}
+	}
+	s, op := v.str, "="
+	for _, prefix := range []string{"<=", ">=", "<>", "<", ">", "="} {
+		if strings.HasPrefix(s, prefix) {
+			s, op = s[len(prefix):], prefix
+			break
+		}
+	}
+	c := criterion{op: op, value: stringValue(s)}
+	if n, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+		c.value = numberValue(n)
+	} else if b, code := toBool(c.value); code == "" {
+		c.value = boolValue(b)
+	}
+	return c
+}
+
+func (c criterion) matches(v evalValue) bool {
+	switch c.value.kind {
+	case evalString:
+		if c.value.str == "" {
+			empty := v.kind == evalBlank || (v.kind == evalString && v.str == "")
+			switch c.op {
+			case "=":
+				return empty
+			case "<>":
+				return !empty
+			}
+			return false
+		}
+		if v.kind != evalString {
+			return c.op == "<>"
+		}
+		if c.op == "=" || c.op == "<>" {
+			return matchWildcard(strings.ToLower(c.value.str), strings.ToLower(v.str)) == (c.op == "=")
+		}
+		return compareOp(c.op, compareValues(v, c.value))
+	case evalNumber:
+		if v.kind == evalString {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(v.str), 64); err == nil {
+				v = numberValue(n)
+			}
+		}
+		if v.kind != evalNumber {
+			return c.op == "<>"
+		}
+		return compareOp(c.op, compareValues(v, c.value))
+	case evalBool, evalError:
+		if v.kind != c.value.kind {
+			return c.op == "<>"
+		}
+		if v.kind == evalError {
+			return (v.str == c.value.str) == (c.op == "=")
+		}
+		return compareOp(c.op, compareValues(v, c.value))
+	}
+	return false
+}
+
+// matchWildcard matches a string against a pattern in which "*" stands
+// for any run of characters, "?" for any single character, and "~"
+// escapes the character after it.
+func matchWildcard(pattern, s string) bool {
+	p, t := []rune(pattern), []rune(s)
+	var match func(i, j int) bool
+	match = func(i, j int) bool {
+		for i < len(p) {
+			switch p[i] {
+			case '*':
+				for k := j; k <= len(t); k++ {
+					if match(i+1, k) {
+						return true
+					}
+				}
+				return false
+			case '?':
+				if j >= len(t) {
+					return false
+				}
+				i++
+				j++
+				continue
+			case '~':
+				if i+1 < len(p) {
+					i++
+				}
+			}
+			if j >= len(t) || p[i] != t[j] {
+				return false
+			}
+			i++
+			j++
+		}
+		return j == len(t)
+	}
+	return match(0, 0)
+}
+
+// criteriaSet is the set of ranges and criteria given to functions
+// such as SUMIFS.  All the ranges are the same size.
+type criteriaSet struct {
+	grids              []*evalGrid
+	criteria           []criterion
+	rows, cols         int
+	usedRows, usedCols int
+}
+
+// newCriteriaSet builds a criteriaSet from arguments that alternate
+// between a range and its criterion.
+func (e *evaluator) newCriteriaSet(args []evalValue, ctx evalContext) (*criteriaSet, string) {
+	if len(args) == 0 || len(args)%2 != 0 {
+		return nil, errValue
+	}
+	cs := &criteriaSet{}
+	for i := 0; i < len(args); i += 2 {
+		g := e.grid(args[i])
+		if g == nil || (i > 0 && (g.rows != cs.rows || g.cols != cs.cols)) {
+			return nil, errValue
+		}
+		crit := e.scalar(args[i+1], ctx)
+		if crit.isError() {
+			return nil, crit.str
+		}
+		cs.grids = append(cs.grids, g)
+		cs.criteria = append(cs.criteria, parseCriterion(crit))
+		cs.rows, cs.cols = g.rows, g.cols
+		cs.usedRows, cs.usedCols = maxInt(cs.usedRows, g.usedRows), maxInt(cs.usedCols, g.usedCols)
+	}
+	return cs, ""
+}
+
+// each calls fn with the position of every cell, within the used part
+// of the ranges, at which all the criteria are met.
+func (cs *criteriaSet) each(fn func(row, col int)) {
+	for row := 0; row < cs.usedRows; row++ {
+		for col := 0; col < cs.usedCols; col++ {
+			matched := true
+			for i, g := range cs.grids {
+				if !cs.criteria[i].matches(g.get(row, col)) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				fn(row, col)
+			}
+		}
+	}
+}
+
+// matchesBlank reports whether empty cells meet all of the criteria.
+func (cs *criteriaSet) matchesBlank() bool {
+	for _, c := range cs.criteria {
+		if !c.matches(blankValue) {
+			return false
+		}
+	}
+	return true
+}
+
+// sizedGrid returns the grid of the values that a SUMIF-style function
+// is to add up.  As in Excel, a range is resized from its top left cell
+// to match the size of the criteria ranges.
+func (e *evaluator) sizedGrid(v evalValue, rows, cols int) *evalGrid {
+	if v.kind == evalReference && len(v.ref.areas) == 1 {
+		a := v.ref.areas[0]
+		if a.MinCol+cols-1 > Excel2006MaxColIndex || a.MinRow+rows-1 > Excel2006MaxRowIndex {
+			return nil
+		}
+		return e.areaGrid(v.ref.sheet, CellRange{a.MinCol, a.MinRow, a.MinCol + cols - 1, a.MinRow + rows - 1})
+	}
+	g := e.grid(v)
+	if g == nil || g.rows != rows || g.cols != cols {
+		return nil
+	}
+	return g
+}
+
+// sumIfs adds up, and counts, the numbers in values at which the
+// criteria are met.
+func (e *evaluator) sumIfs(values evalValue, criteria []evalValue, ctx evalContext) (float64, int, string) {
+	cs, code := e.newCriteriaSet(criteria, ctx)
+	if code != "" {
+		return 0, 0, code
+	}
+	g := e.sizedGrid(values, cs.rows, cs.cols)
+	if g == nil {
+		return 0, 0, errValue
+	}
+	var total float64
+	count := 0
+	cs.each(func(row, col int) {
+		v := g.get(row, col)
+		switch v.kind {
+		case evalNumber:
+			total += v.num
+			count++
+		case evalError:
+			if code == "" {
+				code = v.str
+			}
+		}
+	})
+	return total, count, code
+}
+
+func fnSumIf(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	values := args[0]
+	if len(args) > 2 {
+		values = args[2]
+	}
+	total, _, code := e.sumIfs(values, args[:2], ctx)
+	if code != "" {
+		return errorValue(code)
+	}
+	return numberValue(total)
+}
+
+func fnSumIfs(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	total, _, code := e.sumIfs(args[0], args[1:], ctx)
+	if code != "" {
+		return errorValue(code)
+	}
+	return numberValue(total)
+}
+
+func fnAverageIf(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	values := args[0]
+	if len(args) > 2 {
+		values = args[2]
+	}
+	return averageIfs(e.sumIfs(values, args[:2], ctx))
+}
+
+func fnAverageIfs(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return averageIfs(e.sumIfs(args[0], args[1:], ctx))
+}
+
+func averageIfs(total float64, count int, code string) evalValue {
+	if code != "" {
+		return errorValue(code)
+	}
+	if count == 0 {
+		return errorValue(errDiv0)
+	}
+	return numberValue(total / float64(count))
+}
+
+func fnCountIfs(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	cs, code := e.newCriteriaSet(args, ctx)
+	if code != "" {
+		return errorValue(code)
+	}
+	count := 0
+	cs.each(func(row, col int) {
+		count++
+	})
+	if cs.matchesBlank() {
+		// Count the empty cells beyond the used part of the ranges
+		count += cs.rows*cs.cols - cs.usedRows*cs.usedCols
+	}
+	return numberValue(float64(count))
+}
+
+// logicalFunction makes AND and OR, which combine the booleans among
+// their arguments, ignoring any text within ranges.
+func logicalFunction(args []evalValue, e *evaluator, combine func(acc, b bool) bool, init bool) evalValue {
+	result, seen, code := init, false, ""
+	for _, arg := range args {
+		if arg.kind == evalReference || arg.kind == evalArray {
+			e.forEachValue(arg, func(v evalValue) bool {
+				switch v.kind {
+				case evalNumber, evalBool:
+					b, _ := toBool(v)
+					result, seen = combine(result, b), true
+				case evalError:
+					code = v.str
+					return false
+				}
+				return true
+			})
+			if code != "" {
+				return errorValue(code)
+			}
+			continue
+		}
+		b, code := toBool(arg)
+		if code != "" {
+			return errorValue(code)
+		}
+		result, seen = combine(result, b), true
+	}
+	if !seen {
+		return errorValue(errValue)
+	}
+	return boolValue(result)
+}
+
+func fnAnd(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return logicalFunction(args, e, func(acc, b bool) bool { return acc && b }, true)
+}
+
+func fnOr(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return logicalFunction(args, e, func(acc, b bool) bool { return acc || b }, false)
+}
+
+func fnNot(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	b, code := toBool(args[0])
+	if code != "" {
+		return errorValue(code)
+	}
+	return boolValue(!b)
+}
+
+func fnTrue(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return boolValue(true)
+}
+
+func fnFalse(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return boolValue(false)
+}
+
+// lookupMatches reports whether a value is an exact match for the
+// value being looked up.  Strings are compared ignoring case, and may
+// contain wildcards.
+func lookupMatches(lookup, v evalValue) bool {
+	if lookup.kind != v.kind {
+		return false
+	}
+	if lookup.kind == evalString {
+		return matchWildcard(strings.ToLower(lookup.str), strings.ToLower(v.str))
+	}
+	return compareValues(lookup, v) == 0
+}
+
+// lookupIndex finds a value within n values, returning its index or -1
+// if it isn't found.  As in MATCH, matchType 0 requires an exact match,
+// 1 finds the largest value that is less than or equal to the lookup
+// value in ascending values, and -1 finds the smallest value that is
+// greater than or equal to it in descending values.
+func lookupIndex(lookup evalValue, n int, get func(i int) evalValue, matchType int) int {
+	found := -1
+	for i := 0; i < n; i++ {
+		v := get(i)
+		if matchType == 0 {
+			if lookupMatches(lookup, v) {
+				return i
+			}
+			continue
+		}
+		if v.kind != lookup.kind {
+			continue
+		}
+		cmp := compareValues(v, lookup) * matchType
+		if cmp > 0 {
+			break
+		}
+		found = i
+		if cmp == 0 && matchType < 0 {
+			break
+		}
+	}
+	return found
+}
+
+// tableLookup implements VLOOKUP, and HLOOKUP when horizontal is set.
+func (e *evaluator) tableLookup(args []evalValue, ctx evalContext, horizontal bool) evalValue {
+	lookup := e.scalar(args[0], ctx)
+	if lookup.isError() {
+		return lookup
+	}
+	if lookup.kind == evalBlank {
+		return errorValue(errNA)
+	}
+	g := e.grid(args[1])
+	if g == nil {
+		return errorValue(errValue)
+	}
+	index, code := toInt(e.scalar(args[2], ctx))
+	if code != "" {
+		return errorValue(code)
+	}
+	approximate := true
+	if len(args) > 3 {
+		if approximate, code = toBool(e.scalar(args[3], ctx)); code != "" {
+			return errorValue(code)
+		}
+	}
+	matchType := 0
+	if approximate {
+		matchType = 1
+	}
+
+	size, used, width := g.rows, g.usedRows, g.cols
+	get := func(i, j int) evalValue { return g.get(i, j) }
+	if horizontal {
+		size, used, width = g.cols, g.usedCols, g.rows
+		get = func(i, j int) evalValue { return g.get(j, i) }
+	}
+	if index < 1 {
+		return errorValue(errValue)
+	}
+	if index > width {
+		return errorValue(errRef)
+	}
+	i := lookupIndex(lookup, minInt(size, used), func(i int) evalValue { return get(i, 0) }, matchType)
+	if i < 0 {
+		return errorValue(errNA)
+	}
+	return get(i, index-1)
+}
+
+func fnVLookup(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return e.tableLookup(args, ctx, false)
+}
+
+func fnHLookup(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return e.tableLookup(args, ctx, true)
+}
+
+func fnMatch(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	lookup := e.scalar(args[0], ctx)
+	if lookup.isError() {
+		return lookup
+	}
+	if lookup.kind == evalBlank {
+		return errorValue(errNA)
+	}
+	g := e.grid(args[1])
+	if g == nil || (g.rows != 1 && g.cols != 1) {
+		return errorValue(errNA)
+	}
+	matchType := 1
+	if len(args) > 2 {
+		n, code := toNumber(e.scalar(args[2], ctx))
+		if code != "" {
+			return errorValue(code)
+		}
+		matchType = int(sign(n))
+	}
+	var i int
+	if g.cols == 1 {
+		i = lookupIndex(lookup, g.usedRows, func(i int) evalValue { return g.get(i, 0) }, matchType)
+	} else {
+		i = lookupIndex(lookup, g.usedCols, func(i int) evalValue { return g.get(0, i) }, matchType)
+	}
+	if i < 0 {
+		return errorValue(errNA)
+	}
+	return numberValue(float64(i + 1))
+}
+
+// fnIndex implements INDEX.  Given a range it returns a reference, so
+// that it can be used within a range, as in A1:INDEX(A:A,5).  A row or
+// column number of 0 selects the whole column or row.
+func fnIndex(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	nums := make([]int, 3)
+	for i := 1; i < len(args); i++ {
+		var code string
+		nums[i-1], code = toInt(e.scalar(args[i], ctx))
+		if code != "" {
+			return errorValue(code)
+		}
+	}
+	rowNum, colNum, areaNum := nums[0], nums[1], nums[2]
+	if len(args) < 4 {
+		areaNum = 1
+	}
+
+	v := args[0]
+	if v.kind == evalReference {
+		if areaNum < 1 || areaNum > len(v.ref.areas) {
+			return errorValue(errRef)
+		}
+		a := v.ref.areas[areaNum-1]
+		if len(args) == 2 && a.Height() == 1 {
+			rowNum, colNum = 1, rowNum
+		}
+		if rowNum < 0 || colNum < 0 || rowNum > a.Height() || colNum > a.Width() {
+			return errorValue(errRef)
+		}
+		if rowNum > 0 {
+			a.MinRow += rowNum - 1
+			a.MaxRow = a.MinRow
+		}
+		if colNum > 0 {
+			a.MinCol += colNum - 1
+			a.MaxCol = a.MinCol
+		}
+		return refValue(v.ref.sheet, a)
+	}
+
+	g := e.grid(v)
+	if len(args) == 2 && g.rows == 1 {
+		rowNum, colNum = 1, rowNum
+	}
+	if rowNum < 0 || colNum < 0 || rowNum > g.rows || colNum > g.cols || areaNum != 1 {
+		return errorValue(errRef)
+	}
+	var arr [][]evalValue
+	for row := 0; row < g.rows; row++ {
+		if rowNum > 0 && row != rowNum-1 {
+			continue
+		}
+		var values []evalValue
+		for col := 0; col < g.cols; col++ {
+			if colNum == 0 || col == colNum-1 {
+				values = append(values, g.get(row, col))
+			}
+		}
+		arr = append(arr, values)
+	}
+	if len(arr) == 1 && len(arr[0]) == 1 {
+		return arr[0][0]
+	}
+	return evalValue{kind: evalArray, arr: arr}
+}
+
+// firstArea returns the first range referred to by the optional
+// argument of ROW and COLUMN, or the formula's own cell if there is no
+// argument.
+func firstArea(args []evalValue, ctx evalContext) (CellRange, bool) {
+	if len(args) == 0 {
+		return CellRange{ctx.col, ctx.row, ctx.col, ctx.row}, true
+	}
+	if args[0].kind != evalReference {
+		return CellRange{}, false
+	}
+	return args[0].ref.areas[0], true
+}
+
+func fnRow(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	a, ok := firstArea(args, ctx)
+	if !ok {
+		return errorValue(errValue)
+	}
+	return numberValue(float64(a.MinRow + 1))
+}
+
+func fnColumn(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	a, ok := firstArea(args, ctx)
+	if !ok {
+		return errorValue(errValue)
+	}
+	return numberValue(float64(a.MinCol + 1))
+}
+
+func fnRows(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	g := e.grid(args[0])
+	if g == nil {
+		return errorValue(errRef)
+	}
+	return numberValue(float64(g.rows))
+}
+
+func fnColumns(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	g := e.grid(args[0])
+	if g == nil {
+		return errorValue(errRef)
+	}
+	return numberValue(float64(g.cols))
+}
+
+// textArgs converts the arguments to strings, returning the first
+// error.
+func textArgs(args []evalValue) ([]string, string) {
+	texts := make([]string, len(args))
+	for i, arg := range args {
+		s, code := toText(arg)
+		if code != "" {
+			return nil, code
+		}
+		texts[i] = s
+	}
+	return texts, ""
+}
+
+func textFunction(fn func(string) string) formulaFunction {
+	return formulaFunction{1, 1, false, func(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+		s, code := toText(args[0])
+		if code != "" {
+			return errorValue(code)
+		}
+		return stringValue(fn(s))
+	}}
+}
+
+// trimSpaces removes leading and trailing spaces, and collapses runs
+// of spaces within the text, as TRIM does.
+func trimSpaces(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool { return r == ' ' }), " ")
+}
+
+func fnConcatenate(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	texts, code := textArgs(args)
+	if code != "" {
+		return errorValue(code)
+	}
+	return stringValue(strings.Join(texts, ""))
+}
+
+func fnConcat(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	var b strings.Builder
+	code := ""
+	for _, arg := range args {
+		e.forEachValue(arg, func(v evalValue) bool {
+			var s string
+			s, code = toText(v)
+			b.WriteString(s)
+			return code == ""
+		})
+		if code != "" {
+			return errorValue(code)
+		}
+	}
+	return stringValue(b.String())
+}
+
+func fnLen(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	s, code := toText(args[0])
+	if code != "" {
+		return errorValue(code)
+	}
+	return numberValue(float64(utf8.RuneCountInString(s)))
+}
+
+// substring returns n characters of s from the zero based start,
+// limited to the length of s.
+func substring(s string, start, n int) string {
+	runes := []rune(s)
+	if start > len(runes) {
+		return ""
+	}
+	end := start + n
+	if end > len(runes) {
+		end = len(runes)
+	}
+	return string(runes[start:end])
+}
+
+func fnLeft(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	s, code := toText(args[0])
+	if code != "" {
+		return errorValue(code)
+	}
+	n, code := intArg(args, 1, 1)
+	if code != "" {
+		return errorValue(code)
+	}
+	if n < 0 {
+		return errorValue(errValue)
+	}
+	return stringValue(substring(s, 0, n))
+}
+
+func fnRight(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	s, code := toText(args[0])
+	if code != "" {
+		return errorValue(code)
+	}
+	n, code := intArg(args, 1, 1)
+	if code != "" {
+		return errorValue(code)
+	}
+	if n < 0 {
+		return errorValue(errValue)
+	}
+	length := utf8.RuneCountInString(s)
+	return stringValue(substring(s, maxInt(length-n, 0), n))
+}
+
+func fnMid(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	s, code := toText(args[0])
+	if code != "" {
+		return errorValue(code)
+	}
+	start, code := toInt(args[1])
+	if code != "" {
+		return errorValue(code)
+	}
+	n, code := toInt(args[2])
+	if code != "" {
+		return errorValue(code)
+	}
+	if start < 1 || n < 0 {
+		return errorValue(errValue)
+	}
+	return stringValue(substring(s, start-1, n))
+}
+
+func fnExact(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	texts, code := textArgs(args)
+	if code != "" {
+		return errorValue(code)
+	}
+	return boolValue(texts[0] == texts[1])
+}
+
+// findText implements FIND, and SEARCH when ignoreCase is set.  The
+// result is the one based position of the first occurrence of the text
+// at or after the optional start position.
+func findText(args []evalValue, ignoreCase bool) evalValue {
+	texts, code := textArgs(args[:2])
+	if code != "" {
+		return errorValue(code)
+	}
+	start, code := intArg(args, 2, 1)
+	if code != "" {
+		return errorValue(code)
+	}
+	find, within := []rune(texts[0]), []rune(texts[1])
+	if start < 1 || start > len(within)+1 {
+		return errorValue(errValue)
+	}
+	if ignoreCase {
+		find = []rune(strings.ToLower(texts[0]))
+		within = []rune(strings.ToLower(texts[1]))
+	}
+	i := strings.Index(string(within[start-1:]), string(find))
+	if i < 0 {
+		return errorValue(errValue)
+	}
+	return numberValue(float64(start + utf8.RuneCountInString(string(within[start-1:])[:i])))
+}
+
+func fnFind(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return findText(args, false)
+}
+
+func fnSearch(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return findText(args, true)
+}
+
+func fnSubstitute(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	texts, code := textArgs(args[:3])
+	if code != "" {
+		return errorValue(code)
+	}
+	s, old, replacement := texts[0], texts[1], texts[2]
+	if len(args) < 4 {
+		return stringValue(strings.ReplaceAll(s, old, replacement))
+	}
+	instance, code := toInt(args[3])
+	if code != "" {
+		return errorValue(code)
+	}
+	if instance < 1 {
+		return errorValue(errValue)
+	}
+	if old == "" {
+		return stringValue(s)
+	}
+	offset := 0
+	for n := 1; ; n++ {
+		i := strings.Index(s[offset:], old)
+		if i < 0 {
+			return stringValue(s)
+		}
+		if n == instance {
+			i += offset
+			return stringValue(s[:i] + replacement + s[i+len(old):])
+		}
+		offset += i + len(old)
+	}
+}
+
+func fnRept(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	s, code := toText(args[0])
+	if code != "" {
+		return errorValue(code)
+	}
+	n, code := toInt(args[1])
+	if code != "" {
+		return errorValue(code)
+	}
+	if n < 0 || n*len(s) > 32767 {
+		return errorValue(errValue)
+	}
+	return stringValue(strings.Repeat(s, n))
+}
+
+// fnText formats a value with a number format, e.g. TEXT(A1,"0.00"),
+// in the same way as Cell.FormattedValue.
+func fnText(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	format, code := toText(args[1])
+	if code != "" {
+		return errorValue(code)
+	}
+	cell := &Cell{NumFmt: format, date1904: e.file.Date1904, cellType: CellTypeNumeric}
+	n, code := toNumber(args[0])
+	switch {
+	case args[0].isError():
+		return args[0]
+	case code == "":
+		cell.Value = strconv.FormatFloat(n, 'f', -1, 64)
+	default:
+		cell.Value = args[0].str
+		cell.cellType = CellTypeString
+	}
+	s, err := cell.FormattedValue()
+	if err != nil {
+		return errorValue(errValue)
+	}
+	if cell.cellType == CellTypeNumeric && groupsThousands(format, n) {
+		s = groupThousands(s)
+	}
+	return stringValue(s)
+}
+
+// groupsThousands returns true if the section of the number format
+// that applies to n separates the thousands with commas, which
+// Cell.FormattedValue leaves out.
+func groupsThousands(format string, n float64) bool {
+	parsed := parseFullNumberFormatString(format)
+	if parsed.isTimeFormat {
+		return false
+	}
+	section := parsed.zeroFormat
+	switch {
+	case n > 0:
+		section = parsed.positiveFormat
+	case n < 0:
+		section = parsed.negativeFormat
+	}
+	return strings.HasPrefix(section.reducedFormatString, "#,##0")
+}
+
+// groupThousands puts commas between the thousands of the first whole
+// number in s.
+func groupThousands(s string) string {
+	start := strings.IndexAny(s, "0123456789")
+	if start < 0 {
+		return s
+	}
+	end := start
+	for end < len(s) && isDigit(s[end]) {
+		end++
+	}
+	digits := s[start:end]
+	var b strings.Builder
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte(digits[i])
+	}
+	return s[:start] + b.String() + s[end:]
+}
+
+func fnValue(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	if args[0].kind == evalBool {
+		return errorValue(errValue)
+	}
+	n, code := toNumber(args[0])
+	if code != "" {
+		return errorValue(code)
+	}
+	return numberValue(n)
+}
+
+// dateSerial converts a time to the number that Excel uses for it in
+// this File's date system.
+func (e *evaluator) dateSerial(t time.Time) float64 {
+	n := TimeToExcelTime(t, e.file.Date1904)
+	if !e.file.Date1904 && n < 61 {
+		// Excel counts a 29th of February 1900 that never happened
+		n--
+	}
+	return n
+}
+
+func fnDate(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	var parts [3]int
+	for i, arg := range args {
+		var code string
+		if parts[i], code = toInt(arg); code != "" {
+			return errorValue(code)
+		}
+	}
+	year, month, day := parts[0], parts[1], parts[2]
+	if year < 0 || year > 9999 {
+		return errorValue(errNum)
+	}
+	if year < 1900 {
+		year += 1900
+	}
+	n := e.dateSerial(time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC))
+	if n < 0 {
+		return errorValue(errNum)
+	}
+	return numberValue(n)
+}
+
+// dateFunction makes YEAR, MONTH and DAY, which take a part of a date.
+func dateFunction(part func(time.Time) int) formulaFunction {
+	return formulaFunction{1, 1, false, func(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+		n, code := toNumber(args[0])
+		if code != "" {
+			return errorValue(code)
+		}
+		if n < 0 {
+			return errorValue(errNum)
+		}
+		return numberValue(float64(part(TimeFromExcelTime(n, e.file.Date1904))))
+	}}
+}
+
+func fnToday(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	now := time.Now()
+	return numberValue(e.dateSerial(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)))
+}
+
+func fnNow(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	now := time.Now()
+	_, offset := now.Zone()
+	return numberValue(e.dateSerial(now.UTC().Add(time.Duration(offset) * time.Second)))
+}
+
+// isFunction makes one of the IS functions, which test the kind of a
+// value.
+func isFunction(test func(evalValue) bool) formulaFunction {
+	return formulaFunction{1, 1, false, func(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+		return boolValue(test(args[0]))
+	}}
+}
+
+func fnNA(e *evaluator, ctx evalContext, args []evalValue) evalValue {
+	return errorValue(errNA)
+}
@@ -0,0 +1,495 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HeaderFooterCode identifies one of the control codes that Excel
+// understands inside a page header or footer.
+type HeaderFooterCode int
+
+// Header and footer control codes
+const (
+	// HeaderFooterLiteral is plain text, held in the Value of the item.
+	HeaderFooterLiteral HeaderFooterCode = iota
+	HeaderFooterPageNumber
+	HeaderFooterTotalPages
+	HeaderFooterDate
+	HeaderFooterTime
+	HeaderFooterFilePath
+	HeaderFooterFileName
+	HeaderFooterSheetName
+	// HeaderFooterPicture only places the "&G" code.  Writing the
+	// picture itself (a legacy VML drawing) is not supported, so
+	// Excel will show an empty space unless the drawing is added by
+	// other means.
+	HeaderFooterPicture
+	// HeaderFooterFont takes a Value of the form "name,style",
+	// e.g. "Arial,Bold".  Use "-" as the name to keep the current
+	// font and only change the style.
+	HeaderFooterFont
+	// HeaderFooterFontSize takes the size in points as its Value.
+	HeaderFooterFontSize
+	// HeaderFooterColor takes a Value of six characters, either an
+	// RGB hex colour (e.g. "FF0000") or a theme colour with a tint
+	// (e.g. "01+000").
+	HeaderFooterColor
+	HeaderFooterBold
+	HeaderFooterItalic
+	HeaderFooterUnderline
+	HeaderFooterDoubleUnderline
+	HeaderFooterStrikethrough
+	HeaderFooterSuperscript
+	HeaderFooterSubscript
+	HeaderFooterOutline
+	HeaderFooterShadow
+)
+
+// headerFooterSimpleCodes maps the control codes that take no
+// argument onto the letter that follows the ampersand.
+var headerFooterSimpleCodes = map[HeaderFooterCode]byte{
+	HeaderFooterPageNumber:      'P',
+	HeaderFooterTotalPages:      'N',
+	HeaderFooterDate:            'D',
+	HeaderFooterTime:            'T',
+	HeaderFooterFilePath:        'Z',
+	HeaderFooterFileName:        'F',
+	HeaderFooterSheetName:       'A',
+	HeaderFooterPicture:         'G',
+	HeaderFooterBold:            'B',
+	HeaderFooterItalic:          'I',
+	HeaderFooterUnderline:       'U',
+	HeaderFooterDoubleUnderline: 'E',
+	HeaderFooterStrikethrough:   'S',
+	HeaderFooterSuperscript:     'X',
+	HeaderFooterSubscript:       'Y',
+	HeaderFooterOutline:         'O',
+	HeaderFooterShadow:          'H',
+}
+
+// HeaderFooterItem is a single piece of a header or footer section,
+// either literal text or a control code.
+type HeaderFooterItem struct {
+	Code  HeaderFooterCode
+	Value string
+}
+
+// HeaderFooterSection is the left, center or right part of a header
+// or footer.  The methods on HeaderFooterSection append an item and
+// return the section, so they can be chained:
+//
+//	var hf xlsx.HeaderFooterContent
+//	hf.Center.Text("Page ").PageNumber().Text(" of ").TotalPages()
+type HeaderFooterSection struct {
+	Items []HeaderFooterItem
+}
+
+func (s *HeaderFooterSection) add(code HeaderFooterCode, value string) *HeaderFooterSection {
+	s.Items = append(s.Items, HeaderFooterItem{Code: code, Value: value})
+	return s
+}
+
+// Text appends literal text to the section.
+func (s *HeaderFooterSection) Text(text string) *HeaderFooterSection {
+	return s.add(HeaderFooterLiteral, text)
+}
+
+// PageNumber appends the current page number (&P).
+func (s *HeaderFooterSection) PageNumber() *HeaderFooterSection {
+	return s.add(HeaderFooterPageNumber, "")
+}
+
+// TotalPages appends the total number of pages (&N).
+func (s *HeaderFooterSection) TotalPages() *HeaderFooterSection {
+	return s.add(HeaderFooterTotalPages, "")
+}
+
+// Date appends the date of printing (&D).
+func (s *HeaderFooterSection) Date() *HeaderFooterSection {
+	return s.add(HeaderFooterDate, "")
+}
+
+// Time appends the time of printing (&T).
+func (s *HeaderFooterSection) Time() *HeaderFooterSection {
+	return s.add(HeaderFooterTime, "")
+}
+
+// FilePath appends the path of the workbook (&Z).
+func (s *HeaderFooterSection) FilePath() *HeaderFooterSection {
+	return s.add(HeaderFooterFilePath, "")
+}
+
+// FileName appends the name of the workbook (&F).
+func (s *HeaderFooterSection) FileName() *HeaderFooterSection {
+	return s.add(HeaderFooterFileName, "")
+}
+
+// SheetName appends the name of the sheet (&A).
+func (s *HeaderFooterSection) SheetName() *HeaderFooterSection {
+	return s.add(HeaderFooterSheetName, "")
+}
+
+// Picture appends a picture placeholder (&G).  See
+// HeaderFooterPicture for the limitations.
+func (s *HeaderFooterSection) Picture() *HeaderFooterSection {
+	return s.add(HeaderFooterPicture, "")
+}
+
+// Font switches the font used for the text that follows, e.g.
+// Font("Arial", "Bold Italic").
+func (s *HeaderFooterSection) Font(name, style string) *HeaderFooterSection {
+	if name == "" {
+		name = "-"
+	}
+	if style == "" {
+		style = "Regular"
+	}
+	return s.add(HeaderFooterFont, name+","+style)
+}
+
+// FontSize switches the font size, in points, for the text that follows.
+func (s *HeaderFooterSection) FontSize(size int) *HeaderFooterSection {
+	return s.add(HeaderFooterFontSize, strconv.Itoa(size))
+}
+
+// Color switches the font colour for the text that follows.  The
+// colour is given as an RGB hex string, e.g. "FF0000".
+func (s *HeaderFooterSection) Color(rgb string) *HeaderFooterSection {
+	return s.add(HeaderFooterColor, strings.TrimPrefix(rgb, "#"))
+}
+
+// Bold toggles bold text (&B).
+func (s *HeaderFooterSection) Bold() *HeaderFooterSection {
+	return s.add(HeaderFooterBold, "")
+}
+
+// Italic toggles italic text (&I).
+func (s *HeaderFooterSection) Italic() *HeaderFooterSection {
+	return s.add(HeaderFooterItalic, "")
+}
+
+// Underline toggles single underlining (&U).
+func (s *HeaderFooterSection) Underline() *HeaderFooterSection {
+	return s.add(HeaderFooterUnderline, "")
+}
+
+// DoubleUnderline toggles double underlining (&E).
+func (s *HeaderFooterSection) DoubleUnderline() *HeaderFooterSection {
+	return s.add(HeaderFooterDoubleUnderline, "")
+}
+
+// Strikethrough toggles strikethrough (&S).
+func (s *HeaderFooterSection) Strikethrough() *HeaderFooterSection {
+	return s.add(HeaderFooterStrikethrough, "")
+}
+
+// Superscript toggles superscript (&X).
+func (s *HeaderFooterSection) Superscript() *HeaderFooterSection {
+	return s.add(HeaderFooterSuperscript, "")
+}
+
+// Subscript toggles subscript (&Y).
+func (s *HeaderFooterSection) Subscript() *HeaderFooterSection {
+	return s.add(HeaderFooterSubscript, "")
+}
+
+// Outline toggles outlined text (&O), which only Excel for Mac shows.
+func (s *HeaderFooterSection) Outline() *HeaderFooterSection {
+	return s.add(HeaderFooterOutline, "")
+}
+
+// Shadow toggles shadowed text (&H), which only Excel for Mac shows.
+func (s *HeaderFooterSection) Shadow() *HeaderFooterSection {
+	return s.add(HeaderFooterShadow, "")
+}
+
+// String renders the section using Excel's control codes.
+func (s HeaderFooterSection) String() string {
+	var b strings.Builder
+	for i, item := range s.Items {
+		switch item.Code {
+		case HeaderFooterLiteral:
+			b.WriteString(strings.ReplaceAll(item.Value, "&", "&&"))
+		case HeaderFooterFont:
+			b.WriteString(`&"` + item.Value + `"`)
+		case HeaderFooterFontSize:
+			b.WriteString("&" + item.Value)
+			// A digit straight after the size would be read
+			// as part of it, so keep them apart.
+			if i+1 < len(s.Items) {
+				next := s.Items[i+1]
+				if next.Code == HeaderFooterLiteral && startsWithNumber(next.Value) {
+					b.WriteByte(' ')
+				}
+			}
+		case HeaderFooterColor:
+			b.WriteString("&K" + item.Value)
+		default:
+			b.WriteByte('&')
+			b.WriteByte(headerFooterSimpleCodes[item.Code])
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// startsWithNumber returns true if s is a digit, after any leading
+// spaces.  Such text needs a space to separate it from a font size:
+// the parser drops one space between a size and a number.
+func startsWithNumber(s string) bool {
+	s = strings.TrimLeft(s, " ")
+	return s != "" && isDigit(s[0])
+}
+
+// HeaderFooterContent is a complete header or footer, made up of
+// three sections.
+type HeaderFooterContent struct {
+	Left   HeaderFooterSection
+	Center HeaderFooterSection
+	Right  HeaderFooterSection
+}
+
+// IsEmpty returns true if none of the sections contain anything.
+func (c HeaderFooterContent) IsEmpty() bool {
+	return len(c.Left.Items) == 0 && len(c.Center.Items) == 0 && len(c.Right.Items) == 0
+}
+
+// String renders the header or footer in the form Excel stores it,
+// e.g. "&LConfidential&CPage &P of &N".
+func (c HeaderFooterContent) String() string {
+	var b strings.Builder
+	if len(c.Left.Items) > 0 {
+		b.WriteString("&L" + c.Left.String())
+	}
+	if len(c.Center.Items) > 0 {
+		b.WriteString("&C" + c.Center.String())
+	}
+	if len(c.Right.Items) > 0 {
+		b.WriteString("&R" + c.Right.String())
+	}
+	return b.String()
+}
+
+// ParseHeaderFooter parses a header or footer string, as stored by
+// Excel, into its sections.  Text that appears before any section
+// code belongs to the center section.  A control code that can't be
+// parsed is an error.
+func ParseHeaderFooter(s string) (HeaderFooterContent, error) {
+	return parseHeaderFooter(s, false)
+}
+
+// parseHeaderFooter parses a header or footer string.  When lenient
+// is true, control codes that can't be parsed are kept as literal text
+// instead, so that a file isn't refused over its headers.
+func parseHeaderFooter(s string, lenient bool) (HeaderFooterContent, error) {
+	var content HeaderFooterContent
+	section := &content.Center
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			section.Text(text.String())
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '&' {
+			text.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			if lenient {
+				text.WriteByte('&')
+				break
+			}
+			return content, fmt.Errorf("ParseHeaderFooter: %q ends with an incomplete control code", s)
+		}
+		c := s[i]
+		if c == '&' {
+			text.WriteByte('&')
+			continue
+		}
+		flush()
+		switch {
+		case c == 'L':
+			section = &content.Left
+		case c == 'C':
+			section = &content.Center
+		case c == 'R':
+			section = &content.Right
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				if lenient {
+					text.WriteString(s[i-1:])
+					i = len(s)
+					break
+				}
+				return content, fmt.Errorf("ParseHeaderFooter: unterminated font name in %q", s)
+			}
+			section.add(HeaderFooterFont, s[i+1:i+1+end])
+			i += end + 1
+		case isDigit(c):
+			start := i
+			for i+1 < len(s) && isDigit(s[i+1]) {
+				i++
+			}
+			section.add(HeaderFooterFontSize, s[start:i+1])
+			// Skip the space that separates a size
+			// from a following number.
+			if i+1 < len(s) && s[i+1] == ' ' && startsWithNumber(s[i+1:]) {
+				i++
+			}
+		case c == 'K':
+			if i+6 >= len(s) {
+				if lenient {
+					text.WriteString(s[i-1:])
+					i = len(s)
+					break
+				}
+				return content, fmt.Errorf("ParseHeaderFooter: incomplete colour code in %q", s)
+			}
+			section.add(HeaderFooterColor, s[i+1:i+7])
+			i += 6
+		default:
+			code, ok := headerFooterCodeForLetter(c)
+			if !ok {
+				if lenient {
+					text.WriteString(s[i-1 : i+1])
+					break
+				}
+				return content, fmt.Errorf("ParseHeaderFooter: unknown control code &%c in %q", c, s)
+			}
+			section.add(code, "")
+		}
+	}
+	flush()
+	return content, nil
+}
+
+func headerFooterCodeForLetter(letter byte) (HeaderFooterCode, bool) {
+	for code, l := range headerFooterSimpleCodes {
+		if l == letter {
+			return code, true
+		}
+	}
+	return HeaderFooterLiteral, false
+}
+
+// HeaderFooter holds the page headers and footers of a Sheet.  The
+// Odd variants are used for every page, unless DifferentFirst or
+// DifferentOddEven are set, in which case the First and Even variants
+// apply to the first and even pages respectively.  Setting a First
+// or Even variant implies the corresponding flag.
+type HeaderFooter struct {
+	DifferentFirst   bool
+	DifferentOddEven bool
+	OddHeader        HeaderFooterContent
+	OddFooter        HeaderFooterContent
+	EvenHeader       HeaderFooterContent
+	EvenFooter       HeaderFooterContent
+	FirstHeader      HeaderFooterContent
+	FirstFooter      HeaderFooterContent
+}
+
+// clone returns a copy of the HeaderFooter that shares none of its
+// sections' items.
+func (hf *HeaderFooter) clone() *HeaderFooter {
+	c := *hf
+	for _, content := range []*HeaderFooterContent{&c.OddHeader, &c.OddFooter, &c.EvenHeader, &c.EvenFooter, &c.FirstHeader, &c.FirstFooter} {
+		for _, section := range []*HeaderFooterSection{&content.Left, &content.Center, &content.Right} {
+			section.Items = append([]HeaderFooterItem(nil), section.Items...)
+		}
+	}
+	return &c
+}
+
+// makeXLSXHeaderFooter returns the headerFooter element for the
+// HeaderFooter, or nil if there's nothing to write.
+func (hf *HeaderFooter) makeXLSXHeaderFooter() *xlsxHeaderFooter {
+	if hf == nil {
+		return nil
+	}
+	differentFirst := hf.DifferentFirst || !hf.FirstHeader.IsEmpty() || !hf.FirstFooter.IsEmpty()
+	differentOddEven := hf.DifferentOddEven || !hf.EvenHeader.IsEmpty() || !hf.EvenFooter.IsEmpty()
+	if !differentFirst && !differentOddEven && hf.OddHeader.IsEmpty() && hf.OddFooter.IsEmpty() {
+		return nil
+	}
+
+	x := &xlsxHeaderFooter{}
+	if differentFirst {
+		x.DifferentFirst = &differentFirst
+	}
+	if differentOddEven {
+		x.DifferentOddEven = &differentOddEven
+	}
+	if !hf.OddHeader.IsEmpty() {
+		x.OddHeader = []xlsxOddHeader{{Content: hf.OddHeader.String()}}
+	}
+	if !hf.OddFooter.IsEmpty() {
+		x.OddFooter = []xlsxOddFooter{{Content: hf.OddFooter.String()}}
+	}
+	if differentOddEven {
+		if !hf.EvenHeader.IsEmpty() {
+			x.EvenHeader = []xlsxEvenHeader{{Content: hf.EvenHeader.String()}}
+		}
+		if !hf.EvenFooter.IsEmpty() {
+			x.EvenFooter = []xlsxEvenFooter{{Content: hf.EvenFooter.String()}}
+		}
+	}
+	if differentFirst {
+		if !hf.FirstHeader.IsEmpty() {
+			x.FirstHeader = []xlsxFirstHeader{{Content: hf.FirstHeader.String()}}
+		}
+		if !hf.FirstFooter.IsEmpty() {
+			x.FirstFooter = []xlsxFirstFooter{{Content: hf.FirstFooter.String()}}
+		}
+	}
+	return x
+}
+
+// readHeaderFooter converts a headerFooter element into a
+// HeaderFooter.  Control codes that can't be parsed are read as
+// literal text.
+func readHeaderFooter(x *xlsxHeaderFooter) *HeaderFooter {
+	if x == nil {
+		return nil
+	}
+	hf := &HeaderFooter{}
+	if x.DifferentFirst != nil {
+		hf.DifferentFirst = *x.DifferentFirst
+	}
+	if x.DifferentOddEven != nil {
+		hf.DifferentOddEven = *x.DifferentOddEven
+	}
+
+	parse := func(target *HeaderFooterContent, content string) {
+		*target, _ = parseHeaderFooter(content, true)
+	}
+	if len(x.OddHeader) > 0 {
+		parse(&hf.OddHeader, x.OddHeader[0].Content)
+	}
+	if len(x.OddFooter) > 0 {
+		parse(&hf.OddFooter, x.OddFooter[0].Content)
+	}
+	if len(x.EvenHeader) > 0 {
+		parse(&hf.EvenHeader, x.EvenHeader[0].Content)
+	}
+	if len(x.EvenFooter) > 0 {
+		parse(&hf.EvenFooter, x.EvenFooter[0].Content)
+	}
+	if len(x.FirstHeader) > 0 {
+		parse(&hf.FirstHeader, x.FirstHeader[0].Content)
+	}
+	if len(x.FirstFooter) > 0 {
+		parse(&hf.FirstFooter, x.FirstFooter[0].Content)
+	}
+	return hf
+}
@@ -0,0 +1,193 @@
+package xlsx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestHeaderFooter(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("BuildSections", func(c *qt.C) {
+		var content HeaderFooterContent
+		content.Left.Font("Arial", "Bold").Text("R&D")
+		content.Center.Text("Page ").PageNumber().Text(" of ").TotalPages()
+		content.Right.Date().Text(" ").Time()
+		c.Assert(content.String(), qt.Equals, `&L&"Arial,Bold"R&&D&CPage &P of &N&R&D &T`)
+	})
+
+	c.Run("EmptySectionsAreOmitted", func(c *qt.C) {
+		var content HeaderFooterContent
+		c.Assert(content.IsEmpty(), qt.IsTrue)
+		c.Assert(content.String(), qt.Equals, "")
+		content.Right.SheetName()
+		c.Assert(content.IsEmpty(), qt.IsFalse)
+		c.Assert(content.String(), qt.Equals, "&R&A")
+	})
+
+	c.Run("FontSizeFollowedByDigit", func(c *qt.C) {
+		var content HeaderFooterContent
+		content.Center.FontSize(14).Text("2020 report")
+		c.Assert(content.String(), qt.Equals, "&C&14 2020 report")
+
+		parsed, err := ParseHeaderFooter(content.String())
+		c.Assert(err, qt.IsNil)
+		c.Assert(parsed, qt.DeepEquals, content)
+	})
+
+	c.Run("FontSizeFollowedBySpaceAndDigit", func(c *qt.C) {
+		var content HeaderFooterContent
+		content.Center.FontSize(9).Text(" 5 pages")
+		c.Assert(content.String(), qt.Equals, "&C&9  5 pages")
+
+		parsed, err := ParseHeaderFooter(content.String())
+		c.Assert(err, qt.IsNil)
+		c.Assert(parsed, qt.DeepEquals, content)
+
+		// Text after a size that doesn't start with a number keeps
+		// its spaces as they are
+		parsed, err = ParseHeaderFooter("&C&9 pages")
+		c.Assert(err, qt.IsNil)
+		c.Assert(parsed.Center.Items[1], qt.DeepEquals, HeaderFooterItem{Code: HeaderFooterLiteral, Value: " pages"})
+	})
+
+	c.Run("OutlineAndShadow", func(c *qt.C) {
+		var content HeaderFooterContent
+		content.Left.Outline().Text("Draft").Outline()
+		content.Right.Shadow().SheetName()
+		c.Assert(content.String(), qt.Equals, "&L&ODraft&O&R&H&A")
+
+		parsed, err := ParseHeaderFooter(content.String())
+		c.Assert(err, qt.IsNil)
+		c.Assert(parsed, qt.DeepEquals, content)
+	})
+
+	c.Run("ParseHeaderFooter", func(c *qt.C) {
+		content, err := ParseHeaderFooter(`&L&"Calibri,Italic"&12&KFF0000Draft&C&F - &A&R&G&BPage &P+1`)
+		c.Assert(err, qt.IsNil)
+		c.Assert(content.Left.Items, qt.DeepEquals, []HeaderFooterItem{
+			{Code: HeaderFooterFont, Value: "Calibri,Italic"},
+			{Code: HeaderFooterFontSize, Value: "12"},
+			{Code: HeaderFooterColor, Value: "FF0000"},
+			{Code: HeaderFooterLiteral, Value: "Draft"},
+		})
+		c.Assert(content.Center.Items, qt.DeepEquals, []HeaderFooterItem{
+			{Code: HeaderFooterFileName},
+			{Code: HeaderFooterLiteral, Value: " - "},
+			{Code: HeaderFooterSheetName},
+		})
+		c.Assert(content.Right.Items, qt.DeepEquals, []HeaderFooterItem{
+			{Code: HeaderFooterPicture},
+			{Code: HeaderFooterBold},
+			{Code: HeaderFooterLiteral, Value: "Page "},
+			{Code: HeaderFooterPageNumber},
+			{Code: HeaderFooterLiteral, Value: "+1"},
+		})
+	})
+
+	c.Run("ParseTextWithoutSection", func(c *qt.C) {
+		content, err := ParseHeaderFooter("Q&&A")
+		c.Assert(err, qt.IsNil)
+		c.Assert(content.Left.Items, qt.HasLen, 0)
+		c.Assert(content.Center.Items, qt.DeepEquals, []HeaderFooterItem{
+			{Code: HeaderFooterLiteral, Value: "Q&A"},
+		})
+		c.Assert(content.String(), qt.Equals, "&CQ&&A")
+	})
+
+	c.Run("ParseErrors", func(c *qt.C) {
+		for _, s := range []string{"&", `&"Arial`, "&K00", "&Q"} {
+			_, err := ParseHeaderFooter(s)
+			c.Assert(err, qt.Not(qt.IsNil), qt.Commentf("%q", s))
+		}
+	})
+
+	c.Run("ReadLenient", func(c *qt.C) {
+		// Reading a file keeps the codes it can't parse as text
+		hf := readHeaderFooter(&xlsxHeaderFooter{
+			OddHeader: []xlsxOddHeader{{Content: "&LDraft&Q&C&KFF"}},
+			OddFooter: []xlsxOddFooter{{Content: `&R&"Arial`}},
+		})
+		c.Assert(hf.OddHeader.Left.Items, qt.DeepEquals, []HeaderFooterItem{
+			{Code: HeaderFooterLiteral, Value: "Draft"},
+			{Code: HeaderFooterLiteral, Value: "&Q"},
+		})
+		c.Assert(hf.OddHeader.Center.Items, qt.DeepEquals, []HeaderFooterItem{
+			{Code: HeaderFooterLiteral, Value: "&KFF"},
+		})
+		c.Assert(hf.OddFooter.Right.Items, qt.DeepEquals, []HeaderFooterItem{
+			{Code: HeaderFooterLiteral, Value: `&"Arial`},
+		})
+		hf = readHeaderFooter(&xlsxHeaderFooter{
+			EvenFooter: []xlsxEvenFooter{{Content: "&CPage&"}},
+		})
+		c.Assert(hf.EvenFooter.Center.Items, qt.DeepEquals, []HeaderFooterItem{
+			{Code: HeaderFooterLiteral, Value: "Page&"},
+		})
+	})
+
+	c.Run("MakeXLSXHeaderFooter", func(c *qt.C) {
+		var hf *HeaderFooter
+		c.Assert(hf.makeXLSXHeaderFooter(), qt.IsNil)
+		hf = &HeaderFooter{}
+		c.Assert(hf.makeXLSXHeaderFooter(), qt.IsNil)
+
+		hf.OddFooter.Center.PageNumber()
+		hf.FirstHeader.Center.Text("Title page")
+		x := hf.makeXLSXHeaderFooter()
+		c.Assert(x, qt.Not(qt.IsNil))
+		c.Assert(*x.DifferentFirst, qt.IsTrue)
+		c.Assert(x.DifferentOddEven, qt.IsNil)
+		c.Assert(x.OddHeader, qt.HasLen, 0)
+		c.Assert(x.OddFooter, qt.DeepEquals, []xlsxOddFooter{{Content: "&C&P"}})
+		c.Assert(x.FirstHeader, qt.DeepEquals, []xlsxFirstHeader{{Content: "&CTitle page"}})
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		file := NewFile(option)
+		sheet, err := file.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		cell, err := sheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		cell.Value = "A cell!"
+		cell, err = sheet.Cell(1, 1)
+		c.Assert(err, qt.IsNil)
+		cell.Value = "Merged"
+		cell.Merge(1, 0)
+
+		hf := &HeaderFooter{}
+		hf.OddHeader.Left.Text("Confidential")
+		hf.OddHeader.Right.Date()
+		hf.OddFooter.Center.Text("Page ").PageNumber().Text(" of ").TotalPages()
+		hf.EvenFooter.Left.FileName()
+		hf.FirstHeader.Center.Font("-", "Bold").FontSize(16).SheetName()
+		sheet.HeaderFooter = hf
+
+		var buf bytes.Buffer
+		err = sheet.MarshalSheet(&buf, NewSharedStringRefTable(), newXlsxStyleSheet(nil), nil)
+		c.Assert(err, qt.IsNil)
+		output := buf.String()
+		// The headerFooter must come after sheetData and mergeCells
+		c.Assert(strings.Index(output, "</sheetData>") < strings.Index(output, "<mergeCells"), qt.IsTrue)
+		c.Assert(strings.Index(output, "<mergeCells") < strings.Index(output, "<headerFooter"), qt.IsTrue)
+
+		buf.Reset()
+		err = file.Write(&buf)
+		c.Assert(err, qt.IsNil)
+		file, err = OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+
+		sheet = file.Sheets[0]
+		c.Assert(sheet.HeaderFooter, qt.Not(qt.IsNil))
+		c.Assert(sheet.HeaderFooter.DifferentFirst, qt.IsTrue)
+		c.Assert(sheet.HeaderFooter.DifferentOddEven, qt.IsTrue)
+		c.Assert(sheet.HeaderFooter.OddHeader.String(), qt.Equals, hf.OddHeader.String())
+		c.Assert(sheet.HeaderFooter.OddFooter, qt.DeepEquals, hf.OddFooter)
+		c.Assert(sheet.HeaderFooter.EvenFooter, qt.DeepEquals, hf.EvenFooter)
+		c.Assert(sheet.HeaderFooter.FirstHeader, qt.DeepEquals, hf.FirstHeader)
+		c.Assert(sheet.HeaderFooter.EvenHeader.IsEmpty(), qt.IsTrue)
+	})
+}
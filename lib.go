@@ -18,6 +18,8 @@ import (
 
 const (
 	sheetEnding           = `</sheetData></worksheet>`
+	printAreaName         = "_xlnm.Print_Area"
+	printTitlesName       = "_xlnm.Print_Titles"
 	fixedCellRefChar      = "$"
 	cellRangeChar         = ":"
 	externalSheetBangChar = "!"
@@ -282,58 +284,22 @@ func formulaForCell(rawcell xlsxC, sharedFormulas map[int]sharedFormula) string
 		return ""
 	}
 	if f.T == "shared" {
+		var si int
+		if f.Si != nil {
+			si = *f.Si
+		}
 		x, y, err := GetCoordsFromCellIDString(rawcell.R)
 		if err != nil {
 			res = f.Content
 		} else {
 			if f.Ref != "" {
 				res = f.Content
-				sharedFormulas[f.Si] = sharedFormula{x, y, res}
+				sharedFormulas[si] = sharedFormula{x, y, res}
 			} else {
-				sharedFormula := sharedFormulas[f.Si]
+				sharedFormula := sharedFormulas[si]
 				dx := x - sharedFormula.x
 				dy := y - sharedFormula.y
-				orig := []byte(sharedFormula.formula)
-				var start, end int
-				var stringLiteral bool
-				for end = 0; end < len(orig); end++ {
-					c := orig[end]
-
-					if c == '"' {
-						stringLiteral = !stringLiteral
-					}
-
-					if stringLiteral {
-						continue // Skip characters in quotes
-					}
-
-					if c >= 'A' && c <= 'Z' || c == '$' {
-						res += string(orig[start:end])
-						start = end
-						end++
-						foundNum := false
-						for ; end < len(orig); end++ {
-							idc := orig[end]
-							if idc >= '0' && idc <= '9' || idc == '$' {
-								foundNum = true
-							} else if idc >= 'A' && idc <= 'Z' {
-								if foundNum {
-									break
-								}
-							} else {
-								break
-							}
-						}
-						if foundNum {
-							cellID := string(orig[start:end])
-							res += shiftCell(cellID, dx, dy)
-							start = end
-						}
-					}
-				}
-				if start < len(orig) {
-					res += string(orig[start:])
-				}
+				res = shiftFormula(sharedFormula.formula, dx, dy)
 			}
 		}
 	} else {
@@ -342,61 +308,16 @@ func formulaForCell(rawcell xlsxC, sharedFormulas map[int]sharedFormula) string
 	return strings.Trim(res, " \t\n\r")
 }
 
-// shiftCell returns the cell shifted according to dx and dy taking into consideration of absolute
-// references with dollar sign ($)
-func shiftCell(cellID string, dx, dy int) string {
-	fx, fy, _ := GetCoordsFromCellIDString(cellID)
-
-	// Is fixed column?
-	fixedCol := strings.Index(cellID, fixedCellRefChar) == 0
-
-	// Is fixed row?
-	fixedRow := strings.LastIndex(cellID, fixedCellRefChar) > 0
-
-	if !fixedCol {
-		// Shift column
-		fx += dx
-	}
-
-	if !fixedRow {
-		// Shift row
-		fy += dy
-	}
-
-	// New shifted cell
-	shiftedCellID := GetCellIDStringFromCoords(fx, fy)
-
-	if !fixedCol && !fixedRow {
-		return shiftedCellID
-	}
-
-	// There are absolute references, need to put the $ back into the formula.
-	letterPart := strings.Map(letterOnlyMapF, shiftedCellID)
-	numberPart := strings.Map(intOnlyMapF, shiftedCellID)
-
-	result := ""
-
-	if fixedCol {
-		result += "$"
-	}
-
-	result += letterPart
-
-	if fixedRow {
-		result += "$"
-	}
-
-	result += numberPart
-
-	return result
-}
-
 // fillCellData attempts to extract a valid value, usable in
 // CSV form from the raw cell value.  Note - this is not actually
 // general enough - we should support retaining tabs and newlines.
 func fillCellData(rawCell xlsxC, refTable *RefTable, sharedFormulas map[int]sharedFormula, cell *Cell) {
 	val := strings.Trim(rawCell.V, " \t\n\r")
 	cell.formula = formulaForCell(rawCell, sharedFormulas)
+	if rawCell.F != nil && rawCell.F.T == "array" {
+		cell.arrayRef = rawCell.F.Ref
+		cell.dynamicArray = rawCell.Cm != 0
+	}
 	switch rawCell.T {
 	case "s": // Shared String
 		cell.cellType = CellTypeString
@@ -525,11 +446,15 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 		row.num = rawrow.R - 1
 
 		row.Hidden = rawrow.Hidden
+		row.Collapsed = rawrow.Collapsed
 		height, err := strconv.ParseFloat(rawrow.Ht, 64)
 		if err == nil {
 			row.SetHeight(height)
 		}
 		row.isCustom = rawrow.CustomHeight
+		if rawrow.CustomFormat && file.styles != nil {
+			row.style = file.styles.getStyle(rawrow.S)
+		}
 		row.SetOutlineLevel(rawrow.OutlineLevel)
 
 		for _, rawcell := range rawrow.C {
@@ -552,9 +477,21 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 			cell.HMerge = h
 			cell.VMerge = v
 			fillCellData(rawcell, reftable, sharedFormulas, cell)
+			col := sheet.Cols.FindColByIndex(cellX + 1)
 			if file.styles != nil {
-				cell.SetStyle(file.styles.getStyle(rawcell.S))
-				cell.NumFmt, cell.parsedNumFmt = file.styles.getNumberFormat(rawcell.S)
+				// A cell without a style of its own takes the
+				// style of its row, or failing that, its column
+				styleIndex := rawcell.S
+				if styleIndex == 0 && rawrow.CustomFormat {
+					styleIndex = rawrow.S
+				}
+				if styleIndex == 0 && col != nil && col.style != nil {
+					cell.SetStyle(col.style)
+					cell.NumFmt, cell.parsedNumFmt = col.numFmt, col.parsedNumFmt
+				} else {
+					cell.SetStyle(file.styles.getStyle(styleIndex))
+					cell.NumFmt, cell.parsedNumFmt = file.styles.getNumberFormat(styleIndex)
+				}
 			}
 			cell.date1904 = file.Date1904
 
@@ -563,7 +500,6 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File, sheet *Sheet, rowLi
 			}
 
 			// Cell is considered hidden if the row or the column of this cell is hidden
-			col := sheet.Cols.FindColByIndex(cellX + 1)
 			cell.Hidden = rawrow.Hidden || (col != nil && col.Hidden != nil && *col.Hidden)
 			cell.modified = true
 		}
@@ -591,6 +527,19 @@ type indexedSheet struct {
 	Error error
 }
 
+// readBreaks converts a rowBreaks or colBreaks element into a sorted
+// slice of break indices.
+func readBreaks(xBreaks *xlsxBreaks) []int {
+	if xBreaks == nil {
+		return nil
+	}
+	var breaks []int
+	for _, brk := range xBreaks.Brk {
+		breaks = addBreak(breaks, brk.Id)
+	}
+	return breaks
+}
+
 func readSheetViews(xSheetViews xlsxSheetViews) []SheetView {
 	if xSheetViews.SheetView == nil || len(xSheetViews.SheetView) == 0 {
 		return nil
@@ -608,11 +557,39 @@ func readSheetViews(xSheetViews xlsxSheetViews) []SheetView {
 			pane.State = xlsxPane.State
 			sheetView.Pane = pane
 		}
+		if xSheetView.ZoomScale != 100 {
+			sheetView.ZoomScale = int(xSheetView.ZoomScale)
+		}
+		sheetView.HideGridLines = !xSheetView.ShowGridLines
+		sheetView.HideRowColHeaders = !xSheetView.ShowRowColHeaders
+		sheetView.RightToLeft = xSheetView.RightToLeft
+		if xSheetView.TopLeftCell != "A1" {
+			sheetView.TopLeftCell = xSheetView.TopLeftCell
+		}
+		if selection := readSelection(xSheetView); selection != nil {
+			sheetView.ActiveCell = selection.ActiveCell
+			sheetView.Selection = selection.SQRef
+		}
 		sheetViews = append(sheetViews, sheetView)
 	}
 	return sheetViews
 }
 
+// readSelection returns the selection in the active pane of a sheet
+// view, or nil if it has none.
+func readSelection(xSheetView xlsxSheetView) *xlsxSelection {
+	activePane := "topLeft"
+	if xSheetView.Pane != nil && xSheetView.Pane.ActivePane != "" {
+		activePane = xSheetView.Pane.ActivePane
+	}
+	for i, selection := range xSheetView.Selection {
+		if selection.Pane == activePane || (selection.Pane == "" && activePane == "topLeft") {
+			return &xSheetView.Selection[i]
+		}
+	}
+	return nil
+}
+
 type coord struct {
 	x int
 	y int
@@ -721,10 +698,25 @@ func readSheetFromFile(rsheet xlsxSheet, fi *File, sheetXMLMap map[string]string
 
 	sheet.Hidden = rsheet.State == sheetStateHidden || rsheet.State == sheetStateVeryHidden
 	sheet.SheetViews = readSheetViews(worksheet.SheetViews)
+	for _, xSheetView := range worksheet.SheetViews.SheetView {
+		sheet.Selected = sheet.Selected || xSheetView.TabSelected
+	}
+	if outlinePr := worksheet.SheetPr.OutlinePr; outlinePr != nil {
+		sheet.summaryAbove = outlinePr.SummaryBelow != nil && !*outlinePr.SummaryBelow
+		sheet.summaryLeft = outlinePr.SummaryRight != nil && !*outlinePr.SummaryRight
+	}
+	if worksheet.SheetPr.TabColor != nil {
+		sheet.TabColor = worksheet.SheetPr.TabColor.RGB
+	}
 	if worksheet.AutoFilter != nil {
 		autoFilterBounds := strings.Split(worksheet.AutoFilter.Ref, ":")
 		sheet.AutoFilter = &AutoFilter{autoFilterBounds[0], autoFilterBounds[1]}
 	}
+	sheet.sortState = worksheet.SortState
+
+	sheet.HeaderFooter = readHeaderFooter(worksheet.HeaderFooter)
+	sheet.RowBreaks = readBreaks(worksheet.RowBreaks)
+	sheet.ColBreaks = readBreaks(worksheet.ColBreaks)
 
 	sheet.SheetFormat.DefaultColWidth = worksheet.SheetFormatPr.DefaultColWidth
 	sheet.SheetFormat.DefaultRowHeight = worksheet.SheetFormatPr.DefaultRowHeight
@@ -765,9 +757,11 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 		return wrap(fmt.Errorf("xml.Decoder.Decode: %w", err))
 	}
 	file.Date1904 = workbook.WorkbookPr.Date1904
+	file.FullCalcOnLoad = workbook.CalcPr.FullCalcOnLoad
+	file.r1c1 = file.r1c1 || workbook.CalcPr.RefMode == "R1C1"
 
-	for entryNum := range workbook.DefinedNames.DefinedName {
-		file.DefinedNames = append(file.DefinedNames, &workbook.DefinedNames.DefinedName[entryNum])
+	for _, dn := range workbook.DefinedNames.DefinedName {
+		file.DefinedNames = append(file.DefinedNames, readDefinedName(dn))
 	}
 
 	// Only try and read sheets that have corresponding files.
@@ -808,9 +802,32 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 		sheetsByName[sheetName] = sheet.Sheet
 		sheets[sheet.Index] = sheet.Sheet
 	}
+	file.DefinedNames = readBuiltInDefinedNames(file.DefinedNames, workbook.Sheets.Sheet, sheetsByName)
+	if len(workbook.BookViews.WorkBookView) > 0 {
+		if activeTab := workbook.BookViews.WorkBookView[0].ActiveTab; activeTab < len(workbook.Sheets.Sheet) {
+			file.activeSheet = sheetsByName[workbook.Sheets.Sheet[activeTab].Name]
+		}
+	}
 	return sheetsByName, sheets, nil
 }
 
+// readBuiltInDefinedNames hands the built-in defined names that
+// describe a sheet, such as its print area, to that sheet, and returns
+// the remaining names.
+func readBuiltInDefinedNames(names []*DefinedName, xSheets []xlsxSheet, sheetsByName map[string]*Sheet) []*DefinedName {
+	remaining := names[:0]
+	for _, dn := range names {
+		if dn.localSheetID != nil && *dn.localSheetID >= 0 && *dn.localSheetID < len(xSheets) {
+			sheet, ok := sheetsByName[xSheets[*dn.localSheetID].Name]
+			if ok && sheet.readBuiltInDefinedName(dn) {
+				continue
+			}
+		}
+		remaining = append(remaining, dn)
+	}
+	return remaining
+}
+
 // readSharedStringsFromZipFile() is an internal helper function to
 // extract a reference table from the sharedStrings.xml file within
 // the XLSX zip file.
@@ -1072,6 +1089,7 @@ func ReadZipReader(r *zip.Reader, options ...FileOption) (*File, error) {
 		}
 
 		file.styles = style
+		file.namedStyles = style.readNamedStyles()
 	}
 	sheetsByName, sheets, err = readSheetsFromZipFile(workbook, file, sheetXMLMap, file.rowLimit, file.valueOnly)
 	if err != nil {
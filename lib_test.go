@@ -1264,12 +1264,13 @@ func TestLib(t *testing.T) {
 		}
 
 		for i, formula := range formulas {
+			si := i
 			testCell := xlsxC{
 				R: "D5",
 				F: &xlsxF{
 					Content: formula,
 					T:       "shared",
-					Si:      i,
+					Si:      &si,
 				},
 			}
 
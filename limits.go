@@ -0,0 +1,102 @@
+package xlsx
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Excel refuses to open a file whose sheets go beyond its limits.
+// Besides the number of rows and columns, it limits the length of the
+// names of sheets, and the length of the text in a cell.
+const (
+	ExcelMaxSheetNameLength = 31
+	ExcelMaxCellTextLength  = 32767
+)
+
+// LimitError reports that a Sheet goes beyond one of Excel's limits.
+// It is returned when the File is written, or when a row beyond the
+// last one is asked for or inserted.
+type LimitError struct {
+	Sheet string
+	// Ref is the reference of the cell or row beyond the limit, or
+	// empty when the limit is on the sheet itself
+	Ref string
+	Msg string
+}
+
+func (e *LimitError) Error() string {
+	if e.Ref == "" {
+		return fmt.Sprintf("sheet %q: %s", e.Sheet, e.Msg)
+	}
+	return fmt.Sprintf("sheet %q, %s: %s", e.Sheet, e.Ref, e.Msg)
+}
+
+// checkSheetName returns an error if Excel wouldn't accept name as the
+// name of a sheet.
+func checkSheetName(name string) error {
+	runeLength := utf8.RuneCountInString(name)
+	if runeLength > ExcelMaxSheetNameLength || runeLength == 0 {
+		return fmt.Errorf("sheet name must be %d or fewer characters long.  It is currently '%d' characters long", ExcelMaxSheetNameLength, runeLength)
+	}
+	// Iterate over the runes
+	for _, r := range name {
+		// Excel forbids : \ / ? * [ ]
+		if r == ':' || r == '\\' || r == '/' || r == '?' || r == '*' || r == '[' || r == ']' {
+			return fmt.Errorf("sheet name must not contain any restricted characters : \\ / ? * [ ] but contains '%s'", string(r))
+		}
+	}
+	return nil
+}
+
+// checkLimits returns a LimitError if the Sheet's name is one Excel
+// wouldn't accept.
+func (s *Sheet) checkLimits() error {
+	if err := checkSheetName(s.Name); err != nil {
+		return &LimitError{Sheet: s.Name, Msg: err.Error()}
+	}
+	return nil
+}
+
+// checkLimits returns a LimitError if the Row lies beyond the last
+// row Excel allows.
+func (r *Row) checkLimits() error {
+	if r.num > Excel2006MaxRowIndex {
+		return rowLimitError(r.Sheet, r.num)
+	}
+	return nil
+}
+
+// rowLimitError returns the LimitError for the row with the given zero
+// based index, which lies beyond the last row Excel allows.
+func rowLimitError(s *Sheet, row int) *LimitError {
+	return &LimitError{
+		Sheet: s.Name,
+		Ref:   fmt.Sprintf("row %d", row+1),
+		Msg:   fmt.Sprintf("Excel allows at most %d rows", Excel2006MaxRowCount),
+	}
+}
+
+// checkLimits returns a LimitError if the Cell, in the given row, lies
+// beyond the last column Excel allows, or holds more text than a cell
+// can.
+func (c *Cell) checkLimits(row *Row) error {
+	if c.num > Excel2006MaxColIndex {
+		return &LimitError{
+			Sheet: row.Sheet.Name,
+			Ref:   "cell " + GetCellIDStringFromCoords(c.num, row.num),
+			Msg:   fmt.Sprintf("Excel allows at most %d columns", Excel2006MaxColCount),
+		}
+	}
+	text := c.Value
+	if len(c.RichText) > 0 {
+		text = richTextToPlainText(c.RichText)
+	}
+	if n := utf8.RuneCountInString(text); n > ExcelMaxCellTextLength {
+		return &LimitError{
+			Sheet: row.Sheet.Name,
+			Ref:   "cell " + GetCellIDStringFromCoords(c.num, row.num),
+			Msg:   fmt.Sprintf("the text is %d characters long, but Excel allows at most %d", n, ExcelMaxCellTextLength),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package xlsx
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestLimits(t *testing.T) {
+	c := qt.New(t)
+
+	// write writes the File both ways it can be written, and returns
+	// the errors
+	write := func(f *File) (error, error) {
+		_, streamErr := f.MakeStreamParts()
+		return streamErr, f.Write(&bytes.Buffer{})
+	}
+
+	c.Run("TooManyRows", func(c *qt.C) {
+		// Filling a whole sheet takes too long, so the row is
+		// moved beyond the last one directly
+		f := NewFile()
+		sheet, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		row := sheet.AddRow()
+		row.AddCell().SetString("too far")
+		row.num = Excel2006MaxRowCount
+		_, err = newXlsxWorksheet().makeXlsxRowFromRow(row, newXlsxStyleSheet(nil), NewSharedStringRefTable(), nil)
+		c.Assert(err, qt.ErrorMatches, `sheet "Data", row 1048577: Excel allows at most 1048576 rows`)
+
+		sheet.MaxRow = Excel2006MaxRowCount
+		_, err = sheet.AddRowAtIndex(0)
+		c.Assert(err, qt.ErrorMatches, `AddRowAtIndex: sheet "Data": Excel allows at most 1048576 rows`)
+
+		// Asking for a row beyond the last one fails at once
+		_, err = sheet.Row(Excel2006MaxRowCount)
+		c.Assert(err, qt.ErrorMatches, `sheet "Data", row 1048577: Excel allows at most 1048576 rows`)
+		_, err = sheet.Cell(Excel2006MaxRowCount, 0)
+		var limitErr *LimitError
+		c.Assert(errors.As(err, &limitErr), qt.IsTrue)
+		c.Assert(limitErr.Ref, qt.Equals, "row 1048577")
+	})
+
+	c.Run("TooManyCols", func(c *qt.C) {
+		f := NewFile()
+		sheet, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		cell, err := sheet.Cell(0, Excel2006MaxColCount)
+		c.Assert(err, qt.IsNil)
+		cell.SetString("too far")
+		streamErr, err := write(f)
+		c.Assert(streamErr, qt.ErrorMatches, `sheet "Data", cell XFE1: Excel allows at most 16384 columns`)
+		c.Assert(err, qt.ErrorMatches, `.*sheet "Data", cell XFE1: Excel allows at most 16384 columns`)
+		var limitErr *LimitError
+		c.Assert(errors.As(streamErr, &limitErr), qt.IsTrue)
+		c.Assert(limitErr.Ref, qt.Equals, "cell XFE1")
+	})
+
+	csRunO(c, "TooMuchText", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		sheet.AddRow().AddCell().SetString(strings.Repeat("é", ExcelMaxCellTextLength))
+		_, err = write(f)
+		c.Assert(err, qt.IsNil)
+		cell, err := sheet.Cell(0, 1)
+		c.Assert(err, qt.IsNil)
+		cell.SetRichText([]RichTextRun{
+			{Text: strings.Repeat("x", ExcelMaxCellTextLength)},
+			{Text: "!"},
+		})
+		streamErr, err := write(f)
+		c.Assert(streamErr, qt.ErrorMatches, `sheet "Data", cell B1: the text is 32768 characters long, but Excel allows at most 32767`)
+		c.Assert(err, qt.ErrorMatches, `.*sheet "Data", cell B1: the text is 32768 characters long, but Excel allows at most 32767`)
+	})
+
+	c.Run("SheetName", func(c *qt.C) {
+		f := NewFile()
+		sheet, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		sheet.Name = strings.Repeat("x", 32)
+		streamErr, err := write(f)
+		c.Assert(streamErr, qt.ErrorMatches, `sheet "x{32}": sheet name must be 31 or fewer characters long.  It is currently '32' characters long`)
+		c.Assert(err, qt.ErrorMatches, `.*sheet "x{32}": sheet name must be 31 .*`)
+
+		_, err = f.AppendSheet(Sheet{}, "a/b")
+		c.Assert(err, qt.ErrorMatches, `sheet name must not contain any restricted characters .* but contains '/'`)
+	})
+}
@@ -45,6 +45,14 @@ func (mr *MemoryRow) growCellsSlice(newSize int) {
 	if newSize > (mr.maxCol + 1) {
 		mr.maxCol = (newSize - 1)
 	}
+	// Shrinking only drops empty slots, such as those made for the
+	// span of a row, never the cells that follow
+	for i := len(mr.cells) - 1; i >= newSize; i-- {
+		if mr.cells[i] != nil {
+			newSize = i + 1
+			break
+		}
+	}
 
 	capacity := cap(mr.cells)
 	if newSize > capacity {
@@ -59,6 +67,31 @@ func (mr *MemoryRow) growCellsSlice(newSize int) {
 	mr.cells = newSlice
 }
 
+// ShiftCells moves the cells at or beyond the column index at by n
+// columns, removing the -n cells from index at onwards when n is
+// negative.
+func (mr *MemoryRow) ShiftCells(at, n int) {
+	if at >= len(mr.cells) {
+		return
+	}
+	if n > 0 {
+		mr.growCellsSlice(len(mr.cells) + n)
+		copy(mr.cells[at+n:], mr.cells[at:])
+		for i := at; i < at+n; i++ {
+			mr.cells[i] = nil
+		}
+	} else {
+		end := minInt(at-n, len(mr.cells))
+		mr.cells = append(mr.cells[:at], mr.cells[end:]...)
+		mr.maxCol = len(mr.cells) - 1
+	}
+	for i := at; i < len(mr.cells); i++ {
+		if mr.cells[i] != nil {
+			mr.cells[i].num = i
+		}
+	}
+}
+
 func (mr *MemoryRow) GetCell(colIdx int) *Cell {
 	if colIdx >= len(mr.cells) {
 		cell := newCell(mr.row, colIdx)
@@ -23,6 +23,19 @@ func TestMemoryCellStore(t *testing.T) {
 		c.Assert(ok, qt.Equals, true)
 	})
 
+	c.Run("PushCellKeepsLaterCells", func(c *qt.C) {
+		sheet, err := NewSheetWithCellStore("Sheet1", NewMemoryCellStore)
+		c.Assert(err, qt.IsNil)
+		row := sheet.AddRow()
+		row.AddCell().SetString("A")
+		row.AddCell().SetString("B")
+		cell := row.GetCell(0)
+		cell.SetString("Z")
+		row.PushCell(cell)
+		c.Assert(row.cellStoreRow.CellCount(), qt.Equals, 2)
+		c.Assert(row.GetCell(1).Value, qt.Equals, "B")
+	})
+
 	c.Run("Write and Read Row", func(c *qt.C) {
 		mCs, err := NewMemoryCellStore()
 		c.Assert(err, qt.IsNil)
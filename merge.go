@@ -0,0 +1,150 @@
+package xlsx
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// MergeOptions control how File.MergeFrom combines the sheets of
+// another File into the File.
+type MergeOptions struct {
+	// AppendRows appends the rows of each sheet to the sheet of the
+	// same name, when the File has one, rather than adding a copy of
+	// the sheet under a new name.  The sheets are expected to have
+	// the same shape.
+	AppendRows bool
+	// HeaderRows is the number of rows of headings at the top of each
+	// sheet.  When rows are appended, the headings are only kept once,
+	// and must be the same in every sheet.
+	HeaderRows int
+	// SourceColumn, when set and rows are appended, adds a column
+	// after the last column of each sheet that holds the name of the
+	// File the row came from.  SourceColumn is its heading, in the
+	// last header row.
+	SourceColumn string
+}
+
+// MergeFrom copies the sheets of src into the File, as
+// File.CopySheetFrom does, so that the styles of src are reconciled
+// with those of the File when it's written.  A sheet whose name the
+// File already uses is copied under a new name, such as "Sales (2)",
+// unless opts asks for its rows to be appended to the existing sheet.
+// The name identifies src in the source column.
+func (f *File) MergeFrom(src *File, name string, opts MergeOptions) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("MergeFrom(%q): %w", name, err)
+	}
+	if opts.HeaderRows < 0 {
+		return wrap(fmt.Errorf("HeaderRows must not be negative"))
+	}
+	for _, srcSheet := range src.Sheets {
+		// Excel doesn't tell sheet names apart by case
+		sheet := f.sheetByName(srcSheet.Name)
+		exists := sheet != nil
+		if !opts.AppendRows || !exists {
+			newName := srcSheet.Name
+			if exists {
+				newName = f.uniqueSheetName(newName)
+			}
+			copied, err := f.CopySheetFrom(srcSheet, newName)
+			if err != nil {
+				return wrap(err)
+			}
+			if opts.AppendRows {
+				if err := copied.addSourceColumn(srcSheet, name, opts, 0); err != nil {
+					return wrap(err)
+				}
+			}
+			continue
+		}
+		if err := sheet.appendRows(srcSheet, name, opts); err != nil {
+			return wrap(err)
+		}
+	}
+	return nil
+}
+
+// appendRows appends the rows of src, other than its header rows, to
+// the Sheet.
+func (s *Sheet) appendRows(src *Sheet, name string, opts MergeOptions) error {
+	if opts.HeaderRows > 0 && src.MaxCol > 0 {
+		area := NewCellRange(0, 0, src.MaxCol-1, opts.HeaderRows-1)
+		want, err := NewRange(s, area).Values()
+		if err != nil {
+			return err
+		}
+		got, err := NewRange(src, area).Values()
+		if err != nil {
+			return err
+		}
+		for y := range want {
+			for x := range want[y] {
+				if got[y][x] != want[y][x] {
+					return fmt.Errorf("the headings of the sheet %q differ at %s", src.Name, GetCellIDStringFromCoords(x, y))
+				}
+			}
+		}
+	}
+	dy := s.MaxRow - opts.HeaderRows
+	if err := s.copyRowsFrom(src, opts.HeaderRows, dy, s.styleCopier(src)); err != nil {
+		return err
+	}
+	return s.addSourceColumn(src, name, opts, dy)
+}
+
+// addSourceColumn fills in the source column for the rows of src that
+// were copied dy rows down the Sheet.
+func (s *Sheet) addSourceColumn(src *Sheet, name string, opts MergeOptions, dy int) error {
+	if opts.SourceColumn == "" {
+		return nil
+	}
+	col := src.MaxCol
+	var rows []int
+	err := src.ForEachRow(func(row *Row) error {
+		if row.num >= opts.HeaderRows {
+			rows = append(rows, row.num+dy)
+		}
+		return nil
+	}, SkipEmptyRows)
+	if err != nil {
+		return err
+	}
+	rng := NewRange(s)
+	if opts.HeaderRows > 0 && dy == 0 {
+		heading := NewCellRange(col, opts.HeaderRows-1, col, opts.HeaderRows-1)
+		err := rng.updateCells(heading, func(cell *Cell) error {
+			cell.SetString(opts.SourceColumn)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, y := range rows {
+		err := rng.updateCells(NewCellRange(col, y, col, y), func(cell *Cell) error {
+			cell.SetString(name)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueSheetName returns a name for a sheet, based on name, that the
+// File doesn't yet use, and that fits within the 31 characters that
+// Excel allows.
+func (f *File) uniqueSheetName(name string) string {
+	for n := 2; ; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		base := name
+		for utf8.RuneCountInString(base)+len(suffix) > 31 {
+			_, size := utf8.DecodeLastRuneInString(base)
+			base = base[:len(base)-size]
+		}
+		if f.sheetByName(base+suffix) == nil {
+			return base + suffix
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMerge(t *testing.T) {
+	c := qt.New(t)
+
+	// makeRegion returns a File with a sheet of sales and a sheet of
+	// notes, each headed by a row of headings.
+	makeRegion := func(c *qt.C, option FileOption, region string, amounts ...int) *File {
+		f := NewFile(option)
+		sales, err := f.AddSheet("Sales")
+		c.Assert(err, qt.IsNil)
+		style := NewStyle()
+		style.Font.Bold = true
+		style.ApplyFont = true
+		row := sales.AddRow()
+		for _, heading := range []string{"Item", "Amount", "Double"} {
+			cell := row.AddCell()
+			cell.SetString(heading)
+			cell.SetStyle(style)
+		}
+		for i, amount := range amounts {
+			row := sales.AddRow()
+			row.AddCell().SetString(region + " item")
+			row.AddCell().SetInt(amount)
+			err := row.AddCell().SetFormulaWithResult(fmt.Sprintf("B%d*2", i+2), strconv.Itoa(amount*2), CellTypeNumeric)
+			c.Assert(err, qt.IsNil)
+		}
+		notes, err := f.AddSheet("Notes")
+		c.Assert(err, qt.IsNil)
+		notes.AddRow().AddCell().SetString("Note")
+		notes.AddRow().AddCell().SetString("From " + region)
+		return f
+	}
+
+	csRunO(c, "AppendRows", func(c *qt.C, option FileOption) {
+		master := NewFile(option)
+		opts := MergeOptions{AppendRows: true, HeaderRows: 1, SourceColumn: "Region"}
+		c.Assert(master.MergeFrom(makeRegion(c, option, "North", 1, 2), "north.xlsx", opts), qt.IsNil)
+		c.Assert(master.MergeFrom(makeRegion(c, option, "South", 3), "south.xlsx", opts), qt.IsNil)
+
+		_, b := writeFileParts(c, master)
+		f, err := OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.Sheets, qt.HasLen, 2)
+		sales := f.Sheet["Sales"]
+		values, err := sales.Range("A1:D4")
+		c.Assert(err, qt.IsNil)
+		rows, err := values.Values()
+		c.Assert(err, qt.IsNil)
+		c.Assert(rows, qt.DeepEquals, [][]string{
+			{"Item", "Amount", "Double", "Region"},
+			{"North item", "1", "2", "north.xlsx"},
+			{"North item", "2", "4", "north.xlsx"},
+			{"South item", "3", "6", "south.xlsx"},
+		})
+		c.Assert(sales.MaxRow, qt.Equals, 4)
+		cell, err := sales.Cell(3, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Formula(), qt.Equals, "B4*2")
+		cell, err = sales.Cell(0, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.GetStyle().Font.Bold, qt.IsTrue)
+
+		notes := f.Sheet["Notes"]
+		c.Assert(notes.MaxRow, qt.Equals, 3)
+		cell, err = notes.Cell(2, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "From South")
+		cell, err = notes.Cell(2, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "south.xlsx")
+	})
+
+	csRunO(c, "CopySheets", func(c *qt.C, option FileOption) {
+		master := NewFile(option)
+		c.Assert(master.MergeFrom(makeRegion(c, option, "North", 1), "north.xlsx", MergeOptions{}), qt.IsNil)
+		c.Assert(master.MergeFrom(makeRegion(c, option, "South", 2), "south.xlsx", MergeOptions{}), qt.IsNil)
+		var names []string
+		for _, sheet := range master.Sheets {
+			names = append(names, sheet.Name)
+		}
+		c.Assert(names, qt.DeepEquals, []string{"Sales", "Notes", "Sales (2)", "Notes (2)"})
+		cell, err := master.Sheet["Sales (2)"].Cell(1, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Value, qt.Equals, "South item")
+	})
+
+	csRunO(c, "NamesDifferingInCase", func(c *qt.C, option FileOption) {
+		master := NewFile(option)
+		_, err := master.AddSheet("SALES")
+		c.Assert(err, qt.IsNil)
+		_, err = master.AddSheet("notes (2)")
+		c.Assert(err, qt.IsNil)
+		c.Assert(master.MergeFrom(makeRegion(c, option, "North", 1), "north.xlsx", MergeOptions{}), qt.IsNil)
+		var names []string
+		for _, sheet := range master.Sheets {
+			names = append(names, sheet.Name)
+		}
+		c.Assert(names, qt.DeepEquals, []string{"SALES", "notes (2)", "Sales (2)", "Notes"})
+
+		// Rows are appended to the sheet whose name differs in case
+		master = NewFile(option)
+		_, err = master.AddSheet("NOTES")
+		c.Assert(err, qt.IsNil)
+		opts := MergeOptions{AppendRows: true}
+		c.Assert(master.MergeFrom(makeRegion(c, option, "North", 1), "north.xlsx", opts), qt.IsNil)
+		c.Assert(master.Sheets, qt.HasLen, 2)
+		c.Assert(master.Sheet["NOTES"].MaxRow, qt.Equals, 2)
+	})
+
+	c.Run("MismatchedHeadings", func(c *qt.C) {
+		master := NewFile()
+		opts := MergeOptions{AppendRows: true, HeaderRows: 1}
+		c.Assert(master.MergeFrom(makeRegion(c, UseMemoryCellStore, "North", 1), "north.xlsx", opts), qt.IsNil)
+		other := makeRegion(c, UseMemoryCellStore, "South", 2)
+		cell, err := other.Sheet["Sales"].Cell(0, 1)
+		c.Assert(err, qt.IsNil)
+		cell.SetString("Total")
+		err = master.MergeFrom(other, "south.xlsx", opts)
+		c.Assert(err, qt.ErrorMatches, `MergeFrom\("south.xlsx"\): the headings of the sheet "Sales" differ at B1`)
+	})
+
+	c.Run("UniqueSheetName", func(c *qt.C) {
+		f := NewFile()
+		long := strings.Repeat("x", 31)
+		for _, name := range []string{"Data", "Data (2)", long} {
+			_, err := f.AddSheet(name)
+			c.Assert(err, qt.IsNil)
+		}
+		c.Assert(f.uniqueSheetName("Data"), qt.Equals, "Data (3)")
+		c.Assert(f.uniqueSheetName(long), qt.Equals, strings.Repeat("x", 27)+" (2)")
+	})
+}
@@ -0,0 +1,176 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// namedStyle is a named cell style of a File, such as "Input" or
+// "Heading 1", that Excel offers in its style gallery.  The index of a
+// namedStyle in File.namedStyles, plus one, is the index of its xf in
+// cellStyleXfs, as the first of those is always Excel's Normal style.
+type namedStyle struct {
+	name          string
+	style         *Style
+	builtInId     *int
+	customBuiltIn *bool
+	hidden        *bool
+}
+
+// AddNamedStyle defines a named cell style for the File, which cells
+// can then be given with Cell.SetNamedStyle.  Excel lists the named
+// styles of a workbook in its style gallery.  Names are unique,
+// ignoring case, and "Normal" is taken by Excel's default style.
+func (f *File) AddNamedStyle(name string, style *Style) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("AddNamedStyle(%q): %w", name, err)
+	}
+	if strings.TrimSpace(name) == "" {
+		return wrap(fmt.Errorf("a style must have a name"))
+	}
+	if style == nil {
+		return wrap(fmt.Errorf("no style given"))
+	}
+	if strings.EqualFold(name, "Normal") || f.namedStyleIndex(name) > 0 {
+		return wrap(fmt.Errorf("the style already exists"))
+	}
+	s := *style
+	s.NamedStyleIndex = nil
+	f.namedStyles = append(f.namedStyles, &namedStyle{name: name, style: &s})
+	return nil
+}
+
+// NamedStyle returns a copy of the File's named cell style with the
+// given name, ignoring case, or nil if there is no such style.
+func (f *File) NamedStyle(name string) *Style {
+	index := f.namedStyleIndex(name)
+	if index == 0 {
+		return nil
+	}
+	s := *f.namedStyles[index-1].style
+	return &s
+}
+
+// NamedStyles returns the names of the File's named cell styles,
+// including those read from the file it was opened from, in the order
+// they were defined.
+func (f *File) NamedStyles() []string {
+	names := make([]string, 0, len(f.namedStyles))
+	for _, ns := range f.namedStyles {
+		if ns.name != "" {
+			names = append(names, ns.name)
+		}
+	}
+	return names
+}
+
+// namedStyleIndex returns the index in cellStyleXfs of the named cell
+// style with the given name, ignoring case, or zero if the File has no
+// such style.
+func (f *File) namedStyleIndex(name string) int {
+	for i, ns := range f.namedStyles {
+		if ns.name != "" && strings.EqualFold(ns.name, name) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// makeNamedStyles adds the File's named cell styles to the cellStyleXfs
+// and cellStyles of its freshly reset style sheet.
+func (f *File) makeNamedStyles() {
+	if len(f.namedStyles) == 0 {
+		return
+	}
+	styles := f.styles
+	cellStyles := &xlsxCellStyles{}
+	if styles.CellStyles != nil {
+		// Keep the name that the file gave to the Normal style
+		for _, cs := range styles.CellStyles.CellStyle {
+			if cs.XfId == 0 {
+				cellStyles.CellStyle = append(cellStyles.CellStyle, cs)
+			}
+		}
+	}
+	if len(cellStyles.CellStyle) == 0 {
+		normal := 0
+		cellStyles.CellStyle = append(cellStyles.CellStyle, xlsxCellStyle{Name: "Normal", XfId: 0, BuiltInId: &normal})
+	}
+	for i, ns := range f.namedStyles {
+		xf := makeXfForXLSX(ns.style, 0, styles)
+		xf.XfId = nil
+		styles.CellStyleXfs.addXf(xf)
+		if ns.name == "" {
+			continue
+		}
+		cellStyles.CellStyle = append(cellStyles.CellStyle, xlsxCellStyle{
+			Name:          ns.name,
+			XfId:          i + 1,
+			BuiltInId:     ns.builtInId,
+			CustomBuiltIn: ns.customBuiltIn,
+			Hidden:        ns.hidden,
+		})
+	}
+	cellStyles.Count = len(cellStyles.CellStyle)
+	styles.CellStyles = cellStyles
+}
+
+// readNamedStyles returns the named cell styles defined by the style
+// sheet, with one for every xf in cellStyleXfs after the Normal style's
+// so that the indices of the xfs are kept when the File is written.
+func (styles *xlsxStyleSheet) readNamedStyles() []*namedStyle {
+	if styles.CellStyleXfs == nil || len(styles.CellStyleXfs.Xf) < 2 {
+		return nil
+	}
+	namedStyles := make([]*namedStyle, len(styles.CellStyleXfs.Xf)-1)
+	for i, xf := range styles.CellStyleXfs.Xf[1:] {
+		style := &Style{}
+		styles.populateStyleFromXf(style, xf)
+		namedStyles[i] = &namedStyle{style: style}
+	}
+	if styles.CellStyles == nil {
+		return namedStyles
+	}
+	for _, cs := range styles.CellStyles.CellStyle {
+		if cs.XfId < 1 || cs.XfId > len(namedStyles) || namedStyles[cs.XfId-1].name != "" {
+			continue
+		}
+		ns := namedStyles[cs.XfId-1]
+		ns.name = cs.Name
+		ns.builtInId = cs.BuiltInId
+		ns.customBuiltIn = cs.CustomBuiltIn
+		ns.hidden = cs.Hidden
+	}
+	return namedStyles
+}
+
+// SetNamedStyle gives the Cell the named cell style with the given
+// name, ignoring case, which must have been defined by the Cell's File.
+func (c *Cell) SetNamedStyle(name string) error {
+	if c.Row == nil || c.Row.Sheet == nil || c.Row.Sheet.File == nil {
+		return fmt.Errorf("SetNamedStyle(%q): the cell doesn't belong to a File", name)
+	}
+	f := c.Row.Sheet.File
+	index := f.namedStyleIndex(name)
+	if index == 0 {
+		return fmt.Errorf("SetNamedStyle(%q): no style named %q", name, name)
+	}
+	style := *f.namedStyles[index-1].style
+	style.NamedStyleIndex = &index
+	c.SetStyle(&style)
+	return nil
+}
+
+// NamedStyle returns the name of the named cell style that the Cell's
+// style is based on, or an empty string if it isn't based on one.
+func (c *Cell) NamedStyle() string {
+	if c.style == nil || c.style.NamedStyleIndex == nil || c.Row == nil || c.Row.Sheet == nil || c.Row.Sheet.File == nil {
+		return ""
+	}
+	index := *c.style.NamedStyleIndex
+	namedStyles := c.Row.Sheet.File.namedStyles
+	if index < 1 || index > len(namedStyles) {
+		return ""
+	}
+	return namedStyles[index-1].name
+}
@@ -0,0 +1,144 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestNamedStyle(t *testing.T) {
+	c := qt.New(t)
+
+	inputStyle := func() *Style {
+		style := NewStyle()
+		style.Fill = *NewFill(Solid_Cell_Fill, "FFFFCC99", "FFFFFFFF")
+		style.Font.Color = "FF3F3F76"
+		style.ApplyFill = true
+		style.ApplyFont = true
+		return style
+	}
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.AddNamedStyle("Input", inputStyle()), qt.IsNil)
+		heading := NewStyle()
+		heading.Font.Bold = true
+		heading.ApplyFont = true
+		c.Assert(f.AddNamedStyle("Corporate Heading", heading), qt.IsNil)
+		c.Assert(f.NamedStyles(), qt.DeepEquals, []string{"Input", "Corporate Heading"})
+
+		cell, err := sheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		cell.SetString("Title")
+		c.Assert(cell.SetNamedStyle("corporate heading"), qt.IsNil)
+		c.Assert(cell.NamedStyle(), qt.Equals, "Corporate Heading")
+		c.Assert(cell.GetStyle().Font.Bold, qt.IsTrue)
+		cell, err = sheet.Cell(1, 0)
+		c.Assert(err, qt.IsNil)
+		cell.SetInt(42)
+		c.Assert(cell.SetNamedStyle("Input"), qt.IsNil)
+
+		parts, b := writeFileParts(c, f)
+		xStyles := parts["xl/styles.xml"]
+		c.Assert(xStyles, qt.Contains, `<cellStyleXfs count="3">`)
+		c.Assert(xStyles, qt.Contains, `<cellStyles count="3"><cellStyle builtInId="0" name="Normal" xfId="0"></cellStyle><cellStyle name="Input" xfId="1"></cellStyle><cellStyle name="Corporate Heading" xfId="2"></cellStyle></cellStyles>`)
+		c.Assert(xStyles, qt.Contains, `xfId="2">`)
+
+		f, err = OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.NamedStyles(), qt.DeepEquals, []string{"Input", "Corporate Heading"})
+		input := f.NamedStyle("Input")
+		c.Assert(input.Fill.FgColor, qt.Equals, "FFFFCC99")
+		c.Assert(input.Font.Color, qt.Equals, "FF3F3F76")
+		c.Assert(f.NamedStyle("Corporate Heading").Font.Bold, qt.IsTrue)
+		sheet = f.Sheets[0]
+		cell, err = sheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.NamedStyle(), qt.Equals, "Corporate Heading")
+		cell, err = sheet.Cell(1, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.NamedStyle(), qt.Equals, "Input")
+		c.Assert(cell.GetStyle().Fill.FgColor, qt.Equals, "FFFFCC99")
+
+		// Writing the File again keeps the named styles where they were
+		parts, _ = writeFileParts(c, f)
+		c.Assert(parts["xl/styles.xml"], qt.Equals, xStyles)
+	})
+
+	c.Run("ReadBuiltIn", func(c *qt.C) {
+		f := NewFile()
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.AddNamedStyle("Heading 1", inputStyle()), qt.IsNil)
+		cell, err := sheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		cell.SetString("Title")
+		c.Assert(cell.SetNamedStyle("Heading 1"), qt.IsNil)
+		_, b := writeFileParts(c, f)
+
+		// Make the style Excel's own Heading 1, as Excel would write it
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		c.Assert(err, qt.IsNil)
+		var out bytes.Buffer
+		zw := zip.NewWriter(&out)
+		for _, zf := range zr.File {
+			r, err := zf.Open()
+			c.Assert(err, qt.IsNil)
+			content, err := ioutil.ReadAll(r)
+			c.Assert(err, qt.IsNil)
+			r.Close()
+			if zf.Name == "xl/styles.xml" {
+				content = []byte(strings.Replace(string(content), `<cellStyle name="Heading 1"`, `<cellStyle builtInId="16" name="Heading 1"`, 1))
+			}
+			w, err := zw.Create(zf.Name)
+			c.Assert(err, qt.IsNil)
+			_, err = w.Write(content)
+			c.Assert(err, qt.IsNil)
+		}
+		c.Assert(zw.Close(), qt.IsNil)
+
+		f, err = OpenBinary(out.Bytes())
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.NamedStyles(), qt.DeepEquals, []string{"Heading 1"})
+		parts, _ := writeFileParts(c, f)
+		c.Assert(parts["xl/styles.xml"], qt.Contains, `<cellStyle builtInId="16" name="Heading 1" xfId="1">`)
+	})
+
+	c.Run("NoNamedStyles", func(c *qt.C) {
+		f := NewFile()
+		_, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		parts, _ := writeFileParts(c, f)
+		c.Assert(parts["xl/styles.xml"], qt.Not(qt.Contains), "cellStyles ")
+		c.Assert(f.NamedStyles(), qt.HasLen, 0)
+		c.Assert(f.NamedStyle("Input"), qt.IsNil)
+	})
+
+	c.Run("Errors", func(c *qt.C) {
+		f := NewFile()
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.AddNamedStyle("Input", inputStyle()), qt.IsNil)
+		c.Assert(f.AddNamedStyle("INPUT", NewStyle()), qt.ErrorMatches, `AddNamedStyle\("INPUT"\): the style already exists`)
+		c.Assert(f.AddNamedStyle("Normal", NewStyle()), qt.ErrorMatches, `AddNamedStyle\("Normal"\): the style already exists`)
+		c.Assert(f.AddNamedStyle(" ", NewStyle()), qt.ErrorMatches, `AddNamedStyle\(" "\): a style must have a name`)
+		c.Assert(f.AddNamedStyle("Output", nil), qt.ErrorMatches, `AddNamedStyle\("Output"\): no style given`)
+
+		cell, err := sheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.SetNamedStyle("Output"), qt.ErrorMatches, `SetNamedStyle\("Output"\): no style named "Output"`)
+		c.Assert(cell.NamedStyle(), qt.Equals, "")
+
+		sheet, err = NewSheet("Loose")
+		c.Assert(err, qt.IsNil)
+		cell, err = sheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.SetNamedStyle("Input"), qt.ErrorMatches, `SetNamedStyle\("Input"\): the cell doesn't belong to a File`)
+	})
+}
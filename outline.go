@@ -0,0 +1,132 @@
+package xlsx
+
+import "fmt"
+
+// ExcelMaxOutlineLevel is the deepest that Excel allows groups of rows
+// or columns to be nested.
+const ExcelMaxOutlineLevel = 7
+
+// GroupRows groups the rows from the zero based index from to the
+// index to, inclusive, nesting them one outline level deeper.  When
+// collapsed is true the rows are hidden, and the summary row of the
+// group, which is the row after it or, when the Sheet places summaries
+// above, the row before it, is marked as collapsed.
+func (s *Sheet) GroupRows(from, to int, collapsed bool) error {
+	s.mustBeOpen()
+	wrap := func(err error) error {
+		return fmt.Errorf("GroupRows(%d, %d): %w", from, to, err)
+	}
+	if from < 0 || to < from || to > Excel2006MaxRowIndex {
+		return wrap(fmt.Errorf("invalid range of rows"))
+	}
+	for i := from; i <= to; i++ {
+		row, err := s.Row(i)
+		if err != nil {
+			return wrap(err)
+		}
+		if row.outlineLevel >= ExcelMaxOutlineLevel {
+			return wrap(fmt.Errorf("row %d is already grouped %d levels deep", i+1, ExcelMaxOutlineLevel))
+		}
+	}
+	for i := from; i <= to; i++ {
+		row, err := s.Row(i)
+		if err != nil {
+			return wrap(err)
+		}
+		row.SetOutlineLevel(row.outlineLevel + 1)
+		if collapsed {
+			row.Hidden = true
+		}
+	}
+	summary := to + 1
+	if s.summaryAbove {
+		summary = from - 1
+	}
+	if collapsed && summary >= 0 && summary <= Excel2006MaxRowIndex {
+		row, err := s.Row(summary)
+		if err != nil {
+			return wrap(err)
+		}
+		row.cellStoreRow.Updatable()
+		row.Collapsed = true
+		row.isCustom = true
+	}
+	return nil
+}
+
+// GroupCols groups the columns from the zero based index from to the
+// index to, inclusive, nesting them one outline level deeper.  When
+// collapsed is true the columns are hidden, and the summary column of
+// the group, which is the column after it or, when the Sheet places
+// summaries to the left, the column before it, is marked as collapsed.
+func (s *Sheet) GroupCols(from, to int, collapsed bool) error {
+	s.mustBeOpen()
+	wrap := func(err error) error {
+		return fmt.Errorf("GroupCols(%d, %d): %w", from, to, err)
+	}
+	if from < 0 || to < from || to > Excel2006MaxColIndex {
+		return wrap(fmt.Errorf("invalid range of columns"))
+	}
+	var err error
+	s.Cols.ForEach(func(_ int, col *Col) {
+		if err == nil && col.Max >= from+1 && col.Min <= to+1 && col.OutlineLevel != nil && *col.OutlineLevel >= ExcelMaxOutlineLevel {
+			err = fmt.Errorf("column %s is already grouped %d levels deep", ColIndexToLetters(maxInt(col.Min-1, from)), ExcelMaxOutlineLevel)
+		}
+	})
+	if err != nil {
+		return wrap(err)
+	}
+	// The ColStore numbers columns from one
+	s.setCol(from+1, to+1, func(col *Col) {
+		var level uint8 = 1
+		if col.OutlineLevel != nil {
+			level = *col.OutlineLevel + 1
+		}
+		col.SetOutlineLevel(level)
+		if collapsed {
+			hidden := true
+			col.Hidden = &hidden
+		}
+	})
+	summary := to + 1
+	if s.summaryLeft {
+		summary = from - 1
+	}
+	if collapsed && summary >= 0 && summary <= Excel2006MaxColIndex {
+		s.setCol(summary+1, summary+1, func(col *Col) {
+			collapsed := true
+			col.Collapsed = &collapsed
+		})
+	}
+	return nil
+}
+
+// SetOutlineSummary sets where the summary rows and columns of the
+// Sheet's groups are: below the rows of the group or above them, and
+// to the right of the columns of the group or to their left.  By
+// default they're below and to the right.  Set this before grouping
+// rows or columns with the summary before them.
+func (s *Sheet) SetOutlineSummary(below, right bool) {
+	s.summaryAbove = !below
+	s.summaryLeft = !right
+}
+
+// OutlineSummary returns whether the summary rows of the Sheet's
+// groups are below them, and whether the summary columns are to their
+// right.
+func (s *Sheet) OutlineSummary() (below, right bool) {
+	return !s.summaryAbove, !s.summaryLeft
+}
+
+// makeOutlinePr records where the summaries of the Sheet's groups are,
+// when they're not where Excel expects them by default.
+func (s *Sheet) makeOutlinePr(worksheet *xlsxWorksheet) {
+	if !s.summaryAbove && !s.summaryLeft {
+		return
+	}
+	below, right := s.OutlineSummary()
+	worksheet.SheetPr.OutlinePr = &xlsxOutlinePr{
+		SummaryBelow: &below,
+		SummaryRight: &right,
+	}
+}
@@ -0,0 +1,140 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestOutline(t *testing.T) {
+	c := qt.New(t)
+
+	type rowState struct {
+		Level     uint8
+		Hidden    bool
+		Collapsed bool
+	}
+	rowStates := func(c *qt.C, sheet *Sheet, n int) []rowState {
+		states := make([]rowState, n)
+		for i := range states {
+			row, err := sheet.Row(i)
+			c.Assert(err, qt.IsNil)
+			states[i] = rowState{row.GetOutlineLevel(), row.Hidden, row.Collapsed}
+		}
+		return states
+	}
+
+	csRunO(c, "GroupRows", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		for i := 0; i < 7; i++ {
+			cell, err := sheet.Cell(i, 0)
+			c.Assert(err, qt.IsNil)
+			cell.SetInt(i)
+		}
+		c.Assert(sheet.GroupRows(1, 4, false), qt.IsNil)
+		c.Assert(sheet.GroupRows(2, 3, true), qt.IsNil)
+		// The summary row of this group has no cells
+		c.Assert(sheet.GroupRows(6, 6, true), qt.IsNil)
+		want := []rowState{
+			{0, false, false},
+			{1, false, false},
+			{2, true, false},
+			{2, true, false},
+			{1, false, true},
+			{0, false, false},
+			{1, true, false},
+			{0, false, true},
+		}
+		c.Assert(rowStates(c, sheet, 8), qt.DeepEquals, want)
+		c.Assert(sheet.SheetFormat.OutlineLevelRow, qt.Equals, uint8(2))
+
+		parts, b := writeFileParts(c, f)
+		xSheet := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(xSheet, qt.Contains, `<row r="3" hidden="true" outlineLevel="2">`)
+		c.Assert(xSheet, qt.Contains, `<row r="5" outlineLevel="1" collapsed="true">`)
+		c.Assert(xSheet, qt.Contains, `<row r="8" collapsed="true"/>`)
+		c.Assert(xSheet, qt.Contains, `outlineLevelRow="2"`)
+
+		f, err = OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		c.Assert(rowStates(c, f.Sheets[0], 8), qt.DeepEquals, want)
+	})
+
+	c.Run("GroupCols", func(c *qt.C) {
+		sheet, err := NewSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		sheet.SetColWidth(2, 3, 20)
+		c.Assert(sheet.GroupCols(1, 3, false), qt.IsNil)
+		c.Assert(sheet.GroupCols(2, 2, true), qt.IsNil)
+
+		c.Assert(sheet.Col(0), qt.IsNil)
+		c.Assert(*sheet.Col(1).OutlineLevel, qt.Equals, uint8(1))
+		c.Assert(sheet.Col(1).Hidden, qt.IsNil)
+		c.Assert(*sheet.Col(1).Width, qt.Equals, 20.0)
+		c.Assert(*sheet.Col(2).OutlineLevel, qt.Equals, uint8(2))
+		c.Assert(*sheet.Col(2).Hidden, qt.IsTrue)
+		c.Assert(*sheet.Col(2).Width, qt.Equals, 20.0)
+		c.Assert(*sheet.Col(3).OutlineLevel, qt.Equals, uint8(1))
+		c.Assert(*sheet.Col(3).Collapsed, qt.IsTrue)
+		c.Assert(sheet.Col(3).Hidden, qt.IsNil)
+		c.Assert(sheet.Col(3).Width, qt.IsNil)
+	})
+
+	csRunO(c, "SummaryBefore", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		sheet.SetOutlineSummary(false, false)
+		below, right := sheet.OutlineSummary()
+		c.Assert(below, qt.IsFalse)
+		c.Assert(right, qt.IsFalse)
+		for i := 0; i < 4; i++ {
+			cell, err := sheet.Cell(i, i)
+			c.Assert(err, qt.IsNil)
+			cell.SetInt(i)
+		}
+		c.Assert(sheet.GroupRows(2, 3, true), qt.IsNil)
+		c.Assert(sheet.GroupCols(2, 3, true), qt.IsNil)
+		c.Assert(*sheet.Col(1).Collapsed, qt.IsTrue)
+
+		parts, b := writeFileParts(c, f)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `<outlinePr summaryBelow="false" summaryRight="false"/>`)
+
+		f, err = OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		sheet = f.Sheets[0]
+		below, right = sheet.OutlineSummary()
+		c.Assert(below, qt.IsFalse)
+		c.Assert(right, qt.IsFalse)
+		c.Assert(rowStates(c, sheet, 4), qt.DeepEquals, []rowState{
+			{0, false, false},
+			{0, false, true},
+			{1, true, false},
+			{1, true, false},
+		})
+	})
+
+	c.Run("DefaultSummaryNotWritten", func(c *qt.C) {
+		f := NewFile()
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.GroupRows(0, 1, false), qt.IsNil)
+		parts, _ := writeFileParts(c, f)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Not(qt.Contains), "outlinePr")
+	})
+
+	c.Run("Errors", func(c *qt.C) {
+		sheet, err := NewSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.GroupRows(3, 2, false), qt.ErrorMatches, `GroupRows\(3, 2\): invalid range of rows`)
+		c.Assert(sheet.GroupCols(-1, 2, false), qt.ErrorMatches, `GroupCols\(-1, 2\): invalid range of columns`)
+		for i := 0; i < ExcelMaxOutlineLevel; i++ {
+			c.Assert(sheet.GroupRows(0, 2, false), qt.IsNil)
+			c.Assert(sheet.GroupCols(0, 2, false), qt.IsNil)
+		}
+		c.Assert(sheet.GroupRows(1, 1, false), qt.ErrorMatches, `GroupRows\(1, 1\): row 2 is already grouped 7 levels deep`)
+		c.Assert(sheet.GroupCols(1, 4, false), qt.ErrorMatches, `GroupCols\(1, 4\): column B is already grouped 7 levels deep`)
+	})
+}
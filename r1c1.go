@@ -0,0 +1,120 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToR1C1 returns a reference in A1 notation in R1C1 notation, as it
+// would be written in a formula in the cell at the given zero-based
+// column and row.  The relative parts of the reference become offsets
+// from that cell.  A reference already in R1C1 notation is returned
+// unchanged.
+func (r Reference) ToR1C1(col, row int) Reference {
+	if r.R1C1 {
+		return r
+	}
+	r, _ = r.offset(-col, -row)
+	r.R1C1 = true
+	if r.IsRange && (r.First.NoCol || r.First.NoRow) && r.First == r.Last {
+		// A single row or column needs no range in R1C1 notation,
+		// e.g. R[1] rather than R[1]:R[1]
+		r.IsRange = false
+	}
+	return r
+}
+
+// ToA1 returns a reference in R1C1 notation in A1 notation, resolving
+// its offsets from the cell at the given zero-based column and row.  A
+// reference already in A1 notation is returned unchanged.
+func (r Reference) ToA1(col, row int) (Reference, error) {
+	if !r.R1C1 {
+		return r, nil
+	}
+	a1, ok := r.offset(col, row)
+	if !ok {
+		return r, fmt.Errorf("reference %q from %s is outside the sheet", r.String(), GetCellIDStringFromCoords(col, row))
+	}
+	a1.R1C1 = false
+	if !a1.IsRange && (a1.First.NoCol || a1.First.NoRow) {
+		// A whole row or column must be written as a range in
+		// A1 notation, e.g. 3:3
+		a1.IsRange = true
+		a1.Last = a1.First
+	}
+	return a1, nil
+}
+
+// A1ToR1C1 converts a cell or range reference, such as "B2",
+// "$A$1:C3" or "Sheet2!A:A", from A1 to R1C1 notation, as it would be
+// written in a formula in the cell at the given zero-based column and
+// row.
+func A1ToR1C1(ref string, col, row int) (string, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return "", fmt.Errorf("A1ToR1C1(%q): %w", ref, err)
+	}
+	return r.ToR1C1(col, row).String(), nil
+}
+
+// R1C1ToA1 converts a cell or range reference, such as "R[-1]C",
+// "R1C1:R3C[2]" or "Sheet2!C1", from R1C1 to A1 notation, resolving
+// its offsets from the cell at the given zero-based column and row.
+func R1C1ToA1(ref string, col, row int) (string, error) {
+	r, err := ParseReferenceR1C1(ref)
+	if err == nil {
+		r, err = r.ToA1(col, row)
+	}
+	if err != nil {
+		return "", fmt.Errorf("R1C1ToA1(%q): %w", ref, err)
+	}
+	return r.String(), nil
+}
+
+// FormulaA1ToR1C1 converts every reference in a formula from A1 to
+// R1C1 notation, as the formula would be written in the cell at the
+// given zero-based column and row.  Everything else in the formula is
+// kept as it was.
+func FormulaA1ToR1C1(formula string, col, row int) (string, error) {
+	tokens, err := TokenizeFormula(formula)
+	if err != nil {
+		return "", fmt.Errorf("FormulaA1ToR1C1(%q): %w", formula, err)
+	}
+	var b strings.Builder
+	for _, tok := range tokens {
+		if tok.Type == FormulaTokenReference {
+			if ref, err := ParseReference(tok.Value); err == nil {
+				b.WriteString(ref.ToR1C1(col, row).String())
+				continue
+			}
+		}
+		b.WriteString(tok.Value)
+	}
+	return b.String(), nil
+}
+
+// FormulaR1C1ToA1 converts every reference in a formula from R1C1 to
+// A1 notation, resolving their offsets from the cell at the given
+// zero-based column and row.  References that would lie outside the
+// sheet become #REF!, as they do in Excel.
+func FormulaR1C1ToA1(formula string, col, row int) (string, error) {
+	tokens, err := TokenizeFormulaR1C1(formula)
+	if err != nil {
+		return "", fmt.Errorf("FormulaR1C1ToA1(%q): %w", formula, err)
+	}
+	var b strings.Builder
+	for _, tok := range tokens {
+		if tok.Type == FormulaTokenReference {
+			if ref, err := ParseReferenceR1C1(tok.Value); err == nil {
+				if a1, err := ref.ToA1(col, row); err == nil {
+					b.WriteString(a1.String())
+				} else {
+					b.WriteString(formatRefPrefix(ref.Workbook, ref.Sheet, ref.LastSheet) + "#REF!")
+				}
+				continue
+			}
+		}
+		b.WriteString(tok.Value)
+	}
+	return b.String(), nil
+}
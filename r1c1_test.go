@@ -0,0 +1,87 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestR1C1(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("References", func(c *qt.C) {
+		// The references are seen from a formula in C3
+		cases := []struct{ a1, r1c1 string }{
+			{"C3", "RC"},
+			{"B2", "R[-1]C[-1]"},
+			{"$A$1", "R1C1"},
+			{"$A4", "R[1]C1"},
+			{"D$1", "R1C[1]"},
+			{"A1:$E$5", "R[-2]C[-2]:R5C5"},
+			{"5:5", "R[2]"},
+			{"$1:$3", "R1:R3"},
+			{"A:B", "C[-2]:C[-1]"},
+			{"'My Sheet'!C3", "'My Sheet'!RC"},
+		}
+		for _, tc := range cases {
+			r1c1, err := A1ToR1C1(tc.a1, 2, 2)
+			c.Assert(err, qt.IsNil)
+			c.Assert(r1c1, qt.Equals, tc.r1c1, qt.Commentf(tc.a1))
+			a1, err := R1C1ToA1(tc.r1c1, 2, 2)
+			c.Assert(err, qt.IsNil)
+			c.Assert(a1, qt.Equals, tc.a1, qt.Commentf(tc.r1c1))
+		}
+		// A single column written as a range is the same column
+		a1, err := R1C1ToA1("C[1]:C[1]", 2, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(a1, qt.Equals, "D:D")
+
+		_, err = R1C1ToA1("R[-3]C", 2, 2)
+		c.Assert(err, qt.ErrorMatches, `R1C1ToA1\("R\[-3\]C"\): reference "R\[-3\]C" from C3 is outside the sheet`)
+		_, err = A1ToR1C1("A", 0, 0)
+		c.Assert(err, qt.Not(qt.IsNil))
+	})
+
+	c.Run("Formulas", func(c *qt.C) {
+		r1c1, err := FormulaA1ToR1C1(`SUM(A1:A9)+$B$1*Sheet2!C10&" A1"`, 0, 9)
+		c.Assert(err, qt.IsNil)
+		c.Assert(r1c1, qt.Equals, `SUM(R[-9]C:R[-1]C)+R1C2*Sheet2!RC[2]&" A1"`)
+
+		a1, err := FormulaR1C1ToA1("SUM(R[-9]C:R[-1]C)+R1C2*Rate", 0, 9)
+		c.Assert(err, qt.IsNil)
+		c.Assert(a1, qt.Equals, "SUM(A1:A9)+$B$1*Rate")
+
+		// ROUND is a function, not a row
+		a1, err = FormulaR1C1ToA1("ROUND(R[-1]C,2)", 1, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(a1, qt.Equals, "ROUND(B1,2)")
+
+		a1, err = FormulaR1C1ToA1("R[-1]C+Sheet2!R[-1]C", 0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(a1, qt.Equals, "#REF!+Sheet2!#REF!")
+
+		_, err = FormulaA1ToR1C1(`"unterminated`, 0, 0)
+		c.Assert(err, qt.Not(qt.IsNil))
+	})
+
+	c.Run("RefMode", func(c *qt.C) {
+		f := NewFile(R1C1ReferenceStyle())
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		sheet.AddRow().AddCell().SetFormula("B1")
+		parts, b := writeFileParts(c, f)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `refMode="R1C1"`)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, "<f>B1</f>")
+
+		f, err = OpenBinary(b)
+		c.Assert(err, qt.IsNil)
+		parts, _ = writeFileParts(c, f)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `refMode="R1C1"`)
+
+		f = NewFile()
+		_, err = f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		parts, _ = writeFileParts(c, f)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `refMode="A1"`)
+	})
+}
@@ -0,0 +1,458 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Range is a block of cells on a Sheet, made up of one or more
+// rectangular areas, such as "B2:F20" or "A1:B2 D4:E5".  Whole rows
+// and columns, such as "A:C", only extend as far as the cells that
+// the Sheet holds when the Range is read or modified.
+type Range struct {
+	Sheet *Sheet
+	Areas []CellRange
+}
+
+// NewRange returns the Range made up of the given areas of a Sheet.
+func NewRange(sheet *Sheet, areas ...CellRange) *Range {
+	return &Range{Sheet: sheet, Areas: areas}
+}
+
+// Range returns the Range of cells on the Sheet given by ref, such as
+// "B2:F20".  A Range of several areas is given as a list separated by
+// spaces or commas, such as "A1:B2 D4:E5".
+func (s *Sheet) Range(ref string) (*Range, error) {
+	fields := strings.FieldsFunc(ref, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("Range(%q): empty reference", ref)
+	}
+	r := &Range{Sheet: s}
+	for _, field := range fields {
+		area, err := ParseCellRange(field)
+		if err != nil {
+			return nil, fmt.Errorf("Range(%q): %w", ref, err)
+		}
+		r.Areas = append(r.Areas, area)
+	}
+	return r, nil
+}
+
+// String returns the areas of the Range in A1 notation, separated by
+// spaces, e.g. "A1:B2 D4:E5".
+func (r *Range) String() string {
+	refs := make([]string, len(r.Areas))
+	for i, area := range r.Areas {
+		refs[i] = area.String()
+	}
+	return strings.Join(refs, " ")
+}
+
+// Bounds returns the smallest CellRange that covers every area of the
+// Range.
+func (r *Range) Bounds() CellRange {
+	if len(r.Areas) == 0 {
+		return CellRange{}
+	}
+	b := r.Areas[0]
+	for _, area := range r.Areas[1:] {
+		b = NewCellRange(minInt(b.MinCol, area.MinCol), minInt(b.MinRow, area.MinRow), maxInt(b.MaxCol, area.MaxCol), maxInt(b.MaxRow, area.MaxRow))
+	}
+	return b
+}
+
+// Contains returns true if the cell at the given zero based
+// coordinates lies within any area of the Range.
+func (r *Range) Contains(col, row int) bool {
+	for _, area := range r.Areas {
+		if area.Contains(col, row) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersect returns the Range of the cells that lie within both
+// Ranges.  If the Ranges are on different sheets, or don't overlap,
+// the Range returned has no areas.
+func (r *Range) Intersect(other *Range) *Range {
+	result := &Range{Sheet: r.Sheet}
+	if other.Sheet != r.Sheet {
+		return result
+	}
+	for _, a := range r.Areas {
+		for _, b := range other.Areas {
+			if area, ok := a.Intersect(b); ok {
+				result.Areas = append(result.Areas, area)
+			}
+		}
+	}
+	return result
+}
+
+// Union returns the Range of the cells that lie within either Range.
+// Areas of the other Range that are already covered by an area of
+// this one are left out.  It returns an error if the Ranges are on
+// different sheets.
+func (r *Range) Union(other *Range) (*Range, error) {
+	if other.Sheet != r.Sheet {
+		return nil, fmt.Errorf("Union(%q, %q): the ranges are on different sheets", r.String(), other.String())
+	}
+	result := &Range{Sheet: r.Sheet, Areas: append([]CellRange{}, r.Areas...)}
+	for _, b := range other.Areas {
+		covered := false
+		for _, a := range r.Areas {
+			if area, ok := a.Intersect(b); ok && area == b {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result.Areas = append(result.Areas, b)
+		}
+	}
+	return result, nil
+}
+
+// clamp limits whole rows and columns within an area to the extent of
+// the Sheet.  The area returned is empty, with MaxCol < MinCol or
+// MaxRow < MinRow, if it lies beyond the Sheet's cells.
+func (r *Range) clamp(area CellRange) CellRange {
+	if area.IsWholeCols() && area.MaxRow >= r.Sheet.MaxRow {
+		area.MaxRow = r.Sheet.MaxRow - 1
+	}
+	if area.IsWholeRows() && area.MaxCol >= r.Sheet.MaxCol {
+		area.MaxCol = r.Sheet.MaxCol - 1
+	}
+	return area
+}
+
+// single returns the only area of the Range, or an error naming the
+// operation if the Range has several areas.
+func (r *Range) single(op string) (CellRange, error) {
+	if len(r.Areas) != 1 {
+		return CellRange{}, fmt.Errorf("%s(%q): the range must have a single area", op, r.String())
+	}
+	return r.Areas[0], nil
+}
+
+// updateCells calls fn for every cell within the area, creating cells
+// as needed, and stores the cells once fn has changed them.
+func (r *Range) updateCells(area CellRange, fn func(cell *Cell) error) error {
+	for y := area.MinRow; y <= area.MaxRow; y++ {
+		row, err := r.Sheet.Row(y)
+		if err != nil {
+			return err
+		}
+		for x := area.MinCol; x <= area.MaxCol; x++ {
+			cell := row.GetCell(x)
+			cell.Row = row
+			cell.updatable()
+			if err := fn(cell); err != nil {
+				return err
+			}
+			// Pushing the cell back makes stores that only hold
+			// the current cell in memory, such as DiskV, persist it
+			row.PushCell(cell)
+		}
+		if area.MaxCol >= r.Sheet.MaxCol {
+			r.Sheet.MaxCol = area.MaxCol + 1
+		}
+	}
+	return nil
+}
+
+// forEachExistingCell calls fn for every cell within the area that
+// the Sheet already holds, without creating any.
+func (r *Range) forEachExistingCell(area CellRange, fn func(cell *Cell) error) error {
+	return r.Sheet.ForEachRow(func(row *Row) error {
+		if row.num < area.MinRow || row.num > area.MaxRow {
+			return nil
+		}
+		return row.ForEachCell(func(cell *Cell) error {
+			if cell.num < area.MinCol || cell.num > area.MaxCol {
+				return nil
+			}
+			cell.Row = row
+			return fn(cell)
+		}, SkipEmptyCells)
+	}, SkipEmptyRows)
+}
+
+// ForEachCell calls fn for every cell of the Range, area by area and
+// row by row, creating any cells that don't yet exist.  Changes that
+// fn makes to the cells are kept, whatever the cell store.
+func (r *Range) ForEachCell(fn CellVisitorFunc) error {
+	for _, area := range r.Areas {
+		if err := r.updateCells(r.clamp(area), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetValues sets the values of the cells of a single area Range, from
+// its top left cell, to the given rows of values.  Each value is set
+// as Cell.SetValue would set it.  Cells of the Range without a value
+// are left as they are.  It returns an error if the values don't fit
+// within the Range.
+func (r *Range) SetValues(values [][]interface{}) error {
+	area, err := r.single("SetValues")
+	if err != nil {
+		return err
+	}
+	if len(values) > area.Height() {
+		return fmt.Errorf("SetValues(%q): %d rows of values don't fit", r.String(), len(values))
+	}
+	for _, row := range values {
+		if len(row) > area.Width() {
+			return fmt.Errorf("SetValues(%q): %d columns of values don't fit", r.String(), len(row))
+		}
+	}
+	for i, row := range values {
+		if len(row) == 0 {
+			continue
+		}
+		target := NewCellRange(area.MinCol, area.MinRow+i, area.MinCol+len(row)-1, area.MinRow+i)
+		err := r.updateCells(target, func(cell *Cell) error {
+			cell.SetValue(row[cell.num-area.MinCol])
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Values returns the formatted values of the cells of a single area
+// Range, row by row, as Cell.FormattedValue returns them.
+func (r *Range) Values() ([][]string, error) {
+	area, err := r.single("Values")
+	if err != nil {
+		return nil, err
+	}
+	area = r.clamp(area)
+	if area.Width() <= 0 || area.Height() <= 0 {
+		return [][]string{}, nil
+	}
+	values := make([][]string, area.Height())
+	for i := range values {
+		values[i] = make([]string, area.Width())
+	}
+	err = r.forEachExistingCell(area, func(cell *Cell) error {
+		value, err := cell.FormattedValue()
+		if err != nil {
+			return err
+		}
+		values[cell.Row.num-area.MinRow][cell.num-area.MinCol] = value
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Values(%q): %w", r.String(), err)
+	}
+	return values, nil
+}
+
+// SetStyle sets the Style of every cell of the Range.
+func (r *Range) SetStyle(style *Style) error {
+	return r.ForEachCell(func(cell *Cell) error {
+		cell.SetStyle(style)
+		return nil
+	})
+}
+
+// Clear removes the values, formulas and rich text of the cells of the
+// Range.  Their styles, number formats, hyperlinks and merges are
+// kept.
+func (r *Range) Clear() error {
+	var cells [][2]int
+	for _, area := range r.Areas {
+		err := r.forEachExistingCell(r.clamp(area), func(cell *Cell) error {
+			cells = append(cells, [2]int{cell.num, cell.Row.num})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, pos := range cells {
+		err := r.updateCells(NewCellRange(pos[0], pos[1], pos[0], pos[1]), func(cell *Cell) error {
+			cell.Value = ""
+			cell.RichText = nil
+			cell.clearFormula()
+			cell.cellType = CellTypeString
+			cell.modified = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyTo copies the cells of a single area Range, with their values,
+// formulas, styles, hyperlinks and merges, to the block of the same
+// size whose top left cell is at the given zero based coordinates of
+// dest, which may be the same Sheet.  The relative references of
+// copied formulas are moved along with them, as they are when cells
+// are copied in Excel.  Named cell styles are dropped when dest
+// belongs to another File, which doesn't define them.
+func (r *Range) CopyTo(dest *Sheet, col, row int) error {
+	return r.transfer("CopyTo", dest, col, row, false)
+}
+
+// MoveTo moves the cells of a single area Range, with their values,
+// formulas, styles, hyperlinks and merges, to the block of the same
+// size whose top left cell is at the given zero based coordinates of
+// dest, which may be the same Sheet.  The cells left behind are
+// emptied.  When dest belongs to the same File, the references to the
+// moved cells in formulas, hyperlink locations, data validations and
+// defined names throughout the File follow them, as they do in Excel,
+// if they lie wholly within the Range.  Other references, including
+// those of the moved formulas, keep pointing at the same cells.  Named
+// cell styles are dropped when dest belongs to another File.
+func (r *Range) MoveTo(dest *Sheet, col, row int) error {
+	return r.transfer("MoveTo", dest, col, row, true)
+}
+
+func (r *Range) transfer(op string, dest *Sheet, col, row int, move bool) error {
+	area, err := r.single(op)
+	if err != nil {
+		return err
+	}
+	area = r.clamp(area)
+	if area.Width() <= 0 || area.Height() <= 0 {
+		return nil
+	}
+	dx, dy := col-area.MinCol, row-area.MinRow
+	if col < 0 || row < 0 || area.MaxCol+dx > Excel2006MaxColIndex || area.MaxRow+dy > Excel2006MaxRowIndex {
+		return fmt.Errorf("%s(%q): the destination %s lies outside the sheet", op, r.String(), GetCellIDStringFromCoords(col, row))
+	}
+	// Take copies of the cells first, as the destination may overlap
+	// the Range
+	var copies []Cell
+	err = r.forEachExistingCell(area, func(cell *Cell) error {
+		copies = append(copies, *cell)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	copyStyle := func(style *Style) *Style { return style }
+	if dest.File != r.Sheet.File {
+		copyStyle = dest.styleCopier(r.Sheet)
+	}
+	transfer := func() error {
+		if move {
+			for _, c := range copies {
+				err := r.updateCells(NewCellRange(c.num, c.Row.num, c.num, c.Row.num), func(cell *Cell) error {
+					*cell = Cell{Row: cell.Row, num: cell.num, modified: true}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		target := &Range{Sheet: dest}
+		for i := range copies {
+			src := &copies[i]
+			x, y := src.num+dx, src.Row.num+dy
+			err := target.updateCells(NewCellRange(x, y, x, y), func(cell *Cell) error {
+				cell.copyContents(src, dx, dy, !move)
+				cell.style = copyStyle(src.style)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !move || dest.File != r.Sheet.File {
+		return transfer()
+	}
+	rm := refMove{sheet: r.Sheet, area: area, dest: dest, dx: dx, dy: dy}
+	for i := range copies {
+		c := &copies[i]
+		c.formula = rm.formula(c.formula, r.Sheet, dest)
+		c.Hyperlink.Location = rm.formula(c.Hyperlink.Location, r.Sheet, dest)
+	}
+	return rm.apply(transfer)
+}
+
+// copyContents makes the cell a copy of src, which has been moved by
+// dx columns and dy rows.  When shift is set, the relative references
+// of its formula are moved too.
+func (c *Cell) copyContents(src *Cell, dx, dy int, shift bool) {
+	c.Value = src.Value
+	c.RichText = append([]RichTextRun(nil), src.RichText...)
+	c.formula = src.formula
+	c.arrayRef = src.arrayRef
+	c.dynamicArray = src.dynamicArray
+	if c.arrayRef != "" {
+		if ref, err := ParseCellRange(c.arrayRef); err == nil {
+			c.arrayRef = NewCellRange(ref.MinCol+dx, ref.MinRow+dy, ref.MaxCol+dx, ref.MaxRow+dy).String()
+		}
+	}
+	if shift && c.formula != "" {
+		c.formula = shiftFormula(c.formula, dx, dy)
+	}
+	c.style = src.style
+	c.NumFmt = src.NumFmt
+	c.parsedNumFmt = nil
+	c.date1904 = src.date1904
+	c.Hidden = src.Hidden
+	c.HMerge = src.HMerge
+	c.VMerge = src.VMerge
+	c.cellType = src.cellType
+	c.Hyperlink = src.Hyperlink
+	if c.Hyperlink.Link != "" && c.Row.Sheet != src.Row.Sheet {
+		c.Row.Sheet.addRelation(RelationshipTypeHyperlink, c.Hyperlink.Link, RelationshipTargetModeExternal)
+	}
+	c.modified = true
+}
+
+// Merge merges the cells of each area of the Range, as
+// Sheet.MergeRange does.
+func (r *Range) Merge() error {
+	for _, area := range r.Areas {
+		area = r.clamp(area)
+		if area.Width() <= 0 || area.Height() <= 0 {
+			continue
+		}
+		if err := r.Sheet.MergeRange(area); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetAutoFilter applies an auto-filter to the single area Range, whose
+// first row holds the headings of the columns to filter.
+func (r *Range) SetAutoFilter() error {
+	area, err := r.single("SetAutoFilter")
+	if err != nil {
+		return err
+	}
+	area = r.clamp(area)
+	if area.Width() <= 0 || area.Height() <= 0 {
+		return fmt.Errorf("SetAutoFilter(%q): the range holds no cells", r.String())
+	}
+	r.Sheet.AutoFilter = &AutoFilter{
+		TopLeftCell:     GetCellIDStringFromCoords(area.MinCol, area.MinRow),
+		BottomRightCell: GetCellIDStringFromCoords(area.MaxCol, area.MaxRow),
+	}
+	return nil
+}
+
+// AddDataValidation applies the data validation to every area of the
+// Range, and adds it to the Sheet.
+func (r *Range) AddDataValidation(dv *xlsxDataValidation) {
+	dv.Sqref = r.String()
+	r.Sheet.AddDataValidation(dv)
+}
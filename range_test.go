@@ -0,0 +1,228 @@
+package xlsx
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRange(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("Areas", func(c *qt.C) {
+		sheet, err := NewSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		r, err := sheet.Range("A1:B2 D4:E5,C3")
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.String(), qt.Equals, "A1:B2 D4:E5 C3")
+		c.Assert(r.Bounds().String(), qt.Equals, "A1:E5")
+		c.Assert(r.Contains(2, 2), qt.IsTrue)
+		c.Assert(r.Contains(2, 1), qt.IsFalse)
+
+		other, err := sheet.Range("B2:D4")
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.Intersect(other).String(), qt.Equals, "B2 D4 C3")
+		u, err := r.Union(other)
+		c.Assert(err, qt.IsNil)
+		c.Assert(u.String(), qt.Equals, "A1:B2 D4:E5 C3 B2:D4")
+		u, err = other.Union(NewRange(sheet, NewCellRange(2, 2, 2, 2)))
+		c.Assert(err, qt.IsNil)
+		c.Assert(u.String(), qt.Equals, "B2:D4")
+
+		sheet2, err := NewSheet("Sheet2")
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.Intersect(NewRange(sheet2, NewCellRange(0, 0, 9, 9))).Areas, qt.HasLen, 0)
+		_, err = r.Union(NewRange(sheet2))
+		c.Assert(err, qt.Not(qt.IsNil))
+
+		for _, bad := range []string{"", " ", "A1:B2 X"} {
+			_, err = sheet.Range(bad)
+			c.Assert(err, qt.Not(qt.IsNil), qt.Commentf(bad))
+		}
+	})
+
+	csRunO(c, "SetValuesAndValues", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		r, err := sheet.Range("B2:D4")
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.SetValues([][]interface{}{
+			{"Name", "Qty", "Price"},
+			{"Apple", 3, 1.5},
+			{"Pear", nil},
+		}), qt.IsNil)
+		c.Assert(r.SetValues([][]interface{}{{1, 2, 3, 4}}), qt.ErrorMatches, `SetValues\("B2:D4"\): 4 columns of values don't fit`)
+
+		values, err := r.Values()
+		c.Assert(err, qt.IsNil)
+		c.Assert(values, qt.DeepEquals, [][]string{
+			{"Name", "Qty", "Price"},
+			{"Apple", "3", "1.5"},
+			{"Pear", "", ""},
+		})
+		cell, err := sheet.Cell(2, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Type(), qt.Equals, CellTypeNumeric)
+
+		// Whole columns extend as far as the sheet's cells
+		cols, err := sheet.Range("B:B")
+		c.Assert(err, qt.IsNil)
+		values, err = cols.Values()
+		c.Assert(err, qt.IsNil)
+		c.Assert(values, qt.DeepEquals, [][]string{{""}, {"Name"}, {"Apple"}, {"Pear"}})
+
+		multi, err := sheet.Range("A1 B2")
+		c.Assert(err, qt.IsNil)
+		_, err = multi.Values()
+		c.Assert(err, qt.ErrorMatches, `Values\("A1 B2"\): the range must have a single area`)
+	})
+
+	csRunO(c, "StyleAndClear", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		r, err := sheet.Range("A1:B2")
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.SetValues([][]interface{}{{1, 2}, {3, 4}}), qt.IsNil)
+		cell, err := sheet.Cell(1, 1)
+		c.Assert(err, qt.IsNil)
+		cell.SetFormula("A1+B1")
+
+		style := NewStyle()
+		style.Font.Bold = true
+		c.Assert(r.SetStyle(style), qt.IsNil)
+		part, err := sheet.Range("B1:B2")
+		c.Assert(err, qt.IsNil)
+		c.Assert(part.Clear(), qt.IsNil)
+
+		values, err := r.Values()
+		c.Assert(err, qt.IsNil)
+		c.Assert(values, qt.DeepEquals, [][]string{{"1", ""}, {"3", ""}})
+		for _, pos := range [][2]int{{0, 0}, {1, 1}} {
+			cell, err := sheet.Cell(pos[1], pos[0])
+			c.Assert(err, qt.IsNil)
+			c.Assert(cell.GetStyle().Font.Bold, qt.IsTrue)
+			c.Assert(cell.Formula(), qt.Equals, "")
+		}
+	})
+
+	csRunO(c, "CopyAndMove", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		sheet2, err := f.AddSheet("Sheet2")
+		c.Assert(err, qt.IsNil)
+		r, err := sheet.Range("A1:B2")
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.SetValues([][]interface{}{{1, 2}, {"x"}}), qt.IsNil)
+		cell, err := sheet.Cell(1, 1)
+		c.Assert(err, qt.IsNil)
+		cell.SetFormula("A1+$B$1")
+
+		c.Assert(r.CopyTo(sheet2, 2, 3), qt.IsNil)
+		copied, err := sheet2.Range("C4:D5")
+		c.Assert(err, qt.IsNil)
+		values, err := copied.Values()
+		c.Assert(err, qt.IsNil)
+		c.Assert(values, qt.DeepEquals, [][]string{{"1", "2"}, {"x", ""}})
+		cell, err = sheet2.Cell(4, 3)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Formula(), qt.Equals, "C4+$B$1")
+
+		// Moving onto an overlapping block
+		c.Assert(r.MoveTo(sheet, 1, 1), qt.IsNil)
+		moved, err := sheet.Range("A1:C3")
+		c.Assert(err, qt.IsNil)
+		values, err = moved.Values()
+		c.Assert(err, qt.IsNil)
+		c.Assert(values, qt.DeepEquals, [][]string{{"", "", ""}, {"", "1", "2"}, {"", "x", ""}})
+		// The references to the moved cells follow them
+		cell, err = sheet.Cell(2, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Formula(), qt.Equals, "B2+$C$2")
+		cell, err = sheet.Cell(1, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Formula(), qt.Equals, "")
+
+		c.Assert(r.CopyTo(sheet, Excel2006MaxColIndex, 0), qt.Not(qt.IsNil))
+	})
+
+	csRunO(c, "MoveRewritesReferences", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		other, err := f.AddSheet("Other")
+		c.Assert(err, qt.IsNil)
+		cellAt := func(sheet *Sheet, row, col int) *Cell {
+			cell, err := sheet.Cell(row, col)
+			c.Assert(err, qt.IsNil)
+			return cell
+		}
+		cellAt(data, 0, 0).SetInt(1)
+		cellAt(data, 0, 2).SetFormula("A1*2")
+		cellAt(data, 1, 0).SetFormula("A1+C5")
+		cellAt(data, 1, 2).SetFormula("SUM(A1:A3)")
+		cellAt(other, 0, 0).SetFormula("SUM(Data!A1:A2)")
+		_, err = f.AddName("Values", "Data!$A$1:$A$2", nil)
+		c.Assert(err, qt.IsNil)
+
+		r, err := data.Range("A1:A2")
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.MoveTo(other, 4, 0), qt.IsNil)
+		c.Assert(cellAt(other, 1, 4).Formula(), qt.Equals, "E1+Data!C5")
+		c.Assert(cellAt(data, 0, 2).Formula(), qt.Equals, "Other!E1*2")
+		// Only part of this range moved
+		c.Assert(cellAt(data, 1, 2).Formula(), qt.Equals, "SUM(A1:A3)")
+		c.Assert(cellAt(other, 0, 0).Formula(), qt.Equals, "SUM(Other!E1:E2)")
+		c.Assert(f.Name("Values").Data, qt.Equals, "Other!$E$1:$E$2")
+	})
+
+	csRunO(c, "CopyToAnotherFile", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.AddNamedStyle("Good", NewStyle()), qt.IsNil)
+		cell, err := sheet.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		cell.SetString("x")
+		c.Assert(cell.SetNamedStyle("Good"), qt.IsNil)
+		c.Assert(cell.GetStyle().NamedStyleIndex, qt.Not(qt.IsNil))
+
+		f2 := NewFile(option)
+		dest, err := f2.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		r, err := sheet.Range("A1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.CopyTo(dest, 1, 1), qt.IsNil)
+		copied, err := dest.Cell(1, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(copied.Value, qt.Equals, "x")
+		c.Assert(copied.GetStyle().NamedStyleIndex, qt.IsNil)
+		c.Assert(copied.GetStyle() == cell.GetStyle(), qt.IsFalse)
+		writeFileParts(c, f2)
+	})
+
+	csRunO(c, "MergeFilterAndValidation", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		r, err := sheet.Range("A1:C1 A3:A4")
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.Merge(), qt.IsNil)
+		dv := NewDataValidation(0, 0, 0, 0, true)
+		c.Assert(dv.SetDropList([]string{"a", "b"}), qt.IsNil)
+		r.AddDataValidation(dv)
+		c.Assert(r.SetAutoFilter(), qt.Not(qt.IsNil))
+		table, err := sheet.Range("A5:C9")
+		c.Assert(err, qt.IsNil)
+		c.Assert(table.SetAutoFilter(), qt.IsNil)
+
+		parts, _ := writeFileParts(c, f)
+		xml := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(xml, qt.Contains, `<mergeCell ref="A1:C1"/>`)
+		c.Assert(xml, qt.Contains, `<mergeCell ref="A3:A4"/>`)
+		c.Assert(xml, qt.Contains, `sqref="A1:C1 A3:A4"`)
+		c.Assert(xml, qt.Contains, `<autoFilter ref="A5:C9"/>`)
+	})
+}
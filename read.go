@@ -118,6 +118,9 @@ func (r *Row) ReadStruct(ptr interface{}) error {
 			}
 			fieldV.SetFloat(value)
 		case reflect.Bool:
+			if err := cell.cellError(); err != nil {
+				return err
+			}
 			value := cell.Bool()
 			fieldV.SetBool(value)
 		}
@@ -230,4 +230,24 @@ func TestRead(t *testing.T) {
 		c.Assert(readStruct.BoolVal, qt.Equals, structVal.BoolVal)
 	})
 
+	csRunO(c, "TestReadStructErrorCell", func(c *qt.C, option FileOption) {
+		type structTest struct {
+			StringVal string  `xlsx:"0"`
+			FloatVal  float64 `xlsx:"1"`
+		}
+		f := NewFile(option)
+		sheet, _ := f.AddSheet("TestRead")
+		row := sheet.AddRow()
+		row.AddCell().SetError(ErrNA)
+		row.AddCell().SetError(ErrDiv0)
+
+		readStruct := &structTest{}
+		err := row.ReadStruct(readStruct)
+		var cellErr *CellError
+		c.Assert(errors.As(err, &cellErr), qt.IsTrue)
+		c.Assert(cellErr.Value, qt.Equals, ErrDiv0)
+		c.Assert(cellErr.Ref, qt.Equals, "B1")
+		c.Assert(readStruct.StringVal, qt.Equals, "#N/A")
+	})
+
 }
@@ -0,0 +1,610 @@
+package xlsx
+
+import (
+	"strings"
+)
+
+// refShift describes a structural edit of a sheet: n rows, or columns
+// when cols is set, inserted before the zero-based index at or, when n
+// is negative, -n rows or columns removed starting at index at.  It is
+// used to rewrite the references that the edit affects, the way Excel
+// does.
+type refShift struct {
+	sheet *Sheet
+	cols  bool
+	at    int
+	n     int
+}
+
+// span adjusts the zero-based interval from first to last on the axis
+// of the edit.  An interval grows when rows or columns are inserted
+// within it and shrinks when some of its rows or columns are removed.
+// It returns false if the interval is removed entirely, or pushed off
+// the end of the sheet.
+func (sh refShift) span(first, last int) (int, int, bool) {
+	limit := Excel2006MaxRowIndex
+	if sh.cols {
+		limit = Excel2006MaxColIndex
+	}
+	if sh.n > 0 {
+		if first >= sh.at {
+			first += sh.n
+		}
+		if last >= sh.at {
+			last += sh.n
+		}
+		if last > limit {
+			last = limit
+		}
+		return first, last, first <= limit
+	}
+	// end is the index of the first row or column after the removed ones
+	end := sh.at - sh.n
+	switch {
+	case first >= end:
+		first += sh.n
+	case first >= sh.at:
+		first = sh.at
+	}
+	switch {
+	case last >= end:
+		last += sh.n
+	case last >= sh.at:
+		last = sh.at - 1
+	}
+	return first, last, first <= last
+}
+
+// cellRange adjusts a range on the edited sheet.
+func (sh refShift) cellRange(r CellRange) (CellRange, bool) {
+	var ok bool
+	switch {
+	case sh.cols && r.IsWholeRows(), !sh.cols && r.IsWholeCols():
+		return r, true
+	case sh.cols:
+		r.MinCol, r.MaxCol, ok = sh.span(r.MinCol, r.MaxCol)
+	default:
+		r.MinRow, r.MaxRow, ok = sh.span(r.MinRow, r.MaxRow)
+	}
+	return r, ok
+}
+
+// appliesTo reports whether a reference within a formula on the given
+// sheet points at the edited sheet.  References to other workbooks
+// and 3D references are left alone, as Excel only adjusts those when
+// the edit is made to every sheet they span.
+func (sh refShift) appliesTo(ref Reference, sheet *Sheet) bool {
+	if ref.Workbook != "" || ref.LastSheet != "" {
+		return false
+	}
+	if ref.Sheet == "" {
+		return sheet == sh.sheet
+	}
+	return strings.EqualFold(ref.Sheet, sh.sheet.Name)
+}
+
+// reference adjusts a reference in A1 notation to the edited sheet.
+// Both relative and absolute references move with the cells they
+// point at.
+func (sh refShift) reference(ref Reference) (Reference, bool) {
+	if (sh.cols && ref.First.NoCol) || (!sh.cols && ref.First.NoRow) {
+		return ref, true
+	}
+	get := func(c *CellRef) int {
+		if sh.cols {
+			return c.Col
+		}
+		return c.Row
+	}
+	set := func(c *CellRef, i int) {
+		if sh.cols {
+			c.Col = i
+		} else {
+			c.Row = i
+		}
+	}
+	lo, hi := &ref.First, &ref.First
+	if ref.IsRange {
+		hi = &ref.Last
+		if get(lo) > get(hi) {
+			lo, hi = hi, lo
+		}
+	}
+	first, last, ok := sh.span(get(lo), get(hi))
+	set(lo, first)
+	set(hi, last)
+	return ref, ok
+}
+
+// formula rewrites the references to the edited sheet within a
+// formula on the given sheet.  References to cells that have been
+// removed become #REF! errors.  A formula that can't be tokenized, or
+// isn't affected, is returned unchanged.
+func (sh refShift) formula(formula string, sheet *Sheet) string {
+	tokens, err := TokenizeFormula(formula)
+	if err != nil {
+		return formula
+	}
+	changed := false
+	var b strings.Builder
+	for _, tok := range tokens {
+		if tok.Type == FormulaTokenReference {
+			ref, err := ParseReference(tok.Value)
+			if err == nil && sh.appliesTo(ref, sheet) {
+				adjusted, ok := sh.reference(ref)
+				switch {
+				case !ok && ref.Sheet != "":
+					b.WriteString(sheetRef(ref.Sheet, "#REF!"))
+					changed = true
+					continue
+				case !ok:
+					b.WriteString("#REF!")
+					changed = true
+					continue
+				case adjusted != ref:
+					b.WriteString(adjusted.String())
+					changed = true
+					continue
+				}
+			}
+		}
+		b.WriteString(tok.Value)
+	}
+	if !changed {
+		return formula
+	}
+	return b.String()
+}
+
+// sqref adjusts a space separated list of ranges on the edited sheet,
+// as used by data validations.  Ranges that are removed entirely are
+// dropped from the list.
+func (sh refShift) sqref(sqref string) string {
+	var refs []string
+	for _, part := range strings.Fields(sqref) {
+		r, err := ParseCellRange(part)
+		if err != nil {
+			refs = append(refs, part)
+			continue
+		}
+		if r, ok := sh.cellRange(r); ok {
+			refs = append(refs, r.String())
+		}
+	}
+	return strings.Join(refs, " ")
+}
+
+// cellEdit records the new contents of a cell whose references are
+// affected by a structural edit.
+type cellEdit struct {
+	row, col int
+	formula  string
+	location string
+	link     string
+	arrayRef string
+	hMerge   int
+	vMerge   int
+}
+
+// apply makes the edit, calling move to move the rows or columns of
+// the edited sheet, and rewrites every reference in the File that the
+// edit affects: formulas, defined names, hyperlink locations and data
+// validations on any sheet, and the merged cells, auto-filter, sort
+// state, print area, print titles and data validation ranges of the
+// edited sheet.  The changes to the cells are worked out before move
+// is called and made once it succeeds, so nothing is rewritten if the
+// cells can't be read or moved.
+func (sh refShift) apply(move func() error) error {
+	sheets := []*Sheet{sh.sheet}
+	if sh.sheet.File != nil {
+		sheets = sh.sheet.File.Sheets
+	}
+	edits := make([][]cellEdit, len(sheets))
+	for i, sheet := range sheets {
+		var err error
+		if edits[i], err = sh.cellEdits(sheet); err != nil {
+			return err
+		}
+	}
+	if err := move(); err != nil {
+		return err
+	}
+	for i, sheet := range sheets {
+		if sheet == sh.sheet {
+			edits[i] = sh.moveCellEdits(edits[i])
+		}
+		if err := sheet.writeCellEdits(edits[i]); err != nil {
+			return err
+		}
+		for _, dv := range sheet.DataValidations {
+			dv.Formula1 = sh.formula(dv.Formula1, sheet)
+			dv.Formula2 = sh.formula(dv.Formula2, sheet)
+		}
+	}
+
+	s := sh.sheet
+	dvs := s.DataValidations[:0]
+	for _, dv := range s.DataValidations {
+		if dv.Sqref != "" {
+			dv.Sqref = sh.sqref(dv.Sqref)
+			if dv.Sqref == "" {
+				continue
+			}
+		}
+		dvs = append(dvs, dv)
+	}
+	s.DataValidations = dvs
+	if s.AutoFilter != nil {
+		r, err := ParseCellRange(s.AutoFilter.TopLeftCell + cellRangeChar + s.AutoFilter.BottomRightCell)
+		if err == nil {
+			if r, ok := sh.cellRange(r); ok {
+				s.AutoFilter.TopLeftCell = GetCellIDStringFromCoords(r.MinCol, r.MinRow)
+				s.AutoFilter.BottomRightCell = GetCellIDStringFromCoords(r.MaxCol, r.MaxRow)
+			} else {
+				s.AutoFilter = nil
+			}
+		}
+	}
+	if s.sortState != nil {
+		s.sortState = sh.sortState(s.sortState)
+	}
+	for _, r := range []**CellRange{&s.PrintArea, &s.PrintTitleRows, &s.PrintTitleCols} {
+		if *r == nil {
+			continue
+		}
+		if adjusted, ok := sh.cellRange(**r); ok {
+			**r = adjusted
+		} else {
+			*r = nil
+		}
+	}
+
+	if s.File == nil {
+		return nil
+	}
+	for _, dn := range s.File.DefinedNames {
+		var scope *Sheet
+		if dn.localSheetID != nil && *dn.localSheetID >= 0 && *dn.localSheetID < len(s.File.Sheets) {
+			scope = s.File.Sheets[*dn.localSheetID]
+		}
+		dn.Data = sh.formula(dn.Data, scope)
+	}
+	return nil
+}
+
+// sortState adjusts the range of the last sort of the edited sheet
+// and those of its keys.  Keys whose columns are removed are dropped,
+// and the sort is forgotten if its range or every key is removed.
+func (sh refShift) sortState(ss *xlsxSortState) *xlsxSortState {
+	r, err := ParseCellRange(ss.Ref)
+	if err != nil {
+		return ss
+	}
+	r, ok := sh.cellRange(r)
+	if !ok {
+		return nil
+	}
+	adjusted := &xlsxSortState{Ref: r.String()}
+	for _, cond := range ss.SortCondition {
+		cond.Ref = sh.sqref(cond.Ref)
+		if cond.Ref != "" {
+			adjusted.SortCondition = append(adjusted.SortCondition, cond)
+		}
+	}
+	if len(adjusted.SortCondition) == 0 {
+		return nil
+	}
+	return adjusted
+}
+
+// mayRefer reports whether text on a sheet could hold a reference
+// that the edit affects.  References from other sheets have to name
+// the edited sheet, so the formulas of cells on those sheets that
+// don't mention it needn't be parsed.
+func (sh refShift) mayRefer(text string, sheet *Sheet) bool {
+	return text != "" && (sheet == sh.sheet || mentionsSheet(text, sh.sheet.Name))
+}
+
+// mentionsSheet reports whether text, such as a formula, contains the
+// name of a sheet, ignoring case, as it must to refer to the sheet
+// from elsewhere.
+func mentionsSheet(text, name string) bool {
+	text = strings.ToLower(text)
+	name = strings.ToLower(name)
+	return strings.Contains(text, name) || strings.Contains(text, strings.ReplaceAll(name, "'", "''"))
+}
+
+// cellEdits works out the new formulas and hyperlink locations of the
+// cells on a sheet and, on the edited sheet, the extent of merged
+// cells and array formulas.  It changes nothing; the edits are made
+// once the cells have moved.
+func (sh refShift) cellEdits(sheet *Sheet) ([]cellEdit, error) {
+	var edits []cellEdit
+	err := sheet.ForEachRow(func(row *Row) error {
+		return row.ForEachCell(func(cell *Cell) error {
+			edit := cellEdit{
+				row:      row.num,
+				col:      cell.num,
+				formula:  cell.formula,
+				location: cell.Hyperlink.Location,
+				arrayRef: cell.arrayRef,
+				hMerge:   cell.HMerge,
+				vMerge:   cell.VMerge,
+			}
+			if sh.mayRefer(cell.formula, sheet) {
+				edit.formula = sh.formula(cell.formula, sheet)
+			}
+			if sh.mayRefer(cell.Hyperlink.Location, sheet) {
+				edit.location = sh.formula(cell.Hyperlink.Location, sheet)
+			}
+			if sheet == sh.sheet {
+				edit.hMerge, edit.vMerge = sh.merge(row.num, cell.num, cell.HMerge, cell.VMerge)
+				edit.arrayRef = sh.arrayRef(cell.arrayRef)
+			}
+			if edit.formula != cell.formula || edit.location != cell.Hyperlink.Location || edit.arrayRef != cell.arrayRef ||
+				edit.hMerge != cell.HMerge || edit.vMerge != cell.VMerge {
+				edits = append(edits, edit)
+			}
+			return nil
+		}, SkipEmptyCells)
+	}, SkipEmptyRows)
+	return edits, err
+}
+
+// moveCellEdits moves the edits to cells of the edited sheet to where
+// the cells have moved, dropping those to removed cells.
+func (sh refShift) moveCellEdits(edits []cellEdit) []cellEdit {
+	moved := edits[:0]
+	for _, edit := range edits {
+		var ok bool
+		if sh.cols {
+			edit.col, ok = sh.index(edit.col)
+		} else {
+			edit.row, ok = sh.index(edit.row)
+		}
+		if ok {
+			moved = append(moved, edit)
+		}
+	}
+	return moved
+}
+
+// index returns the zero-based index that the row or column at i of
+// the edited sheet moves to, or false if it is removed.
+func (sh refShift) index(i int) (int, bool) {
+	switch {
+	case i < sh.at:
+		return i, true
+	case sh.n < 0 && i < sh.at-sh.n:
+		return 0, false
+	}
+	return i + sh.n, true
+}
+
+// writeCellEdits gives the cells of the Sheet the formulas, hyperlink
+// locations, array formula ranges and merges of the edits.
+func (s *Sheet) writeCellEdits(edits []cellEdit) error {
+	for _, edit := range edits {
+		row, err := s.Row(edit.row)
+		if err != nil {
+			return err
+		}
+		cell := row.GetCell(edit.col)
+		cell.updatable()
+		cell.formula = edit.formula
+		cell.Hyperlink.Location = edit.location
+		cell.arrayRef = edit.arrayRef
+		cell.HMerge = edit.hMerge
+		cell.VMerge = edit.vMerge
+		cell.modified = true
+		// Pushing the cell back makes stores that only hold the
+		// current cell in memory, such as DiskV, persist it
+		row.PushCell(cell)
+	}
+	return nil
+}
+
+// merge adjusts the extent of a merged cell whose top left cell is at
+// the given row and column.  The merge grows or shrinks with the rows
+// or columns inserted or removed within it, and is undone when its
+// top left cell is removed.
+func (sh refShift) merge(row, col, hMerge, vMerge int) (int, int) {
+	if hMerge == 0 && vMerge == 0 {
+		return 0, 0
+	}
+	origin, extent := row, &vMerge
+	if sh.cols {
+		origin, extent = col, &hMerge
+	}
+	first, last, ok := sh.span(origin, origin+*extent)
+	if !ok || (sh.n < 0 && origin >= sh.at && origin < sh.at-sh.n) {
+		return 0, 0
+	}
+	*extent = last - first
+	return hMerge, vMerge
+}
+
+// arrayRef adjusts the range filled by an array formula on the edited
+// sheet.  Excel doesn't allow part of an array to be removed, so the
+// range is left alone should that happen.
+func (sh refShift) arrayRef(ref string) string {
+	if ref == "" {
+		return ref
+	}
+	r, err := ParseCellRange(ref)
+	if err != nil {
+		return ref
+	}
+	if r, ok := sh.cellRange(r); ok {
+		return r.String()
+	}
+	return ref
+}
+
+// refMove describes the cells of an area of a sheet moving by dx
+// columns and dy rows to dest, another sheet of the same File or the
+// sheet itself.  It is used to rewrite the references to the moved
+// cells, which follow them as they do in Excel.  References to only
+// part of the area keep pointing where they did.
+type refMove struct {
+	sheet  *Sheet
+	area   CellRange
+	dest   *Sheet
+	dx, dy int
+}
+
+// contains reports whether a reference lies within the moved area.
+// References to whole rows or columns never do.
+func (rm refMove) contains(ref Reference) bool {
+	if ref.First.NoCol || ref.First.NoRow {
+		return false
+	}
+	last := ref.First
+	if ref.IsRange {
+		last = ref.Last
+	}
+	return rm.area.Contains(ref.First.Col, ref.First.Row) && rm.area.Contains(last.Col, last.Row)
+}
+
+// reference adjusts a reference within a formula that is on the sheet
+// from before the move, and on the sheet to after it, which differ
+// for the moved cells when they go to another sheet.  Unqualified
+// references that would otherwise point at another sheet's cells are
+// qualified with the name of the sheet they point at.
+func (rm refMove) reference(ref Reference, from, to *Sheet) Reference {
+	if ref.Workbook != "" || ref.LastSheet != "" {
+		return ref
+	}
+	target := from
+	if ref.Sheet != "" {
+		if !strings.EqualFold(ref.Sheet, rm.sheet.Name) {
+			return ref
+		}
+		target = rm.sheet
+	}
+	if target == nil {
+		return ref
+	}
+	if target == rm.sheet && rm.contains(ref) {
+		ref.First.Col += rm.dx
+		ref.First.Row += rm.dy
+		if ref.IsRange {
+			ref.Last.Col += rm.dx
+			ref.Last.Row += rm.dy
+		}
+		target = rm.dest
+		if ref.Sheet != "" {
+			ref.Sheet = rm.dest.Name
+		}
+	}
+	if ref.Sheet == "" && target != to {
+		ref.Sheet = target.Name
+	}
+	return ref
+}
+
+// formula rewrites the references within a formula that is on the
+// sheet from before the move and on to after it.  A formula that
+// can't be tokenized, or isn't affected, is returned unchanged.
+func (rm refMove) formula(formula string, from, to *Sheet) string {
+	if formula == "" || (from == to && from != rm.sheet && !mentionsSheet(formula, rm.sheet.Name)) {
+		return formula
+	}
+	tokens, err := TokenizeFormula(formula)
+	if err != nil {
+		return formula
+	}
+	changed := false
+	var b strings.Builder
+	for _, tok := range tokens {
+		if tok.Type == FormulaTokenReference {
+			if ref, err := ParseReference(tok.Value); err == nil {
+				if adjusted := rm.reference(ref, from, to); adjusted != ref {
+					b.WriteString(adjusted.String())
+					changed = true
+					continue
+				}
+			}
+		}
+		b.WriteString(tok.Value)
+	}
+	if !changed {
+		return formula
+	}
+	return b.String()
+}
+
+// apply moves the cells by calling move, and rewrites the references
+// to them in the formulas, hyperlink locations and data validations
+// of every sheet of the File and in its defined names.  The formulas
+// of the moved cells themselves must be rewritten by move.  As with
+// refShift, the changes are worked out before move is called and made
+// once it succeeds.
+func (rm refMove) apply(move func() error) error {
+	sheets := []*Sheet{rm.sheet}
+	if rm.sheet.File != nil {
+		sheets = rm.sheet.File.Sheets
+	} else if rm.dest != rm.sheet {
+		sheets = append(sheets, rm.dest)
+	}
+	edits := make([][]cellEdit, len(sheets))
+	// moved holds the cells that move, which replace those at their
+	// destinations
+	moved := make(map[[2]int]bool)
+	for i, sheet := range sheets {
+		err := sheet.ForEachRow(func(row *Row) error {
+			return row.ForEachCell(func(cell *Cell) error {
+				if sheet == rm.sheet && rm.area.Contains(cell.num, row.num) {
+					moved[[2]int{cell.num, row.num}] = true
+					return nil
+				}
+				formula := rm.formula(cell.formula, sheet, sheet)
+				location := rm.formula(cell.Hyperlink.Location, sheet, sheet)
+				if formula != cell.formula || location != cell.Hyperlink.Location {
+					edits[i] = append(edits[i], cellEdit{
+						row:      row.num,
+						col:      cell.num,
+						formula:  formula,
+						location: location,
+						arrayRef: cell.arrayRef,
+						hMerge:   cell.HMerge,
+						vMerge:   cell.VMerge,
+					})
+				}
+				return nil
+			}, SkipEmptyCells)
+		}, SkipEmptyRows)
+		if err != nil {
+			return err
+		}
+	}
+	if err := move(); err != nil {
+		return err
+	}
+	for i, sheet := range sheets {
+		kept := edits[i][:0]
+		for _, edit := range edits[i] {
+			if sheet != rm.dest || !moved[[2]int{edit.col - rm.dx, edit.row - rm.dy}] {
+				kept = append(kept, edit)
+			}
+		}
+		if err := sheet.writeCellEdits(kept); err != nil {
+			return err
+		}
+		for _, dv := range sheet.DataValidations {
+			dv.Formula1 = rm.formula(dv.Formula1, sheet, sheet)
+			dv.Formula2 = rm.formula(dv.Formula2, sheet, sheet)
+		}
+	}
+	if rm.sheet.File == nil {
+		return nil
+	}
+	for _, dn := range rm.sheet.File.DefinedNames {
+		var scope *Sheet
+		if dn.localSheetID != nil && *dn.localSheetID >= 0 && *dn.localSheetID < len(rm.sheet.File.Sheets) {
+			scope = rm.sheet.File.Sheets[*dn.localSheetID]
+		}
+		dn.Data = rm.formula(dn.Data, scope, scope)
+	}
+	return nil
+}
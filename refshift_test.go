@@ -0,0 +1,309 @@
+package xlsx
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRefShift(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("Formula", func(c *qt.C) {
+		data := &Sheet{Name: "Data"}
+		other := &Sheet{Name: "Other"}
+		insert := refShift{sheet: data, at: 2, n: 2}
+		remove := refShift{sheet: data, at: 2, n: -2}
+		insertCols := refShift{sheet: data, cols: true, at: 1, n: 1}
+		removeCols := refShift{sheet: data, cols: true, at: 1, n: -1}
+		testCases := []struct {
+			shift    refShift
+			sheet    *Sheet
+			formula  string
+			expected string
+		}{
+			{insert, data, "A2+A3", "A2+A5"},
+			{insert, data, "$A$3*2", "$A$5*2"},
+			{insert, data, "SUM(A1:A3)", "SUM(A1:A5)"},
+			{insert, data, "SUM(A3:A1)", "SUM(A5:A1)"},
+			{insert, data, "SUM(A:A)+SUM(3:4)", "SUM(A:A)+SUM(5:6)"},
+			{insert, data, "Other!A3", "Other!A3"},
+			{insert, other, "A3+data!A3", "A3+data!A5"},
+			{insert, other, "SUM('Data:Other'!A3)", "SUM('Data:Other'!A3)"},
+			{insert, data, "[1]Data!A3", "[1]Data!A3"},
+			{insert, data, `"A3"&a3`, `"A3"&A5`},
+			{insert, data, "SUM(A1048576)", "SUM(#REF!)"},
+			{remove, data, "A2+A3", "A2+#REF!"},
+			{remove, data, "SUM(A1:A10)", "SUM(A1:A8)"},
+			{remove, data, "SUM(A3:A10)", "SUM(A3:A8)"},
+			{remove, data, "SUM(A1:A4)", "SUM(A1:A2)"},
+			{remove, data, "SUM(A3:B4)", "SUM(#REF!)"},
+			{remove, other, "Data!A4*Data!A5", "Data!#REF!*Data!A3"},
+			{remove, data, "SUM(3:4)", "SUM(#REF!)"},
+			{insertCols, data, "SUM(A1:C1)+B$2", "SUM(A1:D1)+C$2"},
+			{insertCols, data, "SUM(1:1)", "SUM(1:1)"},
+			{removeCols, data, "A1+B1+C1", "A1+#REF!+B1"},
+			{remove, data, "SUM(", "SUM("},
+		}
+		for _, test := range testCases {
+			c.Assert(test.shift.formula(test.formula, test.sheet), qt.Equals, test.expected, qt.Commentf(test.formula))
+		}
+	})
+
+	csRunO(c, "RowEdits", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		other, err := f.AddSheet("Other")
+		c.Assert(err, qt.IsNil)
+
+		set := func(sheet *Sheet, ref string, value interface{}) *Cell {
+			col, row, err := GetCoordsFromCellIDString(ref)
+			c.Assert(err, qt.IsNil)
+			cell, err := sheet.Cell(row, col)
+			c.Assert(err, qt.IsNil)
+			if formula, ok := value.(string); ok {
+				cell.SetFormula(formula)
+			} else {
+				cell.SetValue(value)
+			}
+			return cell
+		}
+		formula := func(sheet *Sheet, ref string) string {
+			col, row, err := GetCoordsFromCellIDString(ref)
+			c.Assert(err, qt.IsNil)
+			cell, err := sheet.Cell(row, col)
+			c.Assert(err, qt.IsNil)
+			return cell.Formula()
+		}
+
+		set(data, "A1", 1)
+		set(data, "B1", "SUM(A1:A5)")
+		set(data, "C1", 0).Merge(0, 3)
+		set(data, "A2", 2)
+		set(data, "B2", "A3*2")
+		set(data, "A3", 3)
+		set(data, "B3", "$A$4+Other!A1")
+		set(data, "A4", 4)
+		set(data, "A5", 5)
+		set(other, "A1", "SUM(Data!A2:A4)")
+		set(other, "A2", "Data!A3")
+		data.AutoFilter = &AutoFilter{TopLeftCell: "A1", BottomRightCell: "C5"}
+		c.Assert(data.SetPrintArea("A1:C5"), qt.IsNil)
+		c.Assert(data.SetPrintTitles("1:1", ""), qt.IsNil)
+		data.AddDataValidation(NewDataValidation(1, 0, 4, 0, true))
+		_, err = f.AddName("Total", "Data!$B$1", nil)
+		c.Assert(err, qt.IsNil)
+		_, err = f.AddName("Third", "Data!$A$3", nil)
+		c.Assert(err, qt.IsNil)
+
+		_, err = data.AddRowAtIndex(1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(formula(data, "B1"), qt.Equals, "SUM(A1:A6)")
+		c.Assert(formula(data, "B3"), qt.Equals, "A4*2")
+		c.Assert(formula(data, "B4"), qt.Equals, "$A$5+Other!A1")
+		c.Assert(formula(other, "A1"), qt.Equals, "SUM(Data!A3:A5)")
+		c.Assert(formula(other, "A2"), qt.Equals, "Data!A4")
+		c.Assert(f.Name("Total").Data, qt.Equals, "Data!$B$1")
+		c.Assert(f.Name("Third").Data, qt.Equals, "Data!$A$4")
+		c.Assert(*data.AutoFilter, qt.Equals, AutoFilter{TopLeftCell: "A1", BottomRightCell: "C6"})
+		c.Assert(data.PrintArea.String(), qt.Equals, "A1:C6")
+		c.Assert(data.PrintTitleRows.String(), qt.Equals, "1:1")
+		c.Assert(data.DataValidations[0].Sqref, qt.Equals, "A3:A6")
+		cell, err := data.Cell(0, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.VMerge, qt.Equals, 4)
+
+		err = data.RemoveRowAtIndex(3)
+		c.Assert(err, qt.IsNil)
+		c.Assert(formula(data, "B1"), qt.Equals, "SUM(A1:A5)")
+		c.Assert(formula(data, "B3"), qt.Equals, "#REF!*2")
+		c.Assert(formula(data, "B4"), qt.Equals, "")
+		c.Assert(formula(other, "A1"), qt.Equals, "SUM(Data!A3:A4)")
+		c.Assert(formula(other, "A2"), qt.Equals, "Data!#REF!")
+		c.Assert(f.Name("Third").Data, qt.Equals, "Data!#REF!")
+		c.Assert(*data.AutoFilter, qt.Equals, AutoFilter{TopLeftCell: "A1", BottomRightCell: "C5"})
+		c.Assert(data.PrintArea.String(), qt.Equals, "A1:C5")
+		c.Assert(data.DataValidations[0].Sqref, qt.Equals, "A3:A5")
+		cell, err = data.Cell(0, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.VMerge, qt.Equals, 3)
+
+		v, err := f.Evaluate(data, "B1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(v.Value, qt.Equals, "12")
+		v, err = f.Evaluate(other, "A1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(v.Value, qt.Equals, "6")
+
+		// Removing every row of a range drops what depends on it
+		for i := 0; i < 4; i++ {
+			c.Assert(data.RemoveRowAtIndex(1), qt.IsNil)
+		}
+		c.Assert(data.DataValidations, qt.HasLen, 0)
+		c.Assert(data.PrintArea.String(), qt.Equals, "A1:C1")
+		c.Assert(formula(other, "A1"), qt.Equals, "SUM(Data!#REF!)")
+	})
+
+	csRunO(c, "ColEdits", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		row := data.AddRow()
+		for i := 1; i <= 4; i++ {
+			row.AddCell().SetInt(i)
+		}
+		row.AddCell().SetFormula("SUM(A1:D1)")
+		row = data.AddRow()
+		row.AddCell().Merge(2, 0)
+		row.AddCell()
+		row.AddCell()
+		row.AddCell().SetFormula("C1*2")
+		data.SetColWidth(1, 1, 20)
+		data.SetColWidth(2, 3, 30)
+		data.SetOutlineLevel(5, 5, 1)
+		c.Assert(data.AddColBreak(2), qt.IsNil)
+		data.AddDataValidation(NewDataValidation(0, 1, 1, 2, true))
+
+		cellAt := func(ref string) *Cell {
+			col, row, err := GetCoordsFromCellIDString(ref)
+			c.Assert(err, qt.IsNil)
+			cell, err := data.Cell(row, col)
+			c.Assert(err, qt.IsNil)
+			return cell
+		}
+
+		c.Assert(data.InsertCols(1, 2), qt.IsNil)
+		c.Assert(data.MaxCol, qt.Equals, 7)
+		c.Assert(cellAt("A1").Value, qt.Equals, "1")
+		c.Assert(cellAt("B1").Value, qt.Equals, "")
+		c.Assert(cellAt("D1").Value, qt.Equals, "2")
+		c.Assert(cellAt("F1").Value, qt.Equals, "4")
+		c.Assert(cellAt("G1").Formula(), qt.Equals, "SUM(A1:F1)")
+		c.Assert(cellAt("A2").HMerge, qt.Equals, 4)
+		c.Assert(cellAt("F2").Formula(), qt.Equals, "E1*2")
+		c.Assert(*data.Col(0).Width, qt.Equals, 20.0)
+		c.Assert(data.Col(1), qt.IsNil)
+		c.Assert(data.Col(2), qt.IsNil)
+		c.Assert(*data.Col(3).Width, qt.Equals, 30.0)
+		c.Assert(*data.Col(4).Width, qt.Equals, 30.0)
+		c.Assert(*data.Col(6).OutlineLevel, qt.Equals, uint8(1))
+		c.Assert(data.ColBreaks, qt.DeepEquals, []int{4})
+		c.Assert(data.DataValidations[0].Sqref, qt.Equals, "D1:E2")
+
+		c.Assert(data.RemoveCols(4, 1), qt.IsNil)
+		c.Assert(data.MaxCol, qt.Equals, 6)
+		c.Assert(cellAt("D1").Value, qt.Equals, "2")
+		c.Assert(cellAt("E1").Value, qt.Equals, "4")
+		c.Assert(cellAt("F1").Formula(), qt.Equals, "SUM(A1:E1)")
+		c.Assert(cellAt("A2").HMerge, qt.Equals, 3)
+		c.Assert(cellAt("E2").Formula(), qt.Equals, "#REF!*2")
+		c.Assert(cellAt("F2").Formula(), qt.Equals, "")
+		c.Assert(*data.Col(3).Width, qt.Equals, 30.0)
+		c.Assert(data.Col(4), qt.IsNil)
+		c.Assert(*data.Col(5).OutlineLevel, qt.Equals, uint8(1))
+		c.Assert(data.ColBreaks, qt.DeepEquals, []int{4})
+		c.Assert(data.DataValidations[0].Sqref, qt.Equals, "D1:D2")
+
+		v, err := f.Evaluate(data, "F1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(v.Value, qt.Equals, "7")
+
+		c.Assert(data.InsertCols(-1, 1), qt.ErrorMatches, "InsertCols: index out of range: -1")
+		c.Assert(data.InsertCols(0, 0), qt.ErrorMatches, "InsertCols: invalid number of columns: 0")
+		c.Assert(data.InsertCols(0, Excel2006MaxColCount), qt.Not(qt.IsNil))
+		c.Assert(data.RemoveCols(0, 0), qt.ErrorMatches, "RemoveCols: invalid number of columns: 0")
+	})
+
+	csRunO(c, "HyperlinkLocation", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		for i := 0; i < 5; i++ {
+			data.AddRow().AddCell().SetInt(i)
+		}
+		cell, err := data.Cell(0, 1)
+		c.Assert(err, qt.IsNil)
+		cell.SetString("Last")
+		cell.Hyperlink = Hyperlink{DisplayString: "Last", Location: "Data!A5"}
+		_, err = data.AddRowAtIndex(0)
+		c.Assert(err, qt.IsNil)
+		cell, err = data.Cell(1, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell.Hyperlink.Location, qt.Equals, "Data!A6")
+	})
+
+	c.Run("ColEditsNeedCellShifter", func(c *qt.C) {
+		f := NewFile()
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		cell, err := data.Cell(0, 1)
+		c.Assert(err, qt.IsNil)
+		cell.SetFormula("A1*2")
+		data.cellStore = plainCellStore{data.cellStore}
+		c.Assert(data.InsertCols(0, 1), qt.ErrorMatches, `InsertCols: the sheet's cell store can't move cells along a row`)
+		c.Assert(data.RemoveCols(0, 1), qt.ErrorMatches, `RemoveCols: the sheet's cell store can't move cells along a row`)
+		c.Assert(cell.Formula(), qt.Equals, "A1*2")
+	})
+
+	c.Run("FailedMoveLeavesReferences", func(c *qt.C) {
+		f := NewFile()
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		other, err := f.AddSheet("Other")
+		c.Assert(err, qt.IsNil)
+		for i := 0; i < 3; i++ {
+			data.AddRow().AddCell().SetInt(i)
+		}
+		local, err := data.Cell(0, 1)
+		c.Assert(err, qt.IsNil)
+		local.SetFormula("A3*2")
+		remote, err := other.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		remote.SetFormula("Data!A3")
+		data.cellStore = stuckCellStore{data.cellStore}
+
+		_, err = data.AddRowAtIndex(0)
+		c.Assert(err, qt.ErrorMatches, "AddRowAtIndex: rows can't move")
+		c.Assert(data.RemoveRowAtIndex(1), qt.ErrorMatches, "RemoveRowAtIndex: rows can't move")
+		local, err = data.Cell(0, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(local.Formula(), qt.Equals, "A3*2")
+		remote, err = other.Cell(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(remote.Formula(), qt.Equals, "Data!A3")
+	})
+}
+
+// stuckCellStore is a CellStore whose rows can't be moved.
+type stuckCellStore struct {
+	CellStore
+}
+
+func (cs stuckCellStore) MoveRow(r *Row, index int) error {
+	return errors.New("rows can't move")
+}
+
+// plainCellStore is a CellStore whose rows, like those of a CellStore
+// from another package, can't shift their cells.
+type plainCellStore struct {
+	CellStore
+}
+
+func (cs plainCellStore) MakeRow(sheet *Sheet) *Row {
+	return plainRow(cs.CellStore.MakeRow(sheet))
+}
+
+func (cs plainCellStore) ReadRow(key string, sheet *Sheet) (*Row, error) {
+	row, err := cs.CellStore.ReadRow(key, sheet)
+	if err != nil {
+		return nil, err
+	}
+	return plainRow(row), nil
+}
+
+func plainRow(row *Row) *Row {
+	row.cellStoreRow = struct{ CellStoreRow }{row.cellStoreRow}
+	return row
+}
@@ -0,0 +1,89 @@
+package xlsx
+
+import "fmt"
+
+// RolloverSheet adds rows to a series of Sheets of a File, so that
+// more rows can be written than fit on a single sheet.  Whenever the
+// current Sheet is full, it moves on to a new Sheet, named after the
+// first as "Data (2)", "Data (3)" and so on, at the top of which the
+// header rows of the first Sheet are repeated.
+type RolloverSheet struct {
+	// MaxRows is the number of rows, including the header rows, that
+	// each Sheet may hold.  It is the most Excel allows, unless set to
+	// fewer.
+	MaxRows    int
+	headerRows int
+	sheets     []*Sheet
+}
+
+// AddRolloverSheet adds a Sheet with the given name to the File, and
+// returns a RolloverSheet that adds rows to it, and to the Sheets that
+// follow it once it's full.  The first headerRows rows added are the
+// header rows, which are repeated at the top of each of the Sheets.
+func (f *File) AddRolloverSheet(name string, headerRows int) (*RolloverSheet, error) {
+	if headerRows < 0 {
+		return nil, fmt.Errorf("AddRolloverSheet(%q, %d): headerRows must not be negative", name, headerRows)
+	}
+	sheet, err := f.AddSheet(name)
+	if err != nil {
+		return nil, fmt.Errorf("AddRolloverSheet(%q, %d): %w", name, headerRows, err)
+	}
+	return &RolloverSheet{
+		MaxRows:    Excel2006MaxRowCount,
+		headerRows: headerRows,
+		sheets:     []*Sheet{sheet},
+	}, nil
+}
+
+// Sheet returns the Sheet to which rows are currently added.
+func (rs *RolloverSheet) Sheet() *Sheet {
+	return rs.sheets[len(rs.sheets)-1]
+}
+
+// Sheets returns every Sheet to which the RolloverSheet has added rows,
+// in order.
+func (rs *RolloverSheet) Sheets() []*Sheet {
+	return append([]*Sheet(nil), rs.sheets...)
+}
+
+// AddRow adds a new Row to the end of the current Sheet, first moving
+// on to a new Sheet if the current one is full.
+func (rs *RolloverSheet) AddRow() (*Row, error) {
+	maxRows := rs.MaxRows
+	if maxRows <= 0 || maxRows > Excel2006MaxRowCount {
+		maxRows = Excel2006MaxRowCount
+	}
+	if maxRows <= rs.headerRows {
+		return nil, fmt.Errorf("AddRow: a sheet of %d rows has no room beyond the %d header rows", maxRows, rs.headerRows)
+	}
+	if rs.Sheet().MaxRow >= maxRows {
+		if err := rs.rollover(); err != nil {
+			return nil, fmt.Errorf("AddRow: %w", err)
+		}
+	}
+	return rs.Sheet().AddRow(), nil
+}
+
+// rollover moves on to a new Sheet, with the columns and header rows
+// of the first.
+func (rs *RolloverSheet) rollover() error {
+	first := rs.sheets[0]
+	sheet, err := first.File.AddSheet(first.File.uniqueSheetName(first.Name))
+	if err != nil {
+		return err
+	}
+	first.Cols.ForEach(func(_ int, col *Col) {
+		sheet.Cols.Add(col.copyToRange(col.Min, col.Max))
+	})
+	if rs.headerRows > 0 && first.MaxCol > 0 {
+		header := NewRange(first, NewCellRange(0, 0, first.MaxCol-1, rs.headerRows-1))
+		if err := header.CopyTo(sheet, 0, 0); err != nil {
+			return err
+		}
+	}
+	if sheet.MaxRow < rs.headerRows {
+		sheet.maybeAddRow(rs.headerRows)
+	}
+	rs.sheets = append(rs.sheets, sheet)
+	return nil
+}
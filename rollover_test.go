@@ -0,0 +1,71 @@
+package xlsx
+
+import (
+	"strconv"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRolloverSheet(t *testing.T) {
+	c := qt.New(t)
+
+	csRunO(c, "AddRow", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		rs, err := f.AddRolloverSheet("Data", 1)
+		c.Assert(err, qt.IsNil)
+		rs.MaxRows = 3
+		rs.Sheet().SetColWidth(1, 1, 25)
+		style := NewStyle()
+		style.Font.Bold = true
+		row, err := rs.AddRow()
+		c.Assert(err, qt.IsNil)
+		for _, heading := range []string{"Id", "Name"} {
+			cell := row.AddCell()
+			cell.SetString(heading)
+			cell.SetStyle(style)
+		}
+		for i := 1; i <= 5; i++ {
+			row, err := rs.AddRow()
+			c.Assert(err, qt.IsNil)
+			row.AddCell().SetInt(i)
+			row.AddCell().SetString("Item " + strconv.Itoa(i))
+		}
+
+		sheets := rs.Sheets()
+		c.Assert(sheets, qt.HasLen, 3)
+		c.Assert(rs.Sheet(), qt.Equals, sheets[2])
+		var names []string
+		for _, sheet := range f.Sheets {
+			names = append(names, sheet.Name)
+		}
+		c.Assert(names, qt.DeepEquals, []string{"Data", "Data (2)", "Data (3)"})
+		want := [][][]string{
+			{{"Id", "Name"}, {"1", "Item 1"}, {"2", "Item 2"}},
+			{{"Id", "Name"}, {"3", "Item 3"}, {"4", "Item 4"}},
+			{{"Id", "Name"}, {"5", "Item 5"}},
+		}
+		for i, sheet := range sheets {
+			rng, err := sheet.Range("1:" + strconv.Itoa(len(want[i])))
+			c.Assert(err, qt.IsNil)
+			values, err := rng.Values()
+			c.Assert(err, qt.IsNil)
+			c.Assert(values, qt.DeepEquals, want[i])
+			c.Assert(*sheet.Col(0).Width, qt.Equals, 25.0)
+			cell, err := sheet.Cell(0, 1)
+			c.Assert(err, qt.IsNil)
+			c.Assert(cell.GetStyle().Font.Bold, qt.IsTrue)
+		}
+	})
+
+	c.Run("NoRoom", func(c *qt.C) {
+		f := NewFile()
+		rs, err := f.AddRolloverSheet("Data", 2)
+		c.Assert(err, qt.IsNil)
+		rs.MaxRows = 2
+		_, err = rs.AddRow()
+		c.Assert(err, qt.ErrorMatches, `AddRow: a sheet of 2 rows has no room beyond the 2 header rows`)
+		_, err = f.AddRolloverSheet("Other", -1)
+		c.Assert(err, qt.ErrorMatches, `AddRolloverSheet\("Other", -1\): headerRows must not be negative`)
+	})
+}
@@ -7,11 +7,13 @@ import (
 // Row represents a single Row in the current Sheet.
 type Row struct {
 	Hidden       bool         // Hidden determines whether this Row is hidden or not.
+	Collapsed    bool         // Collapsed marks the Row that summarises a collapsed group of rows.
 	Sheet        *Sheet       // Sheet is a reference back to the Sheet that this Row is within.
 	height       float64      // Height is the current height of the Row in PostScript Points
 	outlineLevel uint8        // OutlineLevel contains the outline level of this Row.  Used for collapsing.
 	isCustom     bool         // isCustom is a flag that is set to true when the Row has been modified
 	customHeight bool         // customHeight is a flag to let the writer know that this row has a custom height
+	style        *Style       // style is the Style of the cells of the Row that don't have one of their own
 	num          int          // Num hold the positional number of the Row in the Sheet
 	cellStoreRow CellStoreRow // A reference to the underlying CellStoreRow which handles persistence of the cells
 }
@@ -65,6 +67,26 @@ func (r *Row) GetOutlineLevel() uint8 {
 	return r.outlineLevel
 }
 
+// SetStyle sets the Style of the Row, which applies to every cell of
+// the Row, out to the last column of the Sheet, that doesn't have a
+// Style of its own.  It takes precedence over the Style of a column.
+func (r *Row) SetStyle(style *Style) {
+	r.cellStoreRow.Updatable()
+	r.style = style
+	r.isCustom = true
+}
+
+// GetStyle returns the Style of the Row, or nil if it doesn't have one.
+func (r *Row) GetStyle() *Style {
+	return r.style
+}
+
+// isFormatted reports whether the Row is hidden, part of an outline
+// of grouped rows, or styled.
+func (r *Row) isFormatted() bool {
+	return r.Hidden || r.Collapsed || r.outlineLevel > 0 || r.style != nil
+}
+
 // AddCell adds a new Cell to the end of the Row
 func (r *Row) AddCell() *Cell {
 	r.cellStoreRow.Updatable()
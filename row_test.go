@@ -1,6 +1,9 @@
 package xlsx
 
 import (
+	"archive/zip"
+	"bytes"
+	"regexp"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
@@ -110,4 +113,90 @@ func TestRow(t *testing.T) {
 		c.Assert(row.isCustom, qt.IsTrue)
 
 	})
+
+	csRunO(c, "Style", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("MySheet")
+		c.Assert(err, qt.IsNil)
+		colStyle := NewStyle()
+		colStyle.Fill = *NewFill("solid", "FF00FF00", "FF00FF00")
+		col := NewColForRange(3, 3)
+		col.SetStyle(colStyle)
+		sheet.SetColParameters(col)
+		rowStyle := NewStyle()
+		rowStyle.Font.Bold = true
+		cellStyle := NewStyle()
+		cellStyle.Font.Italic = true
+
+		row, err := sheet.Row(0)
+		c.Assert(err, qt.IsNil)
+		row.SetStyle(rowStyle)
+		c.Assert(row.GetStyle(), qt.Equals, rowStyle)
+		row.AddCell().SetString("Heading")
+		row.AddCell().SetString("Own style")
+		row.GetCell(1).SetStyle(cellStyle)
+		row.AddCell().SetString("Over column")
+		row, err = sheet.Row(1)
+		c.Assert(err, qt.IsNil)
+		row.AddCell().SetString("Plain")
+		row.AddCell()
+		row.AddCell().SetString("Column")
+		// A styled row without cells is still written
+		row, err = sheet.Row(2)
+		c.Assert(err, qt.IsNil)
+		row.SetStyle(rowStyle)
+
+		parts, b := writeFileParts(c, f)
+		xSheet := parts["xl/worksheets/sheet1.xml"]
+		rowXf := regexp.MustCompile(`<row r="1" s="(\d+)" customFormat="true">`).FindStringSubmatch(xSheet)
+		c.Assert(rowXf, qt.HasLen, 2)
+		c.Assert(xSheet, qt.Contains, `<c r="A1" s="`+rowXf[1]+`" t="s">`)
+		c.Assert(xSheet, qt.Contains, `<c r="C1" s="`+rowXf[1]+`" t="s">`)
+		c.Assert(xSheet, qt.Not(qt.Contains), `<c r="B1" s="`+rowXf[1]+`"`)
+		c.Assert(xSheet, qt.Contains, `<row r="3" s="`+rowXf[1]+`" customFormat="true"`)
+
+		check := func(c *qt.C, sheet *Sheet) {
+			style := func(row, col int) *Style {
+				cell, err := sheet.Cell(row, col)
+				c.Assert(err, qt.IsNil)
+				return cell.GetStyle()
+			}
+			row, err := sheet.Row(0)
+			c.Assert(err, qt.IsNil)
+			c.Assert(row.GetStyle().Font.Bold, qt.IsTrue)
+			c.Assert(style(0, 0).Font.Bold, qt.IsTrue)
+			c.Assert(style(0, 1).Font.Bold, qt.IsFalse)
+			c.Assert(style(0, 1).Font.Italic, qt.IsTrue)
+			c.Assert(style(0, 2).Font.Bold, qt.IsTrue)
+			c.Assert(style(0, 2).Fill.FgColor, qt.Not(qt.Equals), "FF00FF00")
+			c.Assert(style(1, 0).Font.Bold, qt.IsFalse)
+			c.Assert(style(1, 2).Fill.FgColor, qt.Equals, "FF00FF00")
+			row, err = sheet.Row(1)
+			c.Assert(err, qt.IsNil)
+			c.Assert(row.GetStyle(), qt.IsNil)
+			row, err = sheet.Row(2)
+			c.Assert(err, qt.IsNil)
+			c.Assert(row.GetStyle().Font.Bold, qt.IsTrue)
+		}
+		f, err = OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		check(c, f.Sheets[0])
+
+		// Cells that Excel writes without a style of their own
+		// inherit the style of their row, then their column
+		unstyled := regexp.MustCompile(`(<c r="(A1|C1|C2)") s="\d+"`)
+		parts["xl/worksheets/sheet1.xml"] = unstyled.ReplaceAllString(xSheet, "$1")
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, content := range parts {
+			fw, err := w.Create(name)
+			c.Assert(err, qt.IsNil)
+			_, err = fw.Write([]byte(content))
+			c.Assert(err, qt.IsNil)
+		}
+		c.Assert(w.Close(), qt.IsNil)
+		f, err = OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+		check(c, f.Sheets[0])
+	})
 }
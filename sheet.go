@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -22,12 +23,30 @@ type Sheet struct {
 	Hidden          bool
 	Selected        bool
 	SheetViews      []SheetView
+	TabColor        string // The ARGB colour of the tab, such as "FFFF0000"
 	SheetFormat     SheetFormat
 	AutoFilter      *AutoFilter
+	HeaderFooter    *HeaderFooter
+	RowBreaks       []int
+	ColBreaks       []int
+	PrintArea       *CellRange
+	PrintTitleRows  *CellRange
+	PrintTitleCols  *CellRange
 	Relations       []Relation
 	DataValidations []*xlsxDataValidation
 	cellStore       CellStore
 	currentRow      *Row
+	// dynamicArrays is set when the sheet was last written if it
+	// holds dynamic array formulas, which need the workbook to
+	// include their metadata
+	dynamicArrays bool
+	// sortState records the last sort of the sheet, so that Excel
+	// can show it
+	sortState *xlsxSortState
+	// summaryAbove and summaryLeft place the summary rows and
+	// columns of outlines before their groups, rather than after
+	summaryAbove bool
+	summaryLeft  bool
 }
 
 // NewSheet constructs a Sheet with the default CellStore and returns
@@ -58,6 +77,136 @@ func (s *Sheet) Close() {
 	s.cellStore = nil
 }
 
+// styleCopier returns a function that copies styles for the Sheet s
+// from src, copying each style only once.  Named cell styles are
+// dropped when src belongs to another File, which doesn't define them.
+func (s *Sheet) styleCopier(src *Sheet) func(style *Style) *Style {
+	sameFile := src.File == s.File
+	styles := make(map[*Style]*Style)
+	return func(style *Style) *Style {
+		if style == nil {
+			return nil
+		}
+		if c, ok := styles[style]; ok {
+			return c
+		}
+		c := *style
+		if !sameFile {
+			c.NamedStyleIndex = nil
+		}
+		styles[style] = &c
+		return &c
+	}
+}
+
+func copyDataValidation(dv *xlsxDataValidation) *xlsxDataValidation {
+	if dv == nil {
+		return nil
+	}
+	c := *dv
+	return &c
+}
+
+// copyFrom makes the empty Sheet a copy of src, sharing nothing that
+// could change with it.
+func (s *Sheet) copyFrom(src *Sheet) error {
+	copyStyle := s.styleCopier(src)
+	s.Hidden = src.Hidden
+	s.TabColor = src.TabColor
+	s.summaryAbove = src.summaryAbove
+	s.summaryLeft = src.summaryLeft
+	s.SheetFormat = src.SheetFormat
+	for _, view := range src.SheetViews {
+		if view.Pane != nil {
+			pane := *view.Pane
+			view.Pane = &pane
+		}
+		s.SheetViews = append(s.SheetViews, view)
+	}
+	if src.AutoFilter != nil {
+		autoFilter := *src.AutoFilter
+		s.AutoFilter = &autoFilter
+	}
+	if src.HeaderFooter != nil {
+		s.HeaderFooter = src.HeaderFooter.clone()
+	}
+	s.RowBreaks = append([]int(nil), src.RowBreaks...)
+	s.ColBreaks = append([]int(nil), src.ColBreaks...)
+	for _, r := range []struct{ dest, src **CellRange }{
+		{&s.PrintArea, &src.PrintArea},
+		{&s.PrintTitleRows, &src.PrintTitleRows},
+		{&s.PrintTitleCols, &src.PrintTitleCols},
+	} {
+		if *r.src != nil {
+			c := **r.src
+			*r.dest = &c
+		}
+	}
+	s.Relations = append([]Relation(nil), src.Relations...)
+	for _, dv := range src.DataValidations {
+		s.DataValidations = append(s.DataValidations, copyDataValidation(dv))
+	}
+	if src.sortState != nil {
+		sortState := *src.sortState
+		sortState.SortCondition = append([]xlsxSortCondition(nil), src.sortState.SortCondition...)
+		s.sortState = &sortState
+	}
+	src.Cols.ForEach(func(_ int, col *Col) {
+		c := col.copyToRange(col.Min, col.Max)
+		c.style = copyStyle(col.style)
+		s.Cols.Add(c)
+	})
+
+	err := s.copyRowsFrom(src, 0, 0, copyStyle)
+	if err != nil {
+		return err
+	}
+	if src.MaxRow > s.MaxRow {
+		s.MaxRow = src.MaxRow
+	}
+	return nil
+}
+
+// copyRowsFrom copies the rows of src, from the zero based index from
+// onwards, to the Sheet, dy rows further down.  The relative
+// references of formulas move with their rows.
+func (s *Sheet) copyRowsFrom(src *Sheet, from, dy int, copyStyle func(style *Style) *Style) error {
+	err := src.ForEachRow(func(srcRow *Row) error {
+		if srcRow.num < from {
+			return nil
+		}
+		row, err := s.Row(srcRow.num + dy)
+		if err != nil {
+			return err
+		}
+		row.cellStoreRow.Updatable()
+		row.Hidden = srcRow.Hidden
+		row.Collapsed = srcRow.Collapsed
+		row.style = copyStyle(srcRow.style)
+		row.height = srcRow.height
+		row.customHeight = srcRow.customHeight
+		row.outlineLevel = srcRow.outlineLevel
+		row.isCustom = true
+		return srcRow.ForEachCell(func(srcCell *Cell) error {
+			cell := row.GetCell(srcCell.num)
+			cell.Row = row
+			cell.updatable()
+			cell.copyContents(srcCell, 0, dy, true)
+			cell.style = copyStyle(srcCell.style)
+			cell.DataValidation = copyDataValidation(srcCell.DataValidation)
+			row.PushCell(cell)
+			return nil
+		}, SkipEmptyCells)
+	}, SkipEmptyRows)
+	if err != nil {
+		return err
+	}
+	if src.MaxCol > s.MaxCol {
+		s.MaxCol = src.MaxCol
+	}
+	return nil
+}
+
 func (s *Sheet) getState() string {
 	if s.Hidden {
 		return "hidden"
@@ -65,8 +214,24 @@ func (s *Sheet) getState() string {
 	return "visible"
 }
 
+// SheetView describes how a Sheet is shown in a window.  The zero
+// value is the view Excel shows by default.
 type SheetView struct {
 	Pane *Pane
+	// ZoomScale is the magnification of the view, as a percentage
+	// from 10 to 400.  Zero means 100.
+	ZoomScale         int
+	HideGridLines     bool
+	HideRowColHeaders bool
+	RightToLeft       bool
+	// TopLeftCell is the cell shown at the top left of the view,
+	// such as "A1".  Empty means "A1".
+	TopLeftCell string
+	// ActiveCell is the cell the cursor is in, and Selection the
+	// cells that are selected, as a space separated list of ranges
+	// such as "B2:C4 E2".  Both default to "A1".
+	ActiveCell string
+	Selection  string
 }
 
 type Pane struct {
@@ -139,7 +304,9 @@ type rowVisitorFlags struct {
 type RowVisitorOption func(flags *rowVisitorFlags)
 
 // SkipEmptyRows can be passed to the Sheet.ForEachRow function to
-// cause it to skip over empty Rows.
+// cause it to skip over empty Rows.  A Row without cells isn't empty
+// if it's hidden, part of an outline or styled, as it still changes
+// how the Sheet looks.
 func SkipEmptyRows(flags *rowVisitorFlags) {
 	flags.skipEmptyRows = true
 }
@@ -179,7 +346,7 @@ func (s *Sheet) ForEachRow(rv RowVisitor, options ...RowVisitorOption) error {
 			r = s.cellStore.MakeRow(s)
 			r.num = i
 		}
-		if r.cellStoreRow.CellCount() == 0 && flags.skipEmptyRows {
+		if r.cellStoreRow.CellCount() == 0 && flags.skipEmptyRows && !r.isFormatted() {
 			continue
 		}
 		r.Sheet = s
@@ -192,7 +359,11 @@ func (s *Sheet) ForEachRow(rv RowVisitor, options ...RowVisitorOption) error {
 	return nil
 }
 
-// Add a new Row to a Sheet
+// Add a new Row to a Sheet.  AddRow doesn't stop a Sheet from growing
+// past the last row Excel allows; that is only checked when the File
+// is written, which then returns a LimitError.  Use AddRowAtIndex with
+// the index MaxRow, or a RolloverSheet, to find out as soon as the
+// Sheet is full.
 func (s *Sheet) AddRow() *Row {
 	s.mustBeOpen()
 	// NOTE - this is not safe to use concurrently
@@ -216,20 +387,30 @@ func (s *Sheet) AddRowAtIndex(index int) (*Row, error) {
 	if index < 0 || index > s.MaxRow {
 		return nil, errors.New("AddRowAtIndex: index out of bounds")
 	}
-
-	if s.currentRow != nil {
-		s.cellStore.WriteRow(s.currentRow)
+	if s.MaxRow >= Excel2006MaxRowCount {
+		return nil, fmt.Errorf("AddRowAtIndex: %w", &LimitError{Sheet: s.Name, Msg: fmt.Sprintf("Excel allows at most %d rows", Excel2006MaxRowCount)})
 	}
-
-	// We move rows in reverse order to avoid overwriting anyting
-	for i := (s.MaxRow - 1); i >= index; i-- {
-		nRow, err := s.cellStore.ReadRow(makeRowKey(s, i), s)
-		if err != nil {
-			continue
+	move := func() error {
+		if s.currentRow != nil {
+			s.cellStore.WriteRow(s.currentRow)
 		}
-		nRow.Sheet = s
-		s.setCurrentRow(nRow)
-		s.cellStore.MoveRow(nRow, i+1)
+		// We move rows in reverse order to avoid overwriting anyting
+		for i := (s.MaxRow - 1); i >= index; i-- {
+			nRow, err := s.cellStore.ReadRow(makeRowKey(s, i), s)
+			if err != nil {
+				continue
+			}
+			nRow.Sheet = s
+			s.setCurrentRow(nRow)
+			if err := s.cellStore.MoveRow(nRow, i+1); err != nil {
+				return fmt.Errorf("AddRowAtIndex: %w", err)
+			}
+		}
+		s.MaxRow++
+		return nil
+	}
+	if err := (refShift{sheet: s, at: index, n: 1}).apply(move); err != nil {
+		return nil, err
 	}
 	row := s.cellStore.MakeRow(s)
 	row.num = index
@@ -238,7 +419,7 @@ func (s *Sheet) AddRowAtIndex(index int) (*Row, error) {
 	if err != nil {
 		return nil, err
 	}
-	s.MaxRow++
+	s.RowBreaks = shiftBreaks(s.RowBreaks, index, 1)
 	return row, nil
 }
 
@@ -254,25 +435,323 @@ func (s *Sheet) RemoveRowAtIndex(index int) error {
 	if index < 0 || index >= s.MaxRow {
 		return fmt.Errorf("Cannot remove row: index out of range: %d", index)
 	}
-	if s.currentRow != nil {
+	move := func() error {
+		if s.currentRow != nil {
+			s.setCurrentRow(nil)
+		}
+		err := s.cellStore.RemoveRow(makeRowKey(s, index))
+		if err != nil {
+			return err
+		}
+		for i := index + 1; i < s.MaxRow; i++ {
+			nRow, err := s.cellStore.ReadRow(makeRowKey(s, i), s)
+			if err != nil {
+				continue
+			}
+			nRow.Sheet = s
+			if err := s.cellStore.MoveRow(nRow, i-1); err != nil {
+				return fmt.Errorf("RemoveRowAtIndex: %w", err)
+			}
+		}
+		s.MaxRow--
+		return nil
+	}
+	if err := (refShift{sheet: s, at: index, n: -1}).apply(move); err != nil {
+		return err
+	}
+	s.RowBreaks = shiftBreaks(s.RowBreaks, index+1, -1)
+	return nil
+}
+
+// InsertCols inserts n empty columns before the column with the given
+// zero-based index, moving the cells, column definitions, merged
+// cells and page breaks to their right, and rewriting the references
+// to them throughout the File.
+func (s *Sheet) InsertCols(at, n int) error {
+	s.mustBeOpen()
+	if at < 0 || at > Excel2006MaxColIndex {
+		return fmt.Errorf("InsertCols: index out of range: %d", at)
+	}
+	if n < 1 {
+		return fmt.Errorf("InsertCols: invalid number of columns: %d", n)
+	}
+	if at < s.MaxCol && s.MaxCol+n > Excel2006MaxColCount {
+		return fmt.Errorf("InsertCols: inserting %d columns would move cells beyond the last column of the sheet", n)
+	}
+	if !s.canShiftCells() {
+		return fmt.Errorf("InsertCols: the sheet's cell store can't move cells along a row")
+	}
+	return s.shiftCols(refShift{sheet: s, cols: true, at: at, n: n})
+}
+
+// RemoveCols removes n columns, starting with the column with the
+// given zero-based index, moving the cells, column definitions,
+// merged cells and page breaks to their right into their place.
+// References to the removed cells throughout the File become #REF!
+// errors.
+func (s *Sheet) RemoveCols(at, n int) error {
+	s.mustBeOpen()
+	if at < 0 || at > Excel2006MaxColIndex {
+		return fmt.Errorf("RemoveCols: index out of range: %d", at)
+	}
+	if n < 1 {
+		return fmt.Errorf("RemoveCols: invalid number of columns: %d", n)
+	}
+	if !s.canShiftCells() {
+		return fmt.Errorf("RemoveCols: the sheet's cell store can't move cells along a row")
+	}
+	return s.shiftCols(refShift{sheet: s, cols: true, at: at, n: -n})
+}
+
+// canShiftCells returns true if the rows of the Sheet's cell store can
+// move their cells along, which the cell stores in this package can.
+// Only the first row is looked at, as they all come from one store.
+func (s *Sheet) canShiftCells() bool {
+	ok := true
+	errFirstRow := errors.New("first row")
+	s.ForEachRow(func(row *Row) error {
+		_, ok = row.cellStoreRow.(cellShifter)
+		return errFirstRow
+	}, SkipEmptyRows)
+	return ok
+}
+
+func (s *Sheet) shiftCols(sh refShift) error {
+	move := func() error {
+		err := s.ForEachRow(func(row *Row) error {
+			row.cellStoreRow.(cellShifter).ShiftCells(sh.at, sh.n)
+			row.isCustom = true
+			return nil
+		}, SkipEmptyRows)
+		if err != nil {
+			return err
+		}
+		// Make sure the last row is persisted, too
 		s.setCurrentRow(nil)
+		return nil
 	}
-	err := s.cellStore.RemoveRow(makeRowKey(s, index))
-	if err != nil {
+	if err := sh.apply(move); err != nil {
 		return err
 	}
-	for i := index + 1; i < s.MaxRow; i++ {
-		nRow, err := s.cellStore.ReadRow(makeRowKey(s, i), s)
+	if s.Cols != nil {
+		s.Cols.shift(sh)
+	}
+	if sh.n > 0 {
+		if sh.at < s.MaxCol {
+			s.MaxCol += sh.n
+		}
+	} else if sh.at < s.MaxCol {
+		s.MaxCol -= minInt(sh.at-sh.n, s.MaxCol) - sh.at
+	}
+	if sh.n > 0 {
+		s.ColBreaks = shiftBreaks(s.ColBreaks, sh.at, sh.n)
+		return nil
+	}
+	// Breaks before the removed columns, other than the first,
+	// go with them
+	breaks := s.ColBreaks[:0]
+	for _, b := range s.ColBreaks {
+		if b <= sh.at || b >= sh.at-sh.n {
+			breaks = append(breaks, b)
+		}
+	}
+	s.ColBreaks = shiftBreaks(breaks, sh.at+1, sh.n)
+	return nil
+}
+
+// AddRowBreak adds a manual page break before the row with the given
+// zero-based index, so that this row is the first one printed on a
+// new page.
+func (s *Sheet) AddRowBreak(row int) error {
+	if row <= 0 || row > Excel2006MaxRowIndex {
+		return fmt.Errorf("AddRowBreak: row index out of range: %d", row)
+	}
+	s.RowBreaks = addBreak(s.RowBreaks, row)
+	return nil
+}
+
+// AddColBreak adds a manual page break before the column with the
+// given zero-based index, so that this column is the first one
+// printed on a new page.
+func (s *Sheet) AddColBreak(col int) error {
+	if col <= 0 || col > Excel2006MaxColIndex {
+		return fmt.Errorf("AddColBreak: column index out of range: %d", col)
+	}
+	s.ColBreaks = addBreak(s.ColBreaks, col)
+	return nil
+}
+
+// addBreak inserts a break into the sorted slice of breaks, unless
+// it's already there.
+func addBreak(breaks []int, index int) []int {
+	i := sort.SearchInts(breaks, index)
+	if i < len(breaks) && breaks[i] == index {
+		return breaks
+	}
+	breaks = append(breaks, 0)
+	copy(breaks[i+1:], breaks[i:])
+	breaks[i] = index
+	return breaks
+}
+
+// SetPrintArea restricts printing of the sheet to the given range,
+// e.g. "A1:F40".  An empty string clears the print area.
+func (s *Sheet) SetPrintArea(ref string) error {
+	if ref == "" {
+		s.PrintArea = nil
+		return nil
+	}
+	r, err := ParseCellRange(ref)
+	if err != nil {
+		return fmt.Errorf("SetPrintArea: %w", err)
+	}
+	s.PrintArea = &r
+	return nil
+}
+
+// SetPrintTitles sets the rows, e.g. "1:2", and the columns, e.g.
+// "A:A", that are repeated on every printed page.  An empty string
+// clears the corresponding titles.
+func (s *Sheet) SetPrintTitles(rows, cols string) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("SetPrintTitles: %w", err)
+	}
+	var titleRows, titleCols *CellRange
+	if rows != "" {
+		r, err := ParseCellRange(rows)
 		if err != nil {
-			continue
+			return wrap(err)
 		}
-		nRow.Sheet = s
-		s.cellStore.MoveRow(nRow, i-1)
+		if !r.IsWholeRows() {
+			return wrap(fmt.Errorf("%q is not a range of whole rows", rows))
+		}
+		titleRows = &r
 	}
-	s.MaxRow--
+	if cols != "" {
+		r, err := ParseCellRange(cols)
+		if err != nil {
+			return wrap(err)
+		}
+		if !r.IsWholeCols() {
+			return wrap(fmt.Errorf("%q is not a range of whole columns", cols))
+		}
+		titleCols = &r
+	}
+	s.PrintTitleRows = titleRows
+	s.PrintTitleCols = titleCols
 	return nil
 }
 
+// makeBuiltInDefinedNames returns the built-in defined names, such as
+// the print area, that describe this sheet when it is written at the
+// given position in the workbook.
+func (s *Sheet) makeBuiltInDefinedNames(sheetIndex int) []xlsxDefinedName {
+	var names []xlsxDefinedName
+	if s.PrintArea != nil {
+		names = append(names, xlsxDefinedName{
+			Name:         printAreaName,
+			LocalSheetID: &sheetIndex,
+			Data:         sheetRef(s.Name, s.PrintArea.AbsoluteString()),
+		})
+	}
+	var titles []string
+	if s.PrintTitleCols != nil {
+		titles = append(titles, sheetRef(s.Name, s.PrintTitleCols.AbsoluteString()))
+	}
+	if s.PrintTitleRows != nil {
+		titles = append(titles, sheetRef(s.Name, s.PrintTitleRows.AbsoluteString()))
+	}
+	if len(titles) > 0 {
+		names = append(names, xlsxDefinedName{
+			Name:         printTitlesName,
+			LocalSheetID: &sheetIndex,
+			Data:         strings.Join(titles, ","),
+		})
+	}
+	return names
+}
+
+// readBuiltInDefinedName applies a built-in defined name, scoped to
+// this sheet, to the sheet.  It returns false if the name isn't one
+// that the Sheet manages, or if its content can't be represented,
+// in which case the name should be kept as it is.
+func (s *Sheet) readBuiltInDefinedName(dn *DefinedName) bool {
+	var ranges []CellRange
+	for _, ref := range splitRefList(dn.Data) {
+		_, ref, err := splitSheetRef(strings.TrimSpace(ref))
+		if err != nil {
+			return false
+		}
+		r, err := ParseCellRange(ref)
+		if err != nil {
+			return false
+		}
+		ranges = append(ranges, r)
+	}
+
+	switch dn.Name {
+	case printAreaName:
+		if len(ranges) != 1 {
+			return false
+		}
+		s.PrintArea = &ranges[0]
+		return true
+	case printTitlesName:
+		var rows, cols *CellRange
+		for i := range ranges {
+			switch {
+			case ranges[i].IsWholeRows() && rows == nil:
+				rows = &ranges[i]
+			case ranges[i].IsWholeCols() && cols == nil:
+				cols = &ranges[i]
+			default:
+				return false
+			}
+		}
+		s.PrintTitleRows = rows
+		s.PrintTitleCols = cols
+		return true
+	}
+	return false
+}
+
+// makeXLSXBreaks returns the rowBreaks or colBreaks element for the
+// given breaks, or nil if there are none.  max is the index of the
+// last cell that each break spans.
+func makeXLSXBreaks(breaks []int, max int) *xlsxBreaks {
+	if len(breaks) == 0 {
+		return nil
+	}
+	xBreaks := &xlsxBreaks{
+		Count:            len(breaks),
+		ManualBreakCount: len(breaks),
+	}
+	for _, b := range breaks {
+		xBreaks.Brk = append(xBreaks.Brk, xlsxBreak{Id: b, Max: max, Man: true})
+	}
+	return xBreaks
+}
+
+// shiftBreaks moves every break at or after from by delta.  Breaks
+// that end up at the very start of the sheet, or on top of another
+// break, are dropped.
+func shiftBreaks(breaks []int, from, delta int) []int {
+	if len(breaks) == 0 {
+		return breaks
+	}
+	shifted := make([]int, 0, len(breaks))
+	for _, b := range breaks {
+		if b >= from {
+			b += delta
+		}
+		if b <= 0 || (len(shifted) > 0 && shifted[len(shifted)-1] == b) {
+			continue
+		}
+		shifted = append(shifted, b)
+	}
+	return shifted
+}
+
 // Make sure we always have as many Rows as we do cells.
 func (s *Sheet) maybeAddRow(rowCount int) {
 	s.mustBeOpen()
@@ -290,6 +769,9 @@ func (s *Sheet) maybeAddRow(rowCount int) {
 // Make sure we always have as many Rows as we do cells.
 func (s *Sheet) Row(idx int) (*Row, error) {
 	s.mustBeOpen()
+	if idx > Excel2006MaxRowIndex {
+		return nil, rowLimitError(s, idx)
+	}
 	s.maybeAddRow(idx + 1)
 	if s.currentRow != nil && idx == s.currentRow.num {
 		return s.currentRow, nil
@@ -330,6 +812,9 @@ func (s *Sheet) Col(idx int) *Col {
 // containing the data from the field "A1" on the spreadsheet.
 func (s *Sheet) Cell(row, col int) (*Cell, error) {
 	s.mustBeOpen()
+	if row > Excel2006MaxRowIndex {
+		return nil, rowLimitError(s, row)
+	}
 	// If the user requests a row beyond what we have, then extend.
 	for s.MaxRow <= row {
 		s.AddRow()
@@ -392,7 +877,9 @@ func (s *Sheet) setCol(min, max int, setter func(col *Col)) {
 			setter(newCol)
 			s.Cols.Add(newCol)
 		default:
-			newCol := col.copyToRange(min, max)
+			// The column lies within the range, which may
+			// hold other columns that differ from it
+			newCol := col.copyToRange(col.Min, col.Max)
 			setter(newCol)
 			s.Cols.Add(newCol)
 
@@ -460,6 +947,155 @@ func (s *Sheet) SetType(minCol, maxCol int, cellType CellType) {
 
 }
 
+// MergedRegions returns the blocks of merged cells on the Sheet, in
+// the order of their top left cells.
+func (s *Sheet) MergedRegions() ([]CellRange, error) {
+	s.mustBeOpen()
+	var regions []CellRange
+	err := s.ForEachRow(func(row *Row) error {
+		return row.ForEachCell(func(cell *Cell) error {
+			if cell.HMerge > 0 || cell.VMerge > 0 {
+				regions = append(regions, NewCellRange(cell.num, row.num, cell.num+cell.HMerge, row.num+cell.VMerge))
+			}
+			return nil
+		}, SkipEmptyCells)
+	}, SkipEmptyRows)
+	if err != nil {
+		return nil, err
+	}
+	return regions, nil
+}
+
+// mergedRegionsIn returns the blocks of merged cells that overlap the
+// range, in the order of their top left cells.  A block is only known
+// by its top left cell, which may lie any distance above or to the
+// left of the range, so the scan starts at the first row, but it stops
+// after the last row of the range and passes over the cells beyond its
+// last column.
+func (s *Sheet) mergedRegionsIn(r CellRange) ([]CellRange, error) {
+	var regions []CellRange
+	errDone := errors.New("done")
+	err := s.ForEachRow(func(row *Row) error {
+		if row.num > r.MaxRow {
+			return errDone
+		}
+		return row.ForEachCell(func(cell *Cell) error {
+			if cell.num > r.MaxCol || (cell.HMerge == 0 && cell.VMerge == 0) {
+				return nil
+			}
+			region := NewCellRange(cell.num, row.num, cell.num+cell.HMerge, row.num+cell.VMerge)
+			if _, ok := region.Intersect(r); ok {
+				regions = append(regions, region)
+			}
+			return nil
+		}, SkipEmptyCells)
+	}, SkipEmptyRows)
+	if err != nil && err != errDone {
+		return nil, err
+	}
+	return regions, nil
+}
+
+// MergedRegionAt returns the top left cell of the block of merged
+// cells that covers the cell at the given zero based row and column,
+// along with the block.  The cell returned is nil if the cell isn't
+// merged.
+func (s *Sheet) MergedRegionAt(row, col int) (*Cell, CellRange, error) {
+	s.mustBeOpen()
+	regions, err := s.mergedRegionsIn(NewCellRange(col, row, col, row))
+	if err != nil {
+		return nil, CellRange{}, err
+	}
+	if len(regions) == 0 {
+		return nil, CellRange{}, nil
+	}
+	region := regions[0]
+	cell, err := s.Cell(region.MinRow, region.MinCol)
+	if err != nil {
+		return nil, CellRange{}, err
+	}
+	return cell, region, nil
+}
+
+// MergeRange merges the cells of the range into a single block, whose
+// value is that of its top left cell.  It returns an error if the
+// range is a single cell, or overlaps a block that is already merged,
+// as Excel treats a file with overlapping merges as corrupt.
+func (s *Sheet) MergeRange(r CellRange) error {
+	s.mustBeOpen()
+	if r.Width() == 1 && r.Height() == 1 {
+		return fmt.Errorf("MergeRange(%q): a single cell can't be merged", r.String())
+	}
+	regions, err := s.mergedRegionsIn(r)
+	if err != nil {
+		return err
+	}
+	if len(regions) > 0 {
+		return fmt.Errorf("MergeRange(%q): the range overlaps the merged cells %s", r.String(), regions[0].String())
+	}
+	return s.setMerge(r.MinRow, r.MinCol, r.Width()-1, r.Height()-1)
+}
+
+// Unmerge splits every block of merged cells that overlaps the range
+// back into separate cells.
+func (s *Sheet) Unmerge(r CellRange) error {
+	s.mustBeOpen()
+	regions, err := s.mergedRegionsIn(r)
+	if err != nil {
+		return err
+	}
+	for _, region := range regions {
+		if err := s.setMerge(region.MinRow, region.MinCol, 0, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setMerge sets the extent of the merge whose top left cell is at the
+// given row and column, storing the cell whatever the cell store.
+func (s *Sheet) setMerge(row, col, hcells, vcells int) error {
+	r, err := s.Row(row)
+	if err != nil {
+		return err
+	}
+	cell := r.GetCell(col)
+	cell.Row = r
+	cell.Merge(hcells, vcells)
+	r.PushCell(cell)
+	return nil
+}
+
+// checkMergeCells returns an error if any of the merged cells of a
+// worksheet overlap, as Excel treats such a file as corrupt.
+func checkMergeCells(mcs *xlsxMergeCells) error {
+	if mcs == nil {
+		return nil
+	}
+	regions := make([]CellRange, 0, len(mcs.Cells))
+	for _, mc := range mcs.Cells {
+		r, err := ParseCellRange(mc.Ref)
+		if err != nil {
+			return err
+		}
+		regions = append(regions, r)
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].MinRow < regions[j].MinRow
+	})
+	for i, a := range regions {
+		for _, b := range regions[i+1:] {
+			if b.MinRow > a.MaxRow {
+				break
+			}
+			if _, ok := a.Intersect(b); ok {
+				return fmt.Errorf("the merged cells %s and %s overlap", a.String(), b.String())
+			}
+		}
+	}
+	return nil
+}
+
 // When merging cells, the cell may be the 'original' or the 'covered'.
 // First, figure out which cells are merge starting points. Then create
 // the necessary cells underlying the merge area.
@@ -497,21 +1133,48 @@ func (s *Sheet) handleMerged() {
 
 func (s *Sheet) makeSheetView(worksheet *xlsxWorksheet) {
 	for index, sheetView := range s.SheetViews {
+		// The workbook only has a single window to show the sheet in
+		if index >= len(worksheet.SheetViews.SheetView) {
+			break
+		}
+		xView := &worksheet.SheetViews.SheetView[index]
+		selection := &xView.Selection[0]
 		if sheetView.Pane != nil {
-			worksheet.SheetViews.SheetView[index].Pane = &xlsxPane{
+			xView.Pane = &xlsxPane{
 				XSplit:      sheetView.Pane.XSplit,
 				YSplit:      sheetView.Pane.YSplit,
 				TopLeftCell: sheetView.Pane.TopLeftCell,
 				ActivePane:  sheetView.Pane.ActivePane,
 				State:       sheetView.Pane.State,
 			}
-
+			if sheetView.Pane.ActivePane != "" {
+				selection.Pane = sheetView.Pane.ActivePane
+			}
+		}
+		if sheetView.ZoomScale != 0 {
+			xView.ZoomScale = float64(sheetView.ZoomScale)
+			xView.ZoomScaleNormal = float64(sheetView.ZoomScale)
+		}
+		xView.ShowGridLines = !sheetView.HideGridLines
+		xView.ShowRowColHeaders = !sheetView.HideRowColHeaders
+		xView.RightToLeft = sheetView.RightToLeft
+		if sheetView.TopLeftCell != "" {
+			xView.TopLeftCell = sheetView.TopLeftCell
+		}
+		if sheetView.ActiveCell != "" {
+			selection.ActiveCell = sheetView.ActiveCell
+			selection.SQRef = sheetView.ActiveCell
+		}
+		if sheetView.Selection != "" {
+			selection.SQRef = sheetView.Selection
 		}
 	}
 	if s.Selected {
 		worksheet.SheetViews.SheetView[0].TabSelected = true
 	}
-
+	if s.TabColor != "" {
+		worksheet.SheetPr.TabColor = &xlsxColor{RGB: s.TabColor}
+	}
 }
 
 func (s *Sheet) makeSheetFormatPr(worksheet *xlsxWorksheet) {
@@ -654,10 +1317,17 @@ func (s *Sheet) prepWorksheetFromRows(worksheet *xlsxWorksheet, relations *xlsxW
 	if worksheet.MergeCells != nil {
 		worksheet.MergeCells.Count = len(worksheet.MergeCells.Cells)
 	}
+	if err := checkMergeCells(worksheet.MergeCells); err != nil {
+		return err
+	}
 
 	if s.AutoFilter != nil {
 		worksheet.AutoFilter = &xlsxAutoFilter{Ref: fmt.Sprintf("%v:%v", s.AutoFilter.TopLeftCell, s.AutoFilter.BottomRightCell)}
 	}
+	worksheet.SortState = s.sortState
+	worksheet.HeaderFooter = s.HeaderFooter.makeXLSXHeaderFooter()
+	worksheet.RowBreaks = makeXLSXBreaks(s.RowBreaks, Excel2006MaxColIndex)
+	worksheet.ColBreaks = makeXLSXBreaks(s.ColBreaks, Excel2006MaxRowIndex)
 
 	dimension := xlsxDimension{}
 	dimension.Ref = "A1:" + GetCellIDStringFromCoords(maxCell, maxRow)
@@ -674,7 +1344,14 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 	maxCell := 0
 	var maxLevelRow uint8
 	xSheet := xlsxSheetData{}
+	shared, err := s.makeSharedFormulas()
+	if err != nil {
+		return err
+	}
 	makeR := func(row *Row) error {
+		if err := row.checkLimits(); err != nil {
+			return err
+		}
 		r := row.num
 		if r > maxRow {
 			maxRow = r
@@ -685,11 +1362,20 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 			xRow.CustomHeight = true
 			xRow.Ht = fmt.Sprintf("%g", row.GetHeight())
 		}
+		xRow.Hidden = row.Hidden
+		xRow.Collapsed = row.Collapsed
+		if row.style != nil {
+			xRow.S = handleStyleForXLSX(row.style, 0, styles)
+			xRow.CustomFormat = true
+		}
 		xRow.OutlineLevel = row.GetOutlineLevel()
 		if xRow.OutlineLevel > maxLevelRow {
 			maxLevelRow = xRow.OutlineLevel
 		}
 		makeC := func(cell *Cell) error {
+			if err := cell.checkLimits(row); err != nil {
+				return err
+			}
 			var XfId int
 
 			c := cell.num
@@ -702,6 +1388,11 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 			xNumFmt := styles.newNumFmt(cell.NumFmt)
 
 			style := cell.style
+			if style == nil {
+				// The style of the row takes precedence over
+				// that of the column
+				style = row.style
+			}
 			switch {
 			case style != nil:
 				XfId = handleStyleForXLSX(style, xNumFmt.NumFmtId, styles)
@@ -720,9 +1411,7 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 				S: XfId,
 				R: GetCellIDStringFromCoords(c, r),
 			}
-			if cell.formula != "" {
-				xC.F = &xlsxF{Content: cell.formula}
-			}
+			s.makeXLSXFormula(&xC, cell, r, shared)
 			switch cell.cellType {
 			case CellTypeInline:
 				// Inline strings are turned into shared strings since they are more efficient.
@@ -811,7 +1500,7 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 		return nil
 	}
 
-	err := s.ForEachRow(makeR, SkipEmptyRows)
+	err = s.ForEachRow(makeR, SkipEmptyRows)
 	if err != nil {
 		return err
 	}
@@ -825,10 +1514,17 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 	if worksheet.MergeCells != nil {
 		worksheet.MergeCells.Count = len(worksheet.MergeCells.Cells)
 	}
+	if err := checkMergeCells(worksheet.MergeCells); err != nil {
+		return err
+	}
 
 	if s.AutoFilter != nil {
 		worksheet.AutoFilter = &xlsxAutoFilter{Ref: fmt.Sprintf("%v:%v", s.AutoFilter.TopLeftCell, s.AutoFilter.BottomRightCell)}
 	}
+	worksheet.SortState = s.sortState
+	worksheet.HeaderFooter = s.HeaderFooter.makeXLSXHeaderFooter()
+	worksheet.RowBreaks = makeXLSXBreaks(s.RowBreaks, Excel2006MaxColIndex)
+	worksheet.ColBreaks = makeXLSXBreaks(s.ColBreaks, Excel2006MaxRowIndex)
 
 	worksheet.SheetData = xSheet
 	dimension := xlsxDimension{}
@@ -840,6 +1536,79 @@ func (s *Sheet) makeRows(worksheet *xlsxWorksheet, styles *xlsxStyleSheet, refTa
 	return nil
 }
 
+// makeSharedFormulas finds the runs of cells down a column whose
+// formulas are the same but for their relative references, if the
+// File writes shared formulas, and returns the f elements to write
+// for each of their cells.
+func (s *Sheet) makeSharedFormulas() (map[evalPos]*xlsxF, error) {
+	if s.File == nil || !s.File.sharedFormulas {
+		return nil, nil
+	}
+	type run struct {
+		col, first, last int
+		formula          string
+	}
+	// runs holds the runs in the order they start, and columns the
+	// latest run in each column
+	var runs []*run
+	columns := make(map[int]*run)
+	err := s.ForEachRow(func(row *Row) error {
+		return row.ForEachCell(func(cell *Cell) error {
+			if cell.formula == "" || cell.arrayRef != "" {
+				return nil
+			}
+			r := columns[cell.num]
+			if r != nil && r.last == row.num-1 && shiftFormula(r.formula, 0, row.num-r.first) == cell.formula {
+				r.last = row.num
+				return nil
+			}
+			r = &run{col: cell.num, first: row.num, last: row.num, formula: cell.formula}
+			columns[cell.num] = r
+			runs = append(runs, r)
+			return nil
+		}, SkipEmptyCells)
+	}, SkipEmptyRows)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := make(map[evalPos]*xlsxF)
+	si := 0
+	for _, r := range runs {
+		if r.first == r.last {
+			continue
+		}
+		index := si
+		si++
+		shared[evalPos{col: r.col, row: r.first}] = &xlsxF{
+			Content: r.formula,
+			T:       "shared",
+			Ref:     NewCellRange(r.col, r.first, r.col, r.last).String(),
+			Si:      &index,
+		}
+		for row := r.first + 1; row <= r.last; row++ {
+			shared[evalPos{col: r.col, row: row}] = &xlsxF{T: "shared", Si: &index}
+		}
+	}
+	return shared, nil
+}
+
+// makeXLSXFormula sets the formula of the c element for a cell, using
+// the shared formulas found by makeSharedFormulas.
+func (s *Sheet) makeXLSXFormula(xC *xlsxC, cell *Cell, row int, shared map[evalPos]*xlsxF) {
+	if f, ok := shared[evalPos{col: cell.num, row: row}]; ok {
+		xC.F = f
+		return
+	}
+	xC.F = cell.makeXLSXFormula()
+	if cell.dynamicArray && cell.arrayRef != "" {
+		// This refers to the only cell metadata record in
+		// TEMPLATE_XL_METADATA, which marks dynamic arrays
+		xC.Cm = 1
+		s.dynamicArrays = true
+	}
+}
+
 func (s *Sheet) makeDataValidations(worksheet *xlsxWorksheet) {
 	s.mustBeOpen()
 	if len(s.DataValidations) > 0 {
@@ -856,6 +1625,7 @@ func (s *Sheet) MarshalSheet(w io.Writer, refTable *RefTable, styles *xlsxStyleS
 
 	s.handleMerged()
 	s.makeSheetView(worksheet)
+	s.makeOutlinePr(worksheet)
 	s.makeSheetFormatPr(worksheet)
 	maxLevelCol := s.makeCols(worksheet, styles)
 	s.makeDataValidations(worksheet)
@@ -864,6 +1634,7 @@ func (s *Sheet) MarshalSheet(w io.Writer, refTable *RefTable, styles *xlsxStyleS
 	if err != nil {
 		return err
 	}
+	s.dynamicArrays = false
 	xw := xmlwriter.Open(w)
 
 	err = xw.StartDoc(xmlwriter.Doc{})
@@ -879,7 +1650,7 @@ func (s *Sheet) MarshalSheet(w io.Writer, refTable *RefTable, styles *xlsxStyleS
 }
 
 // Dump sheet to its XML representation, intended for internal use only
-func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet, relations *xlsxWorksheetRels) *xlsxWorksheet {
+func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet, relations *xlsxWorksheetRels) (*xlsxWorksheet, error) {
 	s.mustBeOpen()
 	worksheet := newXlsxWorksheet()
 
@@ -889,15 +1660,27 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet, relati
 	s.handleMerged()
 
 	s.makeSheetView(worksheet)
+	s.makeOutlinePr(worksheet)
 	s.makeSheetFormatPr(worksheet)
 	maxLevelCol := s.makeCols(worksheet, styles)
 	s.makeDataValidations(worksheet)
-	s.makeRows(worksheet, styles, refTable, relations, maxLevelCol)
+	s.dynamicArrays = false
+	err := s.makeRows(worksheet, styles, refTable, relations, maxLevelCol)
+	if err != nil {
+		return nil, err
+	}
 
-	return worksheet
+	return worksheet, nil
 }
 
 func handleStyleForXLSX(style *Style, NumFmtId int, styles *xlsxStyleSheet) (XfId int) {
+	XfId = styles.addCellXf(makeXfForXLSX(style, NumFmtId, styles))
+	return
+}
+
+// makeXfForXLSX adds the font, fill and border of the style to the
+// style sheet, and returns the xf that refers to them.
+func makeXfForXLSX(style *Style, NumFmtId int, styles *xlsxStyleSheet) xlsxXf {
 	xFont, xFill, xBorder, xCellXf := style.makeXLSXStyleElements()
 	fontId := styles.addFont(xFont)
 	fillId := styles.addFill(xFill)
@@ -923,9 +1706,7 @@ func handleStyleForXLSX(style *Style, NumFmtId int, styles *xlsxStyleSheet) (XfI
 	xCellXf.Alignment.TextRotation = style.Alignment.TextRotation
 	xCellXf.Alignment.Vertical = style.Alignment.Vertical
 	xCellXf.Alignment.WrapText = style.Alignment.WrapText
-
-	XfId = styles.addCellXf(xCellXf)
-	return
+	return xCellXf
 }
 
 func handleNumFmtIdForXLSX(NumFmtId int, styles *xlsxStyleSheet) (XfId int) {
@@ -177,6 +177,108 @@ func TestSheet(t *testing.T) {
 		c.Assert(sheet.MaxRow, qt.Equals, 11)
 	})
 
+	csRunO(c, "TestPageBreaks", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("MySheet")
+		c.Assert(err, qt.IsNil)
+		for i := 0; i < 10; i++ {
+			sheet.AddRow().AddCell().SetInt(i)
+		}
+
+		c.Assert(sheet.AddRowBreak(0), qt.Not(qt.IsNil))
+		c.Assert(sheet.AddColBreak(Excel2006MaxColCount), qt.Not(qt.IsNil))
+		c.Assert(sheet.AddRowBreak(6), qt.IsNil)
+		c.Assert(sheet.AddRowBreak(3), qt.IsNil)
+		c.Assert(sheet.AddRowBreak(6), qt.IsNil)
+		c.Assert(sheet.AddColBreak(2), qt.IsNil)
+		c.Assert(sheet.RowBreaks, qt.DeepEquals, []int{3, 6})
+
+		_, err = sheet.AddRowAtIndex(4)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.RowBreaks, qt.DeepEquals, []int{3, 7})
+		_, err = sheet.AddRowAtIndex(3)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.RowBreaks, qt.DeepEquals, []int{4, 8})
+		err = sheet.RemoveRowAtIndex(0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.RowBreaks, qt.DeepEquals, []int{3, 7})
+		err = sheet.RemoveRowAtIndex(3)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.RowBreaks, qt.DeepEquals, []int{3, 6})
+
+		var buf bytes.Buffer
+		err = sheet.MarshalSheet(&buf, NewSharedStringRefTable(), newXlsxStyleSheet(nil), nil)
+		c.Assert(err, qt.IsNil)
+		c.Assert(buf.String(), qt.Contains, `<rowBreaks count="2" manualBreakCount="2"><brk id="3" max="16383" man="true"/><brk id="6" max="16383" man="true"/></rowBreaks><colBreaks count="1" manualBreakCount="1"><brk id="2" max="1048575" man="true"/></colBreaks></worksheet>`)
+
+		buf.Reset()
+		err = f.Write(&buf)
+		c.Assert(err, qt.IsNil)
+		f, err = OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.Sheets[0].RowBreaks, qt.DeepEquals, []int{3, 6})
+		c.Assert(f.Sheets[0].ColBreaks, qt.DeepEquals, []int{2})
+	})
+
+	csRunO(c, "TestPrintAreaAndTitles", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		first, err := f.AddSheet("First")
+		c.Assert(err, qt.IsNil)
+		first.AddRow().AddCell().Value = "first"
+		report, err := f.AddSheet("Q1 Report")
+		c.Assert(err, qt.IsNil)
+		report.AddRow().AddCell().Value = "report"
+
+		c.Assert(report.SetPrintArea("A1:F40"), qt.IsNil)
+		c.Assert(report.SetPrintTitles("1:2", "A:A"), qt.IsNil)
+		c.Assert(report.SetPrintArea("A1:"), qt.Not(qt.IsNil))
+		c.Assert(report.SetPrintTitles("A1:B2", ""), qt.Not(qt.IsNil))
+		c.Assert(report.SetPrintTitles("", "1:2"), qt.Not(qt.IsNil))
+		c.Assert(*report.PrintArea, qt.Equals, CellRange{0, 0, 5, 39})
+
+		// A user-supplied name that clashes with the sheet's own
+		// print area is replaced by it.
+		zero := 0
+		f.DefinedNames = append(f.DefinedNames,
+			&DefinedName{Name: "Total", Data: "'Q1 Report'!$F$41"},
+			&DefinedName{Name: printAreaName, localSheetID: &zero, Data: "First!$A$1"})
+
+		names := f.makeDefinedNames()
+		c.Assert(names, qt.HasLen, 4)
+		c.Assert(names[0].Name, qt.Equals, printAreaName)
+		c.Assert(*names[0].LocalSheetID, qt.Equals, 1)
+		c.Assert(names[0].Data, qt.Equals, "'Q1 Report'!$A$1:$F$40")
+		c.Assert(names[1].Name, qt.Equals, printTitlesName)
+		c.Assert(*names[1].LocalSheetID, qt.Equals, 1)
+		c.Assert(names[1].Data, qt.Equals, "'Q1 Report'!$A:$A,'Q1 Report'!$1:$2")
+		c.Assert(names[2].Name, qt.Equals, "Total")
+		c.Assert(names[2].LocalSheetID, qt.IsNil)
+		c.Assert(names[3].Name, qt.Equals, printAreaName)
+
+		// Reordering the sheets keeps the names pointing at the
+		// right sheet.
+		f.Sheets[0], f.Sheets[1] = f.Sheets[1], f.Sheets[0]
+		f.DefinedNames = f.DefinedNames[:1]
+		names = f.makeDefinedNames()
+		c.Assert(*names[0].LocalSheetID, qt.Equals, 0)
+		c.Assert(*names[1].LocalSheetID, qt.Equals, 0)
+
+		var buf bytes.Buffer
+		err = f.Write(&buf)
+		c.Assert(err, qt.IsNil)
+		f, err = OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+
+		report = f.Sheets[0]
+		c.Assert(report.Name, qt.Equals, "Q1 Report")
+		c.Assert(report.PrintArea, qt.DeepEquals, &CellRange{0, 0, 5, 39})
+		c.Assert(report.PrintTitleRows, qt.DeepEquals, &CellRange{0, 0, Excel2006MaxColIndex, 1})
+		c.Assert(report.PrintTitleCols, qt.DeepEquals, &CellRange{0, 0, 0, Excel2006MaxRowIndex})
+		c.Assert(f.Sheets[1].PrintArea, qt.IsNil)
+		c.Assert(f.DefinedNames, qt.HasLen, 1)
+		c.Assert(f.DefinedNames[0].Name, qt.Equals, "Total")
+	})
+
 	csRunO(c, "TestMakeXLSXSheetFromRows", func(c *qt.C, option FileOption) {
 		file := NewFile(option)
 		sheet, _ := file.AddSheet("Sheet1")
@@ -617,6 +719,23 @@ func TestMakeXLSXSheet(t *testing.T) {
 		c.Assert(sheet.Cols.FindColByIndex(2).Min, qt.Equals, 2)
 	})
 
+	csRunO(c, "SetColWidthOverMixedCols", func(c *qt.C, option FileOption) {
+		file := NewFile(option)
+		sheet, _ := file.AddSheet("Sheet1")
+		sheet.SetColWidth(2, 3, 20)
+		sheet.SetOutlineLevel(3, 3, 2)
+		sheet.SetColWidth(1, 5, 15)
+		for i := 1; i <= 5; i++ {
+			col := sheet.Cols.FindColByIndex(i)
+			c.Assert(*col.Width, qt.Equals, 15.0)
+			if i == 3 {
+				c.Assert(*col.OutlineLevel, qt.Equals, uint8(2))
+			} else {
+				c.Assert(col.OutlineLevel, qt.IsNil, qt.Commentf("column %d", i))
+			}
+		}
+	})
+
 	csRunO(c, "SetColAutoWidth", func(c *qt.C, option FileOption) {
 		file := NewFile(option)
 		sheet, _ := file.AddSheet("Sheet1")
@@ -719,7 +838,8 @@ func TestMakeXLSXSheet(t *testing.T) {
 		refTable := NewSharedStringRefTable()
 		styles := newXlsxStyleSheet(nil)
 
-		xSheet := sheet.makeXLSXSheet(refTable, styles, nil)
+		xSheet, err := sheet.makeXLSXSheet(refTable, styles, nil)
+		c.Assert(err, qt.IsNil)
 		// err := sheet.MarshalSheet(&buf, refTable, styles, nil)
 		// c.Assert(err, qt.Equals, nil)
 		// var xSheet xlsxWorksheet
@@ -735,6 +855,64 @@ func TestMakeXLSXSheet(t *testing.T) {
 		c.Assert(xSheet.SheetData.Row[1].OutlineLevel, qt.Equals, uint8(2))
 		c.Assert(xSheet.SheetData.Row[2].OutlineLevel, qt.Equals, uint8(0))
 	})
+
+	csRunO(c, "MergedRegions", func(c *qt.C, option FileOption) {
+		file := NewFile(option)
+		sheet, err := file.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.MergeRange(NewCellRange(1, 1, 3, 2)), qt.IsNil)
+		c.Assert(sheet.MergeRange(NewCellRange(0, 4, 0, 6)), qt.IsNil)
+		c.Assert(sheet.MergeRange(NewCellRange(2, 2, 4, 4)), qt.ErrorMatches, `MergeRange\("C3:E5"\): the range overlaps the merged cells B2:D3`)
+		c.Assert(sheet.MergeRange(NewCellRange(5, 5, 5, 5)), qt.Not(qt.IsNil))
+		// A block whose top left cell lies above the range still
+		// overlaps it
+		c.Assert(sheet.MergeRange(NewCellRange(0, 6, 1, 7)), qt.ErrorMatches, `MergeRange\("A7:B8"\): the range overlaps the merged cells A5:A7`)
+
+		regions, err := sheet.MergedRegions()
+		c.Assert(err, qt.IsNil)
+		c.Assert(regions, qt.DeepEquals, []CellRange{NewCellRange(1, 1, 3, 2), NewCellRange(0, 4, 0, 6)})
+
+		cell, region, err := sheet.MergedRegionAt(2, 3)
+		c.Assert(err, qt.IsNil)
+		c.Assert(region.String(), qt.Equals, "B2:D3")
+		c.Assert(cell.HMerge, qt.Equals, 2)
+		col, row := cell.GetCoordinates()
+		c.Assert([]int{col, row}, qt.DeepEquals, []int{1, 1})
+		cell, _, err = sheet.MergedRegionAt(0, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell, qt.IsNil)
+		cell, region, err = sheet.MergedRegionAt(6, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(region, qt.Equals, NewCellRange(0, 4, 0, 6))
+		c.Assert(cell.VMerge, qt.Equals, 2)
+		cell, _, err = sheet.MergedRegionAt(3, 1)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cell, qt.IsNil)
+
+		c.Assert(sheet.Unmerge(NewCellRange(3, 2, 3, 2)), qt.IsNil)
+		regions, err = sheet.MergedRegions()
+		c.Assert(err, qt.IsNil)
+		c.Assert(regions, qt.DeepEquals, []CellRange{NewCellRange(0, 4, 0, 6)})
+
+		var buf bytes.Buffer
+		c.Assert(file.Write(&buf), qt.IsNil)
+		file, err = OpenBinary(buf.Bytes(), option)
+		c.Assert(err, qt.IsNil)
+		regions, err = file.Sheets[0].MergedRegions()
+		c.Assert(err, qt.IsNil)
+		c.Assert(regions, qt.DeepEquals, []CellRange{NewCellRange(0, 4, 0, 6)})
+	})
+
+	csRunO(c, "OverlappingMergesAreNotWritten", func(c *qt.C, option FileOption) {
+		file := NewFile(option)
+		sheet, err := file.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		row := sheet.AddRow()
+		row.AddCell().Merge(2, 0)
+		row.AddCell().Merge(0, 1)
+		var buf bytes.Buffer
+		c.Assert(file.Write(&buf), qt.ErrorMatches, ".*the merged cells A1:C1 and B1:B2 overlap")
+	})
 }
 
 func TestTemp(t *testing.T) {
@@ -0,0 +1,381 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenameSheet gives the Sheet named oldName the name newName.  The
+// references to the Sheet in formulas, defined names, data
+// validations and hyperlinks throughout the File are rewritten to use
+// the new name, quoted where it needs to be.
+func (f *File) RenameSheet(oldName, newName string) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("RenameSheet(%q, %q): %w", oldName, newName, err)
+	}
+	sheet, ok := f.Sheet[oldName]
+	if !ok {
+		return wrap(fmt.Errorf("no sheet named %q", oldName))
+	}
+	if newName == oldName {
+		return nil
+	}
+	// Excel doesn't tell sheet names apart by case, though a Sheet
+	// may be renamed to change the case of its own name
+	if f.sheetByName(newName) != nil && !strings.EqualFold(newName, oldName) {
+		return wrap(fmt.Errorf("duplicate sheet name '%s'.", newName))
+	}
+	if err := checkSheetName(newName); err != nil {
+		return wrap(err)
+	}
+	// The cell store is rekeyed first, as it's the step most likely
+	// to fail, and is undone if the references can't be rewritten
+	if err := sheet.rekeyCellStore(newName); err != nil {
+		return wrap(err)
+	}
+	edit := sheetEdit{file: f, sheet: sheet, oldName: oldName, newName: newName}
+	if err := edit.apply(); err != nil {
+		undo := sheetEdit{file: f, sheet: sheet, oldName: newName, newName: oldName}
+		undoErr := undo.apply()
+		if rekeyErr := sheet.rekeyCellStore(oldName); undoErr == nil {
+			undoErr = rekeyErr
+		}
+		if undoErr != nil {
+			return wrap(fmt.Errorf("%w (undoing the rename failed, too: %v)", err, undoErr))
+		}
+		return wrap(err)
+	}
+	delete(f.Sheet, oldName)
+	f.Sheet[newName] = sheet
+	return nil
+}
+
+// RemoveSheet removes the Sheet with the given name from the File,
+// and closes it.  The references to the Sheet in formulas, defined
+// names, data validations and hyperlinks throughout the File become
+// #REF! errors, and the defined names that are local to it are
+// removed.  A 3D reference that starts or ends with the Sheet is
+// narrowed to the sheets that remain.
+func (f *File) RemoveSheet(name string) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("RemoveSheet(%q): %w", name, err)
+	}
+	sheet, ok := f.Sheet[name]
+	if !ok {
+		return wrap(fmt.Errorf("no sheet named %q", name))
+	}
+	index := f.sheetIndex(sheet)
+	if err := (sheetEdit{file: f, sheet: sheet, oldName: name}).apply(); err != nil {
+		return wrap(err)
+	}
+
+	definedNames := f.DefinedNames[:0]
+	for _, dn := range f.DefinedNames {
+		if dn.localSheetID != nil {
+			switch id := *dn.localSheetID; {
+			case id == index:
+				continue
+			case id > index:
+				id--
+				dn.localSheetID = &id
+			}
+		}
+		definedNames = append(definedNames, dn)
+	}
+	f.DefinedNames = definedNames
+
+	f.Sheets = append(f.Sheets[:index], f.Sheets[index+1:]...)
+	delete(f.Sheet, name)
+	if sheet.Selected && len(f.Sheets) > 0 {
+		f.Sheets[0].Selected = true
+	}
+	sheet.Close()
+	return nil
+}
+
+// sheetEdit rewrites the references to a sheet that is being renamed
+// or, when newName is empty, removed.  oldName is the name by which
+// the references refer to the sheet, which the Sheet itself may no
+// longer have.
+type sheetEdit struct {
+	file    *File
+	sheet   *Sheet
+	oldName string
+	newName string
+}
+
+// reference adjusts a reference for the edit.  It returns false if the
+// reference points at a removed sheet.  When one end of a 3D
+// reference is removed, that end moves to the next sheet towards the
+// other end.
+func (se sheetEdit) reference(ref Reference) (Reference, bool) {
+	if ref.Workbook != "" {
+		return ref, true
+	}
+	name := se.oldName
+	if se.newName != "" {
+		if strings.EqualFold(ref.Sheet, name) {
+			ref.Sheet = se.newName
+		}
+		if strings.EqualFold(ref.LastSheet, name) {
+			ref.LastSheet = se.newName
+		}
+		return ref, true
+	}
+	if ref.LastSheet == "" {
+		return ref, !strings.EqualFold(ref.Sheet, name)
+	}
+	first := se.file.sheetIndex(se.file.sheetByName(ref.Sheet))
+	last := se.file.sheetIndex(se.file.sheetByName(ref.LastSheet))
+	if first < 0 || last < 0 {
+		return ref, true
+	}
+	step := 1
+	if last < first {
+		step = -1
+	}
+	switch se.file.sheetIndex(se.sheet) {
+	case first:
+		if first == last {
+			return ref, false
+		}
+		ref.Sheet = se.file.Sheets[first+step].Name
+	case last:
+		ref.LastSheet = se.file.Sheets[last-step].Name
+	default:
+		return ref, true
+	}
+	if strings.EqualFold(ref.Sheet, ref.LastSheet) {
+		ref.LastSheet = ""
+	}
+	return ref, true
+}
+
+// formula rewrites the references within a formula for the edit.  A
+// formula that can't be tokenized, or isn't affected, is returned
+// unchanged.
+func (se sheetEdit) formula(formula string) string {
+	if formula == "" {
+		return formula
+	}
+	tokens, err := TokenizeFormula(formula)
+	if err != nil {
+		return formula
+	}
+	changed := false
+	var b strings.Builder
+	for _, tok := range tokens {
+		if tok.Type == FormulaTokenName {
+			name, ok := se.name(tok.Value)
+			switch {
+			case !ok:
+				b.WriteString("#REF!")
+				changed = true
+				continue
+			case name != tok.Value:
+				b.WriteString(name)
+				changed = true
+				continue
+			}
+		}
+		if tok.Type == FormulaTokenReference {
+			if ref, err := ParseReference(tok.Value); err == nil {
+				adjusted, ok := se.reference(ref)
+				switch {
+				case !ok:
+					b.WriteString("#REF!")
+					changed = true
+					continue
+				case adjusted != ref:
+					b.WriteString(adjusted.String())
+					changed = true
+					continue
+				}
+			}
+		}
+		b.WriteString(tok.Value)
+	}
+	if !changed {
+		return formula
+	}
+	return b.String()
+}
+
+// name adjusts a defined name that is qualified with the sheet it's
+// local to, such as "Sales!Total", for the edit.  It returns false if
+// that sheet is removed.
+func (se sheetEdit) name(name string) (string, bool) {
+	body, prefixed, err := scanRefPrefix(name, 0)
+	if err != nil || !prefixed {
+		return name, true
+	}
+	workbook, sheet, lastSheet := parseRefPrefix(name[:body-1])
+	if workbook != "" || lastSheet != "" || !strings.EqualFold(sheet, se.oldName) {
+		return name, true
+	}
+	if se.newName == "" {
+		return name, false
+	}
+	return sheetRef(se.newName, name[body:]), true
+}
+
+// location rewrites the location of an internal hyperlink, which may
+// start with a '#', for the edit.
+func (se sheetEdit) location(location string) string {
+	if strings.HasPrefix(location, "#") {
+		return "#" + se.formula(location[1:])
+	}
+	return se.formula(location)
+}
+
+// dataValidation returns a copy of the data validation with its
+// formulas rewritten for the edit, or dv itself if they're unaffected.
+func (se sheetEdit) dataValidation(dv *xlsxDataValidation) *xlsxDataValidation {
+	if dv == nil {
+		return nil
+	}
+	formula1, formula2 := se.formula(dv.Formula1), se.formula(dv.Formula2)
+	if formula1 == dv.Formula1 && formula2 == dv.Formula2 {
+		return dv
+	}
+	c := *dv
+	c.Formula1, c.Formula2 = formula1, formula2
+	return &c
+}
+
+// apply rewrites the references to the sheet throughout the File:
+// the formulas, hyperlink locations and data validations of the cells
+// of every other sheet, or of every sheet when renaming, the data
+// validations of those sheets, and the defined names.
+func (se sheetEdit) apply() error {
+	for _, sheet := range se.file.Sheets {
+		if sheet == se.sheet && se.newName == "" {
+			continue
+		}
+		if err := se.applyToCells(sheet); err != nil {
+			return err
+		}
+		for i, dv := range sheet.DataValidations {
+			sheet.DataValidations[i] = se.dataValidation(dv)
+		}
+	}
+	for _, dn := range se.file.DefinedNames {
+		dn.Data = se.formula(dn.Data)
+	}
+	return nil
+}
+
+// applyToCells rewrites the references within the cells of a sheet.
+// The link of an internal hyperlink is its location, so it's
+// rewritten along with the relation that the link is written through.
+func (se sheetEdit) applyToCells(sheet *Sheet) error {
+	var edits []cellEdit
+	var dvs []*xlsxDataValidation
+	links := make(map[string]string)
+	err := sheet.ForEachRow(func(row *Row) error {
+		return row.ForEachCell(func(cell *Cell) error {
+			formula := se.formula(cell.formula)
+			location := se.location(cell.Hyperlink.Location)
+			dv := se.dataValidation(cell.DataValidation)
+			if formula != cell.formula || location != cell.Hyperlink.Location || dv != cell.DataValidation {
+				edit := cellEdit{row: row.num, col: cell.num, formula: formula, location: location, link: cell.Hyperlink.Link}
+				if cell.Hyperlink.Link != "" && cell.Hyperlink.Link == cell.Hyperlink.Location {
+					edit.link = location
+					links[cell.Hyperlink.Link] = location
+				}
+				edits = append(edits, edit)
+				dvs = append(dvs, dv)
+			}
+			return nil
+		}, SkipEmptyCells)
+	}, SkipEmptyRows)
+	if err != nil {
+		return err
+	}
+	for i, rel := range sheet.Relations {
+		if link, ok := links[rel.Target]; ok && rel.Type == RelationshipTypeHyperlink {
+			sheet.Relations[i].Target = link
+		}
+	}
+	for i, edit := range edits {
+		row, err := sheet.Row(edit.row)
+		if err != nil {
+			return err
+		}
+		cell := row.GetCell(edit.col)
+		cell.updatable()
+		cell.formula = edit.formula
+		cell.Hyperlink.Location = edit.location
+		cell.Hyperlink.Link = edit.link
+		cell.DataValidation = dvs[i]
+		cell.modified = true
+		// Pushing the cell back makes stores that only hold the
+		// current cell in memory, such as DiskV, persist it
+		row.PushCell(cell)
+	}
+	return nil
+}
+
+// rekeyCellStore renames the Sheet, moving its rows and cells within
+// its cell store, which keys them by the name of the Sheet, to keys
+// made from the new name.  The Sheet keeps its old name if the rows
+// can't all be moved.
+func (s *Sheet) rekeyCellStore(newName string) (err error) {
+	s.mustBeOpen()
+	oldName := s.Name
+	defer func() {
+		if err != nil {
+			s.Name = oldName
+		}
+	}()
+	if s.currentRow != nil {
+		if err := s.cellStore.WriteRow(s.currentRow); err != nil {
+			return err
+		}
+		s.currentRow = nil
+	}
+	for i := 0; i < s.MaxRow; i++ {
+		s.Name = oldName
+		key := makeRowKey(s, i)
+		row, err := s.cellStore.ReadRow(key, s)
+		if err != nil {
+			if _, ok := err.(*RowNotFoundError); ok {
+				continue
+			}
+			return err
+		}
+		var cells []Cell
+		err = row.ForEachCell(func(cell *Cell) error {
+			cells = append(cells, *cell)
+			return nil
+		}, SkipEmptyCells)
+		if err != nil {
+			return err
+		}
+		if err := s.cellStore.RemoveRow(key); err != nil {
+			return err
+		}
+
+		s.Name = newName
+		newRow := s.cellStore.MakeRow(s)
+		newRow.num = i
+		newRow.Hidden = row.Hidden
+		newRow.Collapsed = row.Collapsed
+		newRow.style = row.style
+		newRow.height = row.height
+		newRow.customHeight = row.customHeight
+		newRow.outlineLevel = row.outlineLevel
+		newRow.isCustom = row.isCustom
+		for i := range cells {
+			cell := &cells[i]
+			cell.Row = newRow
+			cell.modified = true
+			newRow.cellStoreRow.PushCell(cell)
+		}
+		if err := s.cellStore.WriteRow(newRow); err != nil {
+			return err
+		}
+	}
+	s.Name = newName
+	return nil
+}
@@ -0,0 +1,143 @@
+package xlsx
+
+import (
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSheetEdit(t *testing.T) {
+	c := qt.New(t)
+
+	cell := func(c *qt.C, sheet *Sheet, row, col int) *Cell {
+		cell, err := sheet.Cell(row, col)
+		c.Assert(err, qt.IsNil)
+		return cell
+	}
+
+	// makeFile makes a File whose Summary sheet refers to its Data
+	// sheet in every way that a reference can be made.
+	makeFile := func(c *qt.C, option FileOption) *File {
+		f := NewFile(option)
+		data, err := f.AddSheet("Data")
+		c.Assert(err, qt.IsNil)
+		summary, err := f.AddSheet("Summary")
+		c.Assert(err, qt.IsNil)
+		_, err = f.AddSheet("Other")
+		c.Assert(err, qt.IsNil)
+		data.Selected = true
+		for i := 0; i < 3; i++ {
+			cell(c, data, i, 0).SetInt(i + 1)
+		}
+
+		cell(c, summary, 0, 0).SetFormula("SUM(Data!A1:A3)*2")
+		cell(c, summary, 1, 0).SetFormula("SUM(Data:Other!A1)")
+		cell(c, summary, 2, 0).SetHyperlink("#Data!A1", "Go", "")
+		dv := NewDataValidation(3, 0, 3, 0, true)
+		c.Assert(dv.SetInFileList("Data", 0, 0, 0, 2), qt.IsNil)
+		cell(c, summary, 3, 0).SetDataValidation(dv)
+		dv = NewDataValidation(4, 0, 4, 0, true)
+		c.Assert(dv.SetInFileList("Data", 0, 0, 0, 2), qt.IsNil)
+		summary.AddDataValidation(dv)
+		cell(c, summary, 5, 0).SetFormula("Data!Local*2")
+
+		_, err = f.AddName("Values", "Data!$A$1:$A$3", nil)
+		c.Assert(err, qt.IsNil)
+		_, err = f.AddName("Local", "Data!$A$1", data)
+		c.Assert(err, qt.IsNil)
+		_, err = f.AddName("Last", "Other!$A$1", f.Sheet["Other"])
+		c.Assert(err, qt.IsNil)
+		return f
+	}
+
+	csRunO(c, "RenameSheet", func(c *qt.C, option FileOption) {
+		f := makeFile(c, option)
+		c.Assert(f.RenameSheet("Data", "O'Brien's Data"), qt.IsNil)
+		c.Assert(f.Sheets[0].Name, qt.Equals, "O'Brien's Data")
+		c.Assert(f.Sheet["Data"], qt.IsNil)
+		data := f.Sheet["O'Brien's Data"]
+		c.Assert(data, qt.Equals, f.Sheets[0])
+		c.Assert(cell(c, data, 2, 0).Value, qt.Equals, "3")
+
+		summary := f.Sheet["Summary"]
+		c.Assert(cell(c, summary, 0, 0).Formula(), qt.Equals, "SUM('O''Brien''s Data'!A1:A3)*2")
+		c.Assert(cell(c, summary, 1, 0).Formula(), qt.Equals, "SUM('O''Brien''s Data:Other'!A1)")
+		c.Assert(cell(c, summary, 2, 0).Hyperlink.Location, qt.Equals, "#'O''Brien''s Data'!A1")
+		c.Assert(cell(c, summary, 2, 0).Hyperlink.Link, qt.Equals, "#'O''Brien''s Data'!A1")
+		c.Assert(cell(c, summary, 3, 0).DataValidation.Formula1, qt.Equals, "'O''Brien''s Data'!$A$1:$A$3")
+		c.Assert(summary.DataValidations[0].Formula1, qt.Equals, "'O''Brien''s Data'!$A$1:$A$3")
+		c.Assert(f.Name("Values").Data, qt.Equals, "'O''Brien''s Data'!$A$1:$A$3")
+		c.Assert(cell(c, summary, 5, 0).Formula(), qt.Equals, "'O''Brien''s Data'!Local*2")
+		names := data.Names()
+		c.Assert(names, qt.HasLen, 1)
+		c.Assert(names[0].Data, qt.Equals, "'O''Brien''s Data'!$A$1")
+
+		path := filepath.Join(c.TempDir(), "renamed.xlsx")
+		c.Assert(f.Save(path), qt.IsNil)
+		f, err := OpenFile(path, option)
+		c.Assert(err, qt.IsNil)
+		data = f.Sheet["O'Brien's Data"]
+		c.Assert(data, qt.Not(qt.IsNil))
+		c.Assert(cell(c, data, 0, 0).Value, qt.Equals, "1")
+		c.Assert(cell(c, f.Sheet["Summary"], 0, 0).Formula(), qt.Equals, "SUM('O''Brien''s Data'!A1:A3)*2")
+		c.Assert(cell(c, f.Sheet["Summary"], 2, 0).Hyperlink.Link, qt.Equals, "#'O''Brien''s Data'!A1")
+	})
+
+	csRunO(c, "RenameSheetErrors", func(c *qt.C, option FileOption) {
+		f := makeFile(c, option)
+		c.Assert(f.RenameSheet("Missing", "New"), qt.ErrorMatches, `RenameSheet\("Missing", "New"\): no sheet named "Missing"`)
+		c.Assert(f.RenameSheet("Data", "Other"), qt.ErrorMatches, `RenameSheet\("Data", "Other"\): duplicate sheet name 'Other'.`)
+		c.Assert(f.RenameSheet("Data", "OTHER"), qt.ErrorMatches, `RenameSheet\("Data", "OTHER"\): duplicate sheet name 'OTHER'.`)
+		c.Assert(f.RenameSheet("Data", "A/B"), qt.ErrorMatches, `RenameSheet\("Data", "A/B"\): sheet name must not contain .*`)
+		c.Assert(f.RenameSheet("Data", "Data"), qt.IsNil)
+		c.Assert(cell(c, f.Sheet["Summary"], 0, 0).Formula(), qt.Equals, "SUM(Data!A1:A3)*2")
+
+		// A Sheet may change the case of its own name
+		c.Assert(f.RenameSheet("Data", "DATA"), qt.IsNil)
+		c.Assert(f.Sheets[0].Name, qt.Equals, "DATA")
+		c.Assert(cell(c, f.Sheet["DATA"], 0, 0).Value, qt.Equals, "1")
+		c.Assert(cell(c, f.Sheet["Summary"], 0, 0).Formula(), qt.Equals, "SUM(DATA!A1:A3)*2")
+	})
+
+	csRunO(c, "RemoveSheet", func(c *qt.C, option FileOption) {
+		f := makeFile(c, option)
+		c.Assert(f.RemoveSheet("Data"), qt.IsNil)
+		c.Assert(f.Sheets, qt.HasLen, 2)
+		c.Assert(f.Sheet["Data"], qt.IsNil)
+		c.Assert(f.Sheets[0].Name, qt.Equals, "Summary")
+		c.Assert(f.Sheets[0].Selected, qt.IsTrue)
+
+		summary := f.Sheet["Summary"]
+		c.Assert(cell(c, summary, 0, 0).Formula(), qt.Equals, "SUM(#REF!)*2")
+		c.Assert(cell(c, summary, 1, 0).Formula(), qt.Equals, "SUM(Summary:Other!A1)")
+		c.Assert(cell(c, summary, 2, 0).Hyperlink.Location, qt.Equals, "##REF!")
+		c.Assert(cell(c, summary, 3, 0).DataValidation.Formula1, qt.Equals, "#REF!")
+		c.Assert(summary.DataValidations[0].Formula1, qt.Equals, "#REF!")
+		c.Assert(f.Name("Values").Data, qt.Equals, "#REF!")
+		c.Assert(cell(c, summary, 5, 0).Formula(), qt.Equals, "#REF!*2")
+
+		c.Assert(f.DefinedNames, qt.HasLen, 2)
+		c.Assert(f.Sheets[0].Names(), qt.HasLen, 0)
+		names := f.Sheet["Other"].Names()
+		c.Assert(names, qt.HasLen, 1)
+		c.Assert(names[0].Name, qt.Equals, "Last")
+		c.Assert(*names[0].localSheetID, qt.Equals, 1)
+
+		c.Assert(f.RemoveSheet("Other"), qt.IsNil)
+		c.Assert(cell(c, summary, 1, 0).Formula(), qt.Equals, "SUM(Summary!A1)")
+		c.Assert(f.DefinedNames, qt.HasLen, 1)
+
+		path := filepath.Join(c.TempDir(), "removed.xlsx")
+		c.Assert(f.Save(path), qt.IsNil)
+		f, err := OpenFile(path, option)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.Sheets, qt.HasLen, 1)
+		c.Assert(f.Sheets[0].Name, qt.Equals, "Summary")
+	})
+
+	c.Run("RemoveSheetErrors", func(c *qt.C) {
+		f := NewFile()
+		c.Assert(f.RemoveSheet("Missing"), qt.ErrorMatches, `RemoveSheet\("Missing"\): no sheet named "Missing"`)
+	})
+}
@@ -0,0 +1,96 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// view returns the SheetView that Excel shows the Sheet in, adding
+// one if the Sheet doesn't have one yet.
+func (s *Sheet) view() *SheetView {
+	if len(s.SheetViews) == 0 {
+		s.SheetViews = append(s.SheetViews, SheetView{})
+	}
+	return &s.SheetViews[0]
+}
+
+// FreezePanes freezes the given number of rows at the top of the
+// Sheet, and columns at its left, so that they stay in view while the
+// rest of the Sheet scrolls.  Freezing no rows and no columns unfreezes
+// the panes.
+func (s *Sheet) FreezePanes(rows, cols int) error {
+	if rows < 0 || cols < 0 || rows > Excel2006MaxRowIndex || cols > Excel2006MaxColIndex {
+		return fmt.Errorf("FreezePanes(%d, %d): can't freeze beyond the edges of the sheet", rows, cols)
+	}
+	view := s.view()
+	if rows == 0 && cols == 0 {
+		view.Pane = nil
+		return nil
+	}
+	pane := &Pane{
+		XSplit:      float64(cols),
+		YSplit:      float64(rows),
+		TopLeftCell: GetCellIDStringFromCoords(cols, rows),
+		State:       "frozen",
+	}
+	switch {
+	case rows > 0 && cols > 0:
+		pane.ActivePane = "bottomRight"
+	case rows > 0:
+		pane.ActivePane = "bottomLeft"
+	default:
+		pane.ActivePane = "topRight"
+	}
+	view.Pane = pane
+	return nil
+}
+
+// SetZoom sets the magnification that Excel shows the Sheet at, as a
+// percentage from 10 to 400.
+func (s *Sheet) SetZoom(percent int) error {
+	if percent < 10 || percent > 400 {
+		return fmt.Errorf("SetZoom(%d): the zoom must be from 10 to 400 percent", percent)
+	}
+	s.view().ZoomScale = percent
+	return nil
+}
+
+// ShowGridLines sets whether Excel shows the grid lines between the
+// cells of the Sheet, which it does by default.
+func (s *Sheet) ShowGridLines(show bool) {
+	s.view().HideGridLines = !show
+}
+
+// ShowRowColHeaders sets whether Excel shows the row numbers and
+// column letters of the Sheet, which it does by default.
+func (s *Sheet) ShowRowColHeaders(show bool) {
+	s.view().HideRowColHeaders = !show
+}
+
+// SetRightToLeft sets whether Excel shows the Sheet from right to
+// left, with column A on the right, as suits languages such as Arabic
+// and Hebrew.
+func (s *Sheet) SetRightToLeft(rightToLeft bool) {
+	s.view().RightToLeft = rightToLeft
+}
+
+// SetSelection puts the cursor in the cell activeCell, such as "B2",
+// and selects the given ranges of cells.  With no ranges, only the
+// active cell is selected.
+func (s *Sheet) SetSelection(activeCell string, ranges ...CellRange) error {
+	cell, err := ParseCellRange(activeCell)
+	if err != nil {
+		return fmt.Errorf("SetSelection(%q): %w", activeCell, err)
+	}
+	if cell.IsWholeRows() || cell.IsWholeCols() || cell.Width() != 1 || cell.Height() != 1 {
+		return fmt.Errorf("SetSelection(%q): the active cell must be a single cell", activeCell)
+	}
+	refs := make([]string, len(ranges))
+	for i, r := range ranges {
+		refs[i] = r.String()
+	}
+	view := s.view()
+	view.ActiveCell = cell.String()
+	view.Selection = strings.Join(refs, " ")
+	return nil
+}
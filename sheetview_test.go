@@ -0,0 +1,133 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSheetView(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("DefaultsUnchanged", func(c *qt.C) {
+		f := NewFile()
+		_, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		parts, _ := writeFileParts(c, f)
+		sheet := parts["xl/worksheets/sheet1.xml"]
+		c.Assert(sheet, qt.Contains, `<sheetPr filterMode="false"><pageSetUpPr fitToPage="false"/></sheetPr>`)
+		c.Assert(sheet, qt.Contains, `showGridLines="true" showRowColHeaders="true" showZeros="true" rightToLeft="false" tabSelected="true"`)
+		c.Assert(sheet, qt.Contains, `zoomScale="100" zoomScaleNormal="100"`)
+		c.Assert(sheet, qt.Contains, `<selection pane="topLeft" activeCell="A1" activeCellId="0" sqref="A1"/>`)
+		c.Assert(parts["xl/workbook.xml"], qt.Not(qt.Contains), "activeTab")
+	})
+
+	csRunO(c, "RoundTrip", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		_, err := f.AddSheet("First")
+		c.Assert(err, qt.IsNil)
+		sheet, err := f.AddSheet("تقرير")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.FreezePanes(1, 2), qt.IsNil)
+		c.Assert(sheet.SetZoom(150), qt.IsNil)
+		sheet.ShowGridLines(false)
+		sheet.ShowRowColHeaders(false)
+		sheet.SetRightToLeft(true)
+		sheet.TabColor = "FFFF0000"
+		c.Assert(sheet.SetSelection("d5", NewCellRange(3, 4, 5, 6), NewCellRange(7, 1, 7, 1)), qt.IsNil)
+		c.Assert(f.SetActiveSheet(1), qt.IsNil)
+		c.Assert(f.Sheets[0].Selected, qt.IsFalse)
+
+		parts, b := writeFileParts(c, f)
+		xSheet := parts["xl/worksheets/sheet2.xml"]
+		c.Assert(xSheet, qt.Contains, `<sheetPr filterMode="false"><tabColor rgb="FFFF0000"/>`)
+		c.Assert(xSheet, qt.Contains, `showGridLines="false" showRowColHeaders="false" showZeros="true" rightToLeft="true" tabSelected="true"`)
+		c.Assert(xSheet, qt.Contains, `zoomScale="150" zoomScaleNormal="150"`)
+		c.Assert(xSheet, qt.Contains, `<pane xSplit="2" ySplit="1" topLeftCell="C2" activePane="bottomRight" state="frozen"/>`)
+		c.Assert(xSheet, qt.Contains, `<selection pane="bottomRight" activeCell="D5" activeCellId="0" sqref="D5:F7 H2"/>`)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `activeTab="1"`)
+
+		f, err = OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.ActiveSheet(), qt.Equals, 1)
+		c.Assert(f.Sheets[0].Selected, qt.IsFalse)
+		sheet = f.Sheets[1]
+		c.Assert(sheet.TabColor, qt.Equals, "FFFF0000")
+		c.Assert(sheet.SheetViews, qt.DeepEquals, []SheetView{{
+			Pane: &Pane{
+				XSplit:      2,
+				YSplit:      1,
+				TopLeftCell: "C2",
+				ActivePane:  "bottomRight",
+				State:       "frozen",
+			},
+			ZoomScale:         150,
+			HideGridLines:     true,
+			HideRowColHeaders: true,
+			RightToLeft:       true,
+			ActiveCell:        "D5",
+			Selection:         "D5:F7 H2",
+		}})
+		c.Assert(f.Sheets[0].SheetViews, qt.DeepEquals, []SheetView{{
+			ActiveCell: "A1",
+			Selection:  "A1",
+		}})
+	})
+
+	csRunO(c, "ActiveSheetApartFromSelection", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		for _, name := range []string{"First", "Second", "Third"} {
+			_, err := f.AddSheet(name)
+			c.Assert(err, qt.IsNil)
+		}
+		c.Assert(f.SetActiveSheet(2), qt.IsNil)
+		// Grouping the first sheet with the active one doesn't make
+		// it the active sheet
+		f.Sheets[0].Selected = true
+		c.Assert(f.ActiveSheet(), qt.Equals, 2)
+		c.Assert(f.MoveSheet(2, 1), qt.IsNil)
+		c.Assert(f.ActiveSheet(), qt.Equals, 1)
+
+		parts, b := writeFileParts(c, f)
+		c.Assert(parts["xl/workbook.xml"], qt.Contains, `activeTab="1"`)
+		f, err := OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.ActiveSheet(), qt.Equals, 1)
+		c.Assert(f.Sheets[0].Selected, qt.IsTrue)
+		c.Assert(f.Sheets[1].Selected, qt.IsTrue)
+		c.Assert(f.Sheets[2].Selected, qt.IsFalse)
+	})
+
+	c.Run("ReadDefaults", func(c *qt.C) {
+		// Attributes that are left out take the schema's defaults
+		worksheet := `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView rightToLeft="1" workbookViewId="0"/></sheetViews><sheetData/></worksheet>`
+		var xSheet xlsxWorksheet
+		c.Assert(xml.Unmarshal([]byte(worksheet), &xSheet), qt.IsNil)
+		views := readSheetViews(xSheet.SheetViews)
+		c.Assert(views, qt.DeepEquals, []SheetView{{RightToLeft: true}})
+	})
+
+	c.Run("FreezePanes", func(c *qt.C) {
+		sheet, err := NewSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.FreezePanes(3, 0), qt.IsNil)
+		c.Assert(*sheet.SheetViews[0].Pane, qt.Equals, Pane{YSplit: 3, TopLeftCell: "A4", ActivePane: "bottomLeft", State: "frozen"})
+		c.Assert(sheet.FreezePanes(0, 1), qt.IsNil)
+		c.Assert(*sheet.SheetViews[0].Pane, qt.Equals, Pane{XSplit: 1, TopLeftCell: "B1", ActivePane: "topRight", State: "frozen"})
+		c.Assert(sheet.FreezePanes(0, 0), qt.IsNil)
+		c.Assert(sheet.SheetViews[0].Pane, qt.IsNil)
+		c.Assert(sheet.SheetViews, qt.HasLen, 1)
+		c.Assert(sheet.FreezePanes(-1, 0), qt.ErrorMatches, `FreezePanes\(-1, 0\): .*`)
+	})
+
+	c.Run("Errors", func(c *qt.C) {
+		f := NewFile()
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.SetZoom(5), qt.ErrorMatches, `SetZoom\(5\): the zoom must be from 10 to 400 percent`)
+		c.Assert(sheet.SetSelection("A1:B2"), qt.ErrorMatches, `SetSelection\("A1:B2"\): the active cell must be a single cell`)
+		c.Assert(sheet.SetSelection("!"), qt.ErrorMatches, `SetSelection\("!"\): .*`)
+		c.Assert(f.SetActiveSheet(1), qt.ErrorMatches, `SetActiveSheet\(1\): the File has 1 sheets`)
+	})
+}
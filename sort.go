@@ -0,0 +1,236 @@
+package xlsx
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortKey is one of the keys by which Sheet.Sort orders the rows of a
+// range.
+type SortKey struct {
+	// Col is the zero based index of the column on the sheet
+	Col        int
+	Descending bool
+}
+
+// sortValue is the value of a cell as Sheet.Sort compares it.
+type sortValue struct {
+	// rank orders values of different kinds, as Excel does:
+	// numbers and dates, then text, then booleans, then errors.
+	// Blank cells always come last.
+	rank int
+	num  float64
+	text string
+}
+
+const (
+	sortRankNumber = iota
+	sortRankText
+	sortRankBool
+	sortRankError
+	sortRankBlank
+)
+
+// makeSortValue returns the value by which a cell is sorted, according
+// to its type.
+func makeSortValue(c *Cell, date1904 bool) sortValue {
+	if c == nil {
+		return sortValue{rank: sortRankBlank}
+	}
+	switch c.cellType {
+	case CellTypeNumeric:
+		if n, err := strconv.ParseFloat(c.Value, 64); err == nil {
+			return sortValue{rank: sortRankNumber, num: n}
+		}
+	case CellTypeDate:
+		if t, err := time.Parse(time.RFC3339, c.Value); err == nil {
+			return sortValue{rank: sortRankNumber, num: TimeToExcelTime(t, date1904)}
+		}
+	case CellTypeBool:
+		if c.Value == "1" {
+			return sortValue{rank: sortRankBool, num: 1}
+		}
+		return sortValue{rank: sortRankBool}
+	case CellTypeError:
+		return sortValue{rank: sortRankError, text: c.Value}
+	}
+	text := c.Value
+	if len(c.RichText) > 0 {
+		text = richTextToPlainText(c.RichText)
+	}
+	if text == "" {
+		return sortValue{rank: sortRankBlank}
+	}
+	return sortValue{rank: sortRankText, text: strings.ToLower(text)}
+}
+
+// compare returns a negative number, zero or a positive number as v
+// sorts before, with or after other in ascending order.
+func (v sortValue) compare(other sortValue) int {
+	switch {
+	case v.rank != other.rank:
+		return v.rank - other.rank
+	case v.rank == sortRankText || v.rank == sortRankError:
+		return strings.Compare(v.text, other.text)
+	case v.num < other.num:
+		return -1
+	case v.num > other.num:
+		return 1
+	}
+	return 0
+}
+
+// sortRow is a row of the range being sorted, holding only the values
+// of its keys, so that the rows themselves needn't be held in memory.
+type sortRow struct {
+	num    int
+	values []sortValue
+}
+
+// Sort orders the rows of the range by the values in the columns of
+// the keys, comparing numbers and dates by value, and text without
+// regard to case.  As in Excel, numbers come before text, then
+// booleans and errors, and blank cells always come last.  Rows that
+// are equal by every key keep their order.  When header is set, the
+// first row of the range holds headings and is left in place.  The
+// relative references of formulas move with their rows.
+//
+// The sort is recorded in the sheet, so that Excel shows it.  It
+// returns an error if a key lies outside the range, or the range
+// holds merged cells.
+func (s *Sheet) Sort(r CellRange, header bool, keys ...SortKey) error {
+	s.mustBeOpen()
+	wrap := func(err error) error {
+		return fmt.Errorf("Sort(%q): %w", r.String(), err)
+	}
+	if len(keys) == 0 {
+		return wrap(fmt.Errorf("no sort keys"))
+	}
+	rng := NewRange(s, r)
+	area := rng.clamp(r)
+	if header {
+		area.MinRow++
+	}
+	for _, key := range keys {
+		if key.Col < area.MinCol || key.Col > area.MaxCol {
+			return wrap(fmt.Errorf("the key column %s is outside the range", ColIndexToLetters(key.Col)))
+		}
+	}
+	if area.Height() <= 0 {
+		return nil
+	}
+	regions, err := s.mergedRegionsIn(area)
+	if err != nil {
+		return wrap(err)
+	}
+	if len(regions) > 0 {
+		return wrap(fmt.Errorf("the range holds the merged cells %s", regions[0].String()))
+	}
+
+	date1904 := s.File != nil && s.File.Date1904
+	rows := make([]*sortRow, area.Height())
+	for i := range rows {
+		rows[i] = &sortRow{num: area.MinRow + i, values: make([]sortValue, len(keys))}
+		for k := range keys {
+			rows[i].values[k] = sortValue{rank: sortRankBlank}
+		}
+	}
+	err = rng.forEachExistingCell(area, func(cell *Cell) error {
+		for k, key := range keys {
+			if key.Col == cell.num {
+				rows[cell.Row.num-area.MinRow].values[k] = makeSortValue(cell, date1904)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return wrap(err)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for k, key := range keys {
+			a, b := rows[i].values[k], rows[j].values[k]
+			cmp := a.compare(b)
+			if key.Descending && a.rank != sortRankBlank && b.rank != sortRankBlank {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+
+	// Each row moves to where the sort puts it by following the
+	// cycles of the permutation, so that only the row a cycle
+	// starts with is held aside while the others are moved.
+	moved := make([]bool, len(rows))
+	for start := range rows {
+		if moved[start] || rows[start].num == area.MinRow+start {
+			continue
+		}
+		first, err := rng.rowCells(area, area.MinRow+start)
+		if err != nil {
+			return wrap(err)
+		}
+		dest := start
+		for !moved[dest] {
+			moved[dest] = true
+			src := rows[dest].num - area.MinRow
+			cells := first
+			if src != start {
+				if cells, err = rng.rowCells(area, rows[dest].num); err != nil {
+					return wrap(err)
+				}
+			}
+			if err := rng.moveRowCells(area, cells, rows[dest].num, area.MinRow+dest); err != nil {
+				return wrap(err)
+			}
+			dest = src
+		}
+	}
+
+	s.sortState = &xlsxSortState{Ref: area.String()}
+	for _, key := range keys {
+		s.sortState.SortCondition = append(s.sortState.SortCondition, xlsxSortCondition{
+			Descending: key.Descending,
+			Ref:        NewCellRange(key.Col, area.MinRow, key.Col, area.MaxRow).String(),
+		})
+	}
+	return nil
+}
+
+// rowCells returns copies of the cells of a row that lie within the
+// columns of the area, keyed by their column.
+func (r *Range) rowCells(area CellRange, num int) (map[int]Cell, error) {
+	row, err := r.Sheet.Row(num)
+	if err != nil {
+		return nil, err
+	}
+	cells := make(map[int]Cell)
+	err = row.ForEachCell(func(cell *Cell) error {
+		if cell.num >= area.MinCol && cell.num <= area.MaxCol {
+			cell.Row = row
+			cells[cell.num] = *cell
+		}
+		return nil
+	}, SkipEmptyCells)
+	return cells, err
+}
+
+// moveRowCells replaces the cells of the row dest within the columns
+// of the area by the cells taken from the row src.
+func (r *Range) moveRowCells(area CellRange, cells map[int]Cell, src, dest int) error {
+	target := NewCellRange(area.MinCol, dest, area.MaxCol, dest)
+	return r.updateCells(target, func(cell *Cell) error {
+		c, ok := cells[cell.num]
+		if !ok {
+			*cell = Cell{Row: cell.Row, num: cell.num, modified: true}
+			return nil
+		}
+		cell.copyContents(&c, 0, dest-src, true)
+		return nil
+	})
+}
@@ -0,0 +1,163 @@
+package xlsx
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSort(t *testing.T) {
+	c := qt.New(t)
+
+	// column returns the formatted values of a column of the sheet
+	column := func(c *qt.C, sheet *Sheet, ref string) []string {
+		r, err := sheet.Range(ref)
+		c.Assert(err, qt.IsNil)
+		values, err := r.Values()
+		c.Assert(err, qt.IsNil)
+		var col []string
+		for _, row := range values {
+			col = append(col, row[0])
+		}
+		return col
+	}
+
+	csRunO(c, "ByCellType", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		r, err := sheet.Range("A1:B7")
+		c.Assert(err, qt.IsNil)
+		c.Assert(r.SetValues([][]interface{}{
+			{"Key", "Row"},
+			{"pear", 1},
+			{10, 2},
+			{nil, 3},
+			{"Apple", 4},
+			{nil, 5},
+			{9.5, 6},
+		}), qt.IsNil)
+		cell, err := sheet.Cell(5, 0)
+		c.Assert(err, qt.IsNil)
+		cell.SetBool(true)
+
+		c.Assert(sheet.Sort(NewCellRange(0, 0, 1, 6), true, SortKey{Col: 0}), qt.IsNil)
+		c.Assert(column(c, sheet, "A1:A7"), qt.DeepEquals, []string{"Key", "9.5", "10", "Apple", "pear", "TRUE", ""})
+		c.Assert(column(c, sheet, "B1:B7"), qt.DeepEquals, []string{"Row", "6", "2", "4", "1", "5", "3"})
+
+		// Blank cells stay last when descending
+		c.Assert(sheet.Sort(NewCellRange(0, 0, 1, 6), true, SortKey{Col: 0, Descending: true}), qt.IsNil)
+		c.Assert(column(c, sheet, "A1:A7"), qt.DeepEquals, []string{"Key", "TRUE", "pear", "Apple", "10", "9.5", ""})
+
+		parts, _ := writeFileParts(c, f)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `<sortState ref="A2:B7"><sortCondition descending="true" ref="A2:A7"/></sortState>`)
+	})
+
+	csRunO(c, "SeveralKeys", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		day := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+		for i, group := range []string{"b", "a", "b", "a"} {
+			row := sheet.AddRow()
+			row.AddCell().SetString(group)
+			row.AddCell().SetDate(day.AddDate(0, 0, -i))
+			row.AddCell().SetFormula("B" + RowIndexToString(i) + "+1")
+		}
+
+		c.Assert(sheet.Sort(NewCellRange(0, 0, 2, 3), false, SortKey{Col: 0}, SortKey{Col: 1}), qt.IsNil)
+		c.Assert(column(c, sheet, "A1:A4"), qt.DeepEquals, []string{"a", "a", "b", "b"})
+		var days []time.Time
+		for row := 0; row < 4; row++ {
+			cell, err := sheet.Cell(row, 1)
+			c.Assert(err, qt.IsNil)
+			t, err := cell.GetTime(false)
+			c.Assert(err, qt.IsNil)
+			days = append(days, t)
+			// Formulas refer to their own rows wherever they move to
+			cell, err = sheet.Cell(row, 2)
+			c.Assert(err, qt.IsNil)
+			c.Assert(cell.Formula(), qt.Equals, "B"+RowIndexToString(row)+"+1")
+		}
+		c.Assert(days, qt.DeepEquals, []time.Time{day.AddDate(0, 0, -3), day.AddDate(0, 0, -1), day.AddDate(0, 0, -2), day})
+
+		parts, b := writeFileParts(c, f)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `<sortState ref="A1:C4"><sortCondition ref="A1:A4"/><sortCondition ref="B1:B4"/></sortState>`)
+		f, err = OpenBinary(b, option)
+		c.Assert(err, qt.IsNil)
+		parts, _ = writeFileParts(c, f)
+		c.Assert(parts["xl/worksheets/sheet1.xml"], qt.Contains, `<sortState ref="A1:C4">`)
+	})
+
+	csRunO(c, "ManyRows", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		const n = 50
+		for i := 0; i < n; i++ {
+			row := sheet.AddRow()
+			row.AddCell().SetInt(i * 7 % n)
+			row.AddCell().SetInt(i)
+		}
+		c.Assert(sheet.Sort(NewCellRange(0, 0, 1, n-1), false, SortKey{Col: 0}), qt.IsNil)
+		for i := 0; i < n; i++ {
+			key, err := sheet.Cell(i, 0)
+			c.Assert(err, qt.IsNil)
+			c.Assert(key.Value, qt.Equals, strconv.Itoa(i))
+			// The rest of the row moves with its key
+			cell, err := sheet.Cell(i, 1)
+			c.Assert(err, qt.IsNil)
+			orig, err := cell.Int()
+			c.Assert(err, qt.IsNil)
+			c.Assert(orig*7%n, qt.Equals, i)
+		}
+	})
+
+	csRunO(c, "StateFollowsEdits", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		for i := 0; i < 4; i++ {
+			row := sheet.AddRow()
+			row.AddCell().SetInt(4 - i)
+			row.AddCell().SetInt(i)
+			row.AddCell().SetInt(i)
+		}
+		c.Assert(sheet.Sort(NewCellRange(0, 0, 2, 3), false, SortKey{Col: 0}, SortKey{Col: 1}), qt.IsNil)
+
+		_, err = sheet.AddRowAtIndex(0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(sheet.InsertCols(0, 1), qt.IsNil)
+		c.Assert(sheet.sortState, qt.DeepEquals, &xlsxSortState{
+			Ref:           "B2:D5",
+			SortCondition: []xlsxSortCondition{{Ref: "B2:B5"}, {Ref: "C2:C5"}},
+		})
+
+		// Removing a key's column drops the key
+		c.Assert(sheet.RemoveRowAtIndex(0), qt.IsNil)
+		c.Assert(sheet.RemoveCols(1, 1), qt.IsNil)
+		c.Assert(sheet.sortState, qt.DeepEquals, &xlsxSortState{
+			Ref:           "B1:C4",
+			SortCondition: []xlsxSortCondition{{Ref: "B1:B4"}},
+		})
+
+		// Removing the last key forgets the sort
+		c.Assert(sheet.RemoveCols(1, 1), qt.IsNil)
+		c.Assert(sheet.sortState, qt.IsNil)
+	})
+
+	csRunO(c, "Errors", func(c *qt.C, option FileOption) {
+		f := NewFile(option)
+		sheet, err := f.AddSheet("Sheet1")
+		c.Assert(err, qt.IsNil)
+		sheet.AddRow().AddCell().SetInt(1)
+		sheet.AddRow().AddCell().SetInt(2)
+		r := NewCellRange(0, 0, 1, 1)
+		c.Assert(sheet.Sort(r, false), qt.ErrorMatches, `Sort\("A1:B2"\): no sort keys`)
+		c.Assert(sheet.Sort(r, false, SortKey{Col: 2}), qt.ErrorMatches, `Sort\("A1:B2"\): the key column C is outside the range`)
+		c.Assert(sheet.MergeRange(NewCellRange(1, 0, 1, 1)), qt.IsNil)
+		c.Assert(sheet.Sort(r, false, SortKey{Col: 0}), qt.ErrorMatches, `Sort\("A1:B2"\): the range holds the merged cells B1:B2`)
+	})
+}
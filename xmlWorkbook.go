@@ -141,7 +141,7 @@ type xlsxDefinedName struct {
 	Help              string `xml:"help,attr,omitempty"`
 	ShortcutKey       string `xml:"shortcutKey,attr,omitempty"`
 	StatusBar         string `xml:"statusBar,attr,omitempty"`
-	LocalSheetID      int    `xml:"localSheetId,attr,omitempty"`
+	LocalSheetID      *int   `xml:"localSheetId,attr"`
 	FunctionGroupID   int    `xml:"functionGroupId,attr,omitempty"`
 	Function          bool   `xml:"function,attr,omitempty"`
 	Hidden            bool   `xml:"hidden,attr,omitempty"`
@@ -156,11 +156,12 @@ type xlsxDefinedName struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxCalcPr struct {
-	CalcId       string  `xml:"calcId,attr,omitempty"`
-	IterateCount int     `xml:"iterateCount,attr,omitempty"`
-	RefMode      string  `xml:"refMode,attr,omitempty"`
-	Iterate      bool    `xml:"iterate,attr,omitempty"`
-	IterateDelta float64 `xml:"iterateDelta,attr,omitempty"`
+	CalcId         string  `xml:"calcId,attr,omitempty"`
+	IterateCount   int     `xml:"iterateCount,attr,omitempty"`
+	RefMode        string  `xml:"refMode,attr,omitempty"`
+	Iterate        bool    `xml:"iterate,attr,omitempty"`
+	IterateDelta   float64 `xml:"iterateDelta,attr,omitempty"`
+	FullCalcOnLoad bool    `xml:"fullCalcOnLoad,attr,omitempty"`
 }
 
 // Helper function to lookup the file corresponding to a xlsxSheet object in the worksheets map
@@ -49,14 +49,39 @@ type xlsxWorksheet struct {
 	SheetFormatPr   xlsxSheetFormatPr    `xml:"sheetFormatPr"`
 	Cols            *xlsxCols            `xml:"cols,omitempty"`
 	SheetData       xlsxSheetData        `xml:"sheetData"`
-	Hyperlinks      *xlsxHyperlinks      `xml:"hyperlinks,omitempty"`
-	DataValidations *xlsxDataValidations `xml:"dataValidations"`
 	AutoFilter      *xlsxAutoFilter      `xml:"autoFilter,omitempty"`
+	SortState       *xlsxSortState       `xml:"sortState,omitempty"`
 	MergeCells      *xlsxMergeCells      `xml:"mergeCells,omitempty"`
+	DataValidations *xlsxDataValidations `xml:"dataValidations"`
+	Hyperlinks      *xlsxHyperlinks      `xml:"hyperlinks,omitempty"`
 	PrintOptions    *xlsxPrintOptions    `xml:"printOptions,omitempty"`
 	PageMargins     *xlsxPageMargins     `xml:"pageMargins,omitempty"`
 	PageSetUp       *xlsxPageSetUp       `xml:"pageSetup,omitempty"`
 	HeaderFooter    *xlsxHeaderFooter    `xml:"headerFooter,omitempty"`
+	RowBreaks       *xlsxBreaks          `xml:"rowBreaks,omitempty"`
+	ColBreaks       *xlsxBreaks          `xml:"colBreaks,omitempty"`
+}
+
+// xlsxBreaks directly maps the rowBreaks and colBreaks elements in
+// the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxBreaks struct {
+	Count            int         `xml:"count,attr"`
+	ManualBreakCount int         `xml:"manualBreakCount,attr"`
+	Brk              []xlsxBreak `xml:"brk"`
+}
+
+// xlsxBreak directly maps the brk element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxBreak struct {
+	Id  int  `xml:"id,attr"`
+	Min int  `xml:"min,attr,omitempty"`
+	Max int  `xml:"max,attr,omitempty"`
+	Man bool `xml:"man,attr,omitempty"`
 }
 
 // xlsxHeaderFooter directly maps the headerFooter element in the namespace
@@ -64,10 +89,14 @@ type xlsxWorksheet struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxHeaderFooter struct {
-	DifferentFirst   *bool           `xml:"differentFirst,attr,omitempty"`
-	DifferentOddEven *bool           `xml:"differentOddEven,attr,omitempty"`
-	OddHeader        []xlsxOddHeader `xml:"oddHeader"`
-	OddFooter        []xlsxOddFooter `xml:"oddFooter"`
+	DifferentFirst   *bool             `xml:"differentFirst,attr,omitempty"`
+	DifferentOddEven *bool             `xml:"differentOddEven,attr,omitempty"`
+	OddHeader        []xlsxOddHeader   `xml:"oddHeader"`
+	OddFooter        []xlsxOddFooter   `xml:"oddFooter"`
+	EvenHeader       []xlsxEvenHeader  `xml:"evenHeader"`
+	EvenFooter       []xlsxEvenFooter  `xml:"evenFooter"`
+	FirstHeader      []xlsxFirstHeader `xml:"firstHeader"`
+	FirstFooter      []xlsxFirstFooter `xml:"firstFooter"`
 }
 
 // xlsxOddHeader directly maps the oddHeader element in the namespace
@@ -86,6 +115,38 @@ type xlsxOddFooter struct {
 	Content string `xml:",chardata"`
 }
 
+// xlsxEvenHeader directly maps the evenHeader element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxEvenHeader struct {
+	Content string `xml:",chardata"`
+}
+
+// xlsxEvenFooter directly maps the evenFooter element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxEvenFooter struct {
+	Content string `xml:",chardata"`
+}
+
+// xlsxFirstHeader directly maps the firstHeader element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxFirstHeader struct {
+	Content string `xml:",chardata"`
+}
+
+// xlsxFirstFooter directly maps the firstFooter element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxFirstFooter struct {
+	Content string `xml:",chardata"`
+}
+
 // xlsxPageSetUp directly maps the pageSetup element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
@@ -177,6 +238,28 @@ type xlsxSheetView struct {
 	Selection               []xlsxSelection `xml:"selection"`
 }
 
+// UnmarshalXML implements xml.Unmarshaler interface for
+// xlsxSheetView, so that the attributes a file leaves out take the
+// default values of the schema rather than Go's zero values.
+func (v *xlsxSheetView) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type sheetView xlsxSheetView
+	view := sheetView{
+		ShowGridLines:      true,
+		ShowRowColHeaders:  true,
+		ShowZeros:          true,
+		ShowOutlineSymbols: true,
+		DefaultGridColor:   true,
+		View:               "normal",
+		ColorId:            64,
+		ZoomScale:          100,
+	}
+	if err := d.DecodeElement(&view, &start); err != nil {
+		return err
+	}
+	*v = xlsxSheetView(view)
+	return nil
+}
+
 // xlsxSelection directly maps the selection element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
@@ -206,9 +289,20 @@ type xlsxPane struct {
 // as I need.
 type xlsxSheetPr struct {
 	FilterMode  bool              `xml:"filterMode,attr"`
+	TabColor    *xlsxColor        `xml:"tabColor,omitempty"`
+	OutlinePr   *xlsxOutlinePr    `xml:"outlinePr,omitempty"`
 	PageSetUpPr []xlsxPageSetUpPr `xml:"pageSetUpPr"`
 }
 
+// xlsxOutlinePr directly maps the outlinePr element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxOutlinePr struct {
+	SummaryBelow *bool `xml:"summaryBelow,attr,omitempty"`
+	SummaryRight *bool `xml:"summaryRight,attr,omitempty"`
+}
+
 // xlsxPageSetUpPr directly maps the pageSetupPr element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
@@ -316,17 +410,36 @@ type xlsxDataValidation struct {
 type xlsxRow struct {
 	R            int     `xml:"r,attr"`
 	Spans        string  `xml:"spans,attr,omitempty"`
+	S            int     `xml:"s,attr,omitempty"`
+	CustomFormat bool    `xml:"customFormat,attr,omitempty"`
 	Hidden       bool    `xml:"hidden,attr,omitempty"`
 	C            []xlsxC `xml:"c"`
 	Ht           string  `xml:"ht,attr,omitempty"`
 	CustomHeight bool    `xml:"customHeight,attr,omitempty"`
 	OutlineLevel uint8   `xml:"outlineLevel,attr,omitempty"`
+	Collapsed    bool    `xml:"collapsed,attr,omitempty"`
 }
 
 type xlsxAutoFilter struct {
 	Ref string `xml:"ref,attr"`
 }
 
+// xlsxSortState directly maps the sortState element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxSortState struct {
+	Ref           string              `xml:"ref,attr"`
+	SortCondition []xlsxSortCondition `xml:"sortCondition"`
+}
+
+// xlsxSortCondition directly maps the sortCondition element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+type xlsxSortCondition struct {
+	Descending bool   `xml:"descending,attr,omitempty"`
+	Ref        string `xml:"ref,attr"`
+}
+
 type xlsxMergeCell struct {
 	Ref string `xml:"ref,attr"` // ref: horiz "A1:C1", vert "B3:B6", both  "D3:G4"
 }
@@ -389,12 +502,13 @@ func (mc *xlsxMergeCells) getExtent(cellRef string) (int, int, error) {
 // as I need.
 type xlsxC struct {
 	XMLName xml.Name
-	R       string  `xml:"r,attr"`           // Cell ID, e.g. A1
-	S       int     `xml:"s,attr,omitempty"` // Style reference.
-	T       string  `xml:"t,attr,omitempty"` // Type.
-	F       *xlsxF  `xml:"f,omitempty"`      // Formula
-	V       string  `xml:"v,omitempty"`      // Value
-	Is      *xlsxSI `xml:"is,omitempty"`     // Inline String.
+	R       string  `xml:"r,attr"`            // Cell ID, e.g. A1
+	S       int     `xml:"s,attr,omitempty"`  // Style reference.
+	T       string  `xml:"t,attr,omitempty"`  // Type.
+	Cm      int     `xml:"cm,attr,omitempty"` // Cell metadata index, used for dynamic arrays.
+	F       *xlsxF  `xml:"f,omitempty"`       // Formula
+	V       string  `xml:"v,omitempty"`       // Value
+	Is      *xlsxSI `xml:"is,omitempty"`      // Inline String.
 }
 
 // xlsxF directly maps the f element in the namespace
@@ -405,7 +519,7 @@ type xlsxF struct {
 	Content string `xml:",chardata"`
 	T       string `xml:"t,attr,omitempty"`   // Formula type
 	Ref     string `xml:"ref,attr,omitempty"` // Shared formula ref
-	Si      int    `xml:"si,attr,omitempty"`  // Shared formula index
+	Si      *int   `xml:"si,attr,omitempty"`  // Shared formula index
 }
 
 // Create a new XLSX Worksheet with default values populated.
@@ -595,13 +709,15 @@ func emitStructAsXML(v reflect.Value, name, xmlNS string) (xmlwriter.Elem, error
 				Name:  "xmlns",
 				Value: xmlNS,
 			})
-		case "SheetData", "MergeCells", "DataValidations":
-			// Skip SheetData here, we explicitly generate this in writeXML below
-			// Microsoft Excel considers a mergeCells element before a sheetData element to be
-			// an error and will fail to open the document, so we'll be back with this data
-			// from writeXml later.
-
-			continue
+		case "SheetData":
+			// Stop at SheetData, we explicitly generate it in
+			// WriteXML below.  Microsoft Excel considers any
+			// of the elements that follow sheetData in the
+			// schema (mergeCells, headerFooter, etc.)  to be an
+			// error if they appear before it, and will fail to
+			// open the document, so we'll be back with the
+			// remaining fields from WriteXML later.
+			return output, nil
 		default:
 			if fv.Kind() == reflect.Ptr {
 				if fv.IsNil() {
@@ -644,16 +760,28 @@ func emitStructAsXML(v reflect.Value, name, xmlNS string) (xmlwriter.Elem, error
 
 }
 
-func (worksheet *xlsxWorksheet) makeXlsxRowFromRow(row *Row, styles *xlsxStyleSheet, refTable *RefTable) (*xlsxRow, error) {
+func (worksheet *xlsxWorksheet) makeXlsxRowFromRow(row *Row, styles *xlsxStyleSheet, refTable *RefTable, shared map[evalPos]*xlsxF) (*xlsxRow, error) {
+	if err := row.checkLimits(); err != nil {
+		return nil, err
+	}
 	xRow := &xlsxRow{}
 	xRow.R = row.num + 1
 	if row.customHeight {
 		xRow.CustomHeight = true
 		xRow.Ht = fmt.Sprintf("%g", row.GetHeight())
 	}
+	xRow.Hidden = row.Hidden
+	xRow.Collapsed = row.Collapsed
 	xRow.OutlineLevel = row.GetOutlineLevel()
+	if row.style != nil {
+		xRow.S = handleStyleForXLSX(row.style, 0, styles)
+		xRow.CustomFormat = true
+	}
 
 	err := row.ForEachCell(func(cell *Cell) error {
+		if err := cell.checkLimits(row); err != nil {
+			return err
+		}
 		var XfId int
 
 		col := row.Sheet.Col(cell.num)
@@ -665,6 +793,11 @@ func (worksheet *xlsxWorksheet) makeXlsxRowFromRow(row *Row, styles *xlsxStyleSh
 		xNumFmt := styles.newNumFmt(cell.NumFmt)
 
 		style := cell.style
+		if style == nil {
+			// The style of the row takes precedence over that
+			// of the column
+			style = row.style
+		}
 		switch {
 		case style != nil:
 			XfId = handleStyleForXLSX(style, xNumFmt.NumFmtId, styles)
@@ -679,9 +812,7 @@ func (worksheet *xlsxWorksheet) makeXlsxRowFromRow(row *Row, styles *xlsxStyleSh
 			S: XfId,
 			R: GetCellIDStringFromCoords(cell.num, row.num),
 		}
-		if cell.formula != "" {
-			xC.F = &xlsxF{Content: cell.formula}
-		}
+		row.Sheet.makeXLSXFormula(&xC, cell, row.num, shared)
 		switch cell.cellType {
 		case CellTypeInline:
 			// Inline strings are turned into shared strings since they are more efficient.
@@ -730,6 +861,10 @@ func (worksheet *xlsxWorksheet) WriteXML(xw *xmlwriter.Writer, s *Sheet, styles
 	if err != nil {
 		return
 	}
+	shared, err := s.makeSharedFormulas()
+	if err != nil {
+		return
+	}
 
 	ec := xmlwriter.ErrCollector{}
 	defer ec.Set(&err)
@@ -737,7 +872,7 @@ func (worksheet *xlsxWorksheet) WriteXML(xw *xmlwriter.Writer, s *Sheet, styles
 		xw.StartElem(output),
 		xw.StartElem(xmlwriter.Elem{Name: "sheetData"}),
 		s.ForEachRow(func(row *Row) error {
-			xRow, err := worksheet.makeXlsxRowFromRow(row, styles, refTable)
+			xRow, err := worksheet.makeXlsxRowFromRow(row, styles, refTable, shared)
 			if err != nil {
 				return err
 			}
@@ -754,26 +889,43 @@ func (worksheet *xlsxWorksheet) WriteXML(xw *xmlwriter.Writer, s *Sheet, styles
 
 		}, SkipEmptyRows),
 		xw.EndElem("sheetData"),
-		func() error {
-			if worksheet.MergeCells != nil {
-				mergeCells, err := emitStructAsXML(reflect.ValueOf(worksheet.MergeCells), "mergeCells", "")
-				if err != nil {
-					return err
-				}
-				return xw.Write(mergeCells)
-			}
-			if worksheet.DataValidations != nil {
-				dataValidation, err := emitStructAsXML(reflect.ValueOf(worksheet.DataValidations), "dataValidations", "")
-				if err != nil {
-					return err
-				}
-				return xw.Write(dataValidation)
-			}
-			return nil
-		}(),
+		worksheet.writeTrailingElements(xw),
 		xw.EndElem(output.Name),
 		xw.Flush(),
 	)
 	return
 
 }
+
+// writeTrailingElements emits all the elements of the worksheet that
+// must follow the sheetData element.  They're emitted in the order
+// their fields are declared in xlsxWorksheet, which matches the order
+// demanded by the schema.
+func (worksheet *xlsxWorksheet) writeTrailingElements(xw *xmlwriter.Writer) error {
+	v := reflect.ValueOf(worksheet).Elem()
+	trailing := false
+	for i := 0; i < v.NumField(); i++ {
+		ft := v.Type().Field(i)
+		if ft.Name == "SheetData" {
+			trailing = true
+			continue
+		}
+		if !trailing {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			continue
+		}
+		_, name, _, _, _ := parseXMLTag(ft.Tag.Get("xml"))
+		elem, err := emitStructAsXML(fv, name, "")
+		if err != nil {
+			return err
+		}
+		err = xw.Write(elem)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}